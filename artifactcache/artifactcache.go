@@ -0,0 +1,300 @@
+// Package artifactcache keeps downloaded artifacts on disk so they can be deployed again without downloading them.
+package artifactcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/spf13/afero"
+)
+
+const (
+	artifactExtension = ".artifact"
+	metadataExtension = ".json"
+)
+
+// ArtifactCache keeps artifacts in a directory, keyed by the URL they were downloaded from.
+// Artifacts can also be found by their digest, so the same artifact is only downloaded once
+// when it is promoted through environments. The least recently used artifacts are removed
+// when the artifacts in the cache are larger than MaxSize.
+type ArtifactCache struct {
+	Directory  string
+	MaxSize    int64
+	FileSystem *afero.Afero
+	Log        I.Logger
+	mutex      sync.Mutex
+	entries    map[string]S.CachedArtifact
+}
+
+// New returns an ArtifactCache with the artifacts already stored in the directory.
+func New(directory string, maxSize int64, fileSystem *afero.Afero, log I.Logger) (*ArtifactCache, error) {
+	err := fileSystem.MkdirAll(directory, 0700)
+	if err != nil {
+		return nil, CreateDirectoryError{directory, err}
+	}
+
+	cache := &ArtifactCache{
+		Directory:  directory,
+		MaxSize:    maxSize,
+		FileSystem: fileSystem,
+		Log:        log,
+		entries:    make(map[string]S.CachedArtifact),
+	}
+
+	files, err := fileSystem.ReadDir(directory)
+	if err != nil {
+		return nil, ReadDirectoryError{directory, err}
+	}
+
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), metadataExtension) {
+			continue
+		}
+
+		key := strings.TrimSuffix(file.Name(), metadataExtension)
+
+		artifact, err := cache.readMetadata(key)
+		if err != nil {
+			log.Errorf("removing unreadable cached artifact %s: %s", key, err)
+			cache.remove(key)
+			continue
+		}
+
+		cache.entries[key] = artifact
+	}
+
+	return cache, nil
+}
+
+// LookupDigest finds an artifact with the same SHA-256 digest as the checksums.
+// MD5 and SHA-1 digests can be forged, so an artifact is never found by them alone;
+// they are only used to check an artifact once it has been downloaded.
+// An artifact that was downloaded with credentials is only found with the same credentials,
+// so a deploy cannot get an artifact it would not be allowed to download.
+func (c *ArtifactCache) LookupDigest(checksums S.Checksums, credentials string) (S.CachedArtifact, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key, artifact := range c.entries {
+		if artifact.Credentials != "" && artifact.Credentials != credentials {
+			continue
+		}
+
+		if matches(checksums.SHA256, artifact.Checksums.SHA256) {
+			return c.use(key), true
+		}
+	}
+
+	return S.CachedArtifact{}, false
+}
+
+// LookupURL finds the artifact that was downloaded from the URL.
+func (c *ArtifactCache) LookupURL(url string) (S.CachedArtifact, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := cacheKey(url)
+	if _, found := c.entries[key]; !found {
+		return S.CachedArtifact{}, false
+	}
+
+	return c.use(key), true
+}
+
+// CopyTo writes the contents of a cached artifact to the destination.
+// Returns an error when the artifact has been replaced or removed since it was looked up.
+func (c *ArtifactCache) CopyTo(artifact S.CachedArtifact, destination io.Writer) error {
+	file, err := c.open(artifact)
+	if err != nil {
+		return ReadArtifactError{artifact.URL, err}
+	}
+	defer file.Close()
+
+	_, err = io.Copy(destination, file)
+	if err != nil {
+		return ReadArtifactError{artifact.URL, err}
+	}
+
+	return nil
+}
+
+// Store copies the artifact at source into the cache, replacing any artifact downloaded from the same URL.
+// Least recently used artifacts are removed until the cache fits in MaxSize.
+// Artifacts larger than MaxSize are not cached.
+func (c *ArtifactCache) Store(artifact S.CachedArtifact, source string) error {
+	if artifact.Size > c.MaxSize {
+		c.Log.Infof("not caching %s: it is larger than the artifact cache", artifact.URL)
+		return nil
+	}
+
+	key := cacheKey(artifact.URL)
+	temporaryPath := c.artifactPath(key) + ".tmp"
+
+	err := c.copyFile(source, temporaryPath)
+	if err != nil {
+		c.FileSystem.Remove(temporaryPath)
+		return WriteArtifactError{artifact.URL, err}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err = c.FileSystem.Rename(temporaryPath, c.artifactPath(key))
+	if err != nil {
+		c.FileSystem.Remove(temporaryPath)
+		return WriteArtifactError{artifact.URL, err}
+	}
+
+	artifact.LastUsed = time.Now()
+	c.entries[key] = artifact
+
+	err = c.writeMetadata(key, artifact)
+	if err != nil {
+		c.remove(key)
+		return WriteArtifactError{artifact.URL, err}
+	}
+
+	c.evict()
+
+	return nil
+}
+
+// open opens a cached artifact while holding the mutex, so it cannot be replaced or removed
+// before it is opened. A file that is open can still be read once it has been replaced or removed.
+func (c *ArtifactCache) open(artifact S.CachedArtifact) (afero.File, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := cacheKey(artifact.URL)
+	if current, found := c.entries[key]; !found || current.Checksums != artifact.Checksums {
+		return nil, errors.New("it is no longer in the cache")
+	}
+
+	return c.FileSystem.Open(c.artifactPath(key))
+}
+
+// use records that an artifact was just used. The mutex must be held.
+func (c *ArtifactCache) use(key string) S.CachedArtifact {
+	artifact := c.entries[key]
+	artifact.LastUsed = time.Now()
+	c.entries[key] = artifact
+
+	if err := c.writeMetadata(key, artifact); err != nil {
+		c.Log.Errorf("could not update cached artifact %s: %s", artifact.URL, err)
+	}
+
+	return artifact
+}
+
+// evict removes the least recently used artifacts until the cache fits in MaxSize. The mutex must be held.
+func (c *ArtifactCache) evict() {
+	var (
+		keys = byLastUsed{entries: c.entries}
+		size int64
+	)
+
+	for key, artifact := range c.entries {
+		keys.keys = append(keys.keys, key)
+		size += artifact.Size
+	}
+
+	sort.Sort(keys)
+
+	for _, key := range keys.keys {
+		if size <= c.MaxSize {
+			return
+		}
+
+		c.Log.Infof("evicting %s from the artifact cache", c.entries[key].URL)
+		size -= c.entries[key].Size
+		c.remove(key)
+	}
+}
+
+func (c *ArtifactCache) remove(key string) {
+	delete(c.entries, key)
+	c.FileSystem.Remove(c.artifactPath(key))
+	c.FileSystem.Remove(c.metadataPath(key))
+}
+
+func (c *ArtifactCache) copyFile(source, destination string) error {
+	sourceFile, err := c.FileSystem.Open(source)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	destinationFile, err := c.FileSystem.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer destinationFile.Close()
+
+	_, err = io.Copy(destinationFile, sourceFile)
+	return err
+}
+
+func (c *ArtifactCache) readMetadata(key string) (S.CachedArtifact, error) {
+	var artifact S.CachedArtifact
+
+	data, err := c.FileSystem.ReadFile(c.metadataPath(key))
+	if err != nil {
+		return artifact, err
+	}
+
+	err = json.Unmarshal(data, &artifact)
+	if err != nil {
+		return artifact, err
+	}
+
+	_, err = c.FileSystem.Stat(c.artifactPath(key))
+	return artifact, err
+}
+
+func (c *ArtifactCache) writeMetadata(key string, artifact S.CachedArtifact) error {
+	data, err := json.Marshal(artifact)
+	if err != nil {
+		return err
+	}
+
+	return c.FileSystem.WriteFile(c.metadataPath(key), data, 0600)
+}
+
+func (c *ArtifactCache) artifactPath(key string) string {
+	return path.Join(c.Directory, key+artifactExtension)
+}
+
+func (c *ArtifactCache) metadataPath(key string) string {
+	return path.Join(c.Directory, key+metadataExtension)
+}
+
+type byLastUsed struct {
+	keys    []string
+	entries map[string]S.CachedArtifact
+}
+
+func (b byLastUsed) Len() int      { return len(b.keys) }
+func (b byLastUsed) Swap(i, j int) { b.keys[i], b.keys[j] = b.keys[j], b.keys[i] }
+func (b byLastUsed) Less(i, j int) bool {
+	return b.entries[b.keys[i]].LastUsed.Before(b.entries[b.keys[j]].LastUsed)
+}
+
+func cacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+func matches(expected, actual string) bool {
+	return expected != "" && strings.EqualFold(strings.TrimSpace(expected), actual)
+}
@@ -0,0 +1,193 @@
+package artifactcache_test
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/compozed/deployadactyl/artifactcache"
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
+	logging "github.com/op/go-logging"
+	"github.com/spf13/afero"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("ArtifactCache", func() {
+	var (
+		directory string
+		af        *afero.Afero
+		logBuffer *Buffer
+		cache     *ArtifactCache
+	)
+
+	store := func(url, contents, sha256 string) {
+		source := "/tmp/" + randomizer.StringRunes(10)
+		Expect(af.WriteFile(source, []byte(contents), 0600)).To(Succeed())
+
+		Expect(cache.Store(S.CachedArtifact{
+			URL:       url,
+			ETag:      "etag-" + sha256,
+			Checksums: S.Checksums{SHA256: sha256},
+			Size:      int64(len(contents)),
+		}, source)).To(Succeed())
+	}
+
+	contentsOf := func(artifact S.CachedArtifact) string {
+		contents := &bytes.Buffer{}
+		Expect(cache.CopyTo(artifact, contents)).To(Succeed())
+		return contents.String()
+	}
+
+	BeforeEach(func() {
+		directory = "/cache-" + randomizer.StringRunes(10)
+		af = &afero.Afero{Fs: afero.NewMemMapFs()}
+		logBuffer = NewBuffer()
+
+		var err error
+		cache, err = New(directory, 10, af, logger.DefaultLogger(logBuffer, logging.DEBUG, "artifactcache_test"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("finds a stored artifact by its URL", func() {
+		store("https://example.com/app.jar", "app", "abc123")
+
+		artifact, found := cache.LookupURL("https://example.com/app.jar")
+		Expect(found).To(BeTrue())
+
+		Expect(artifact.ETag).To(Equal("etag-abc123"))
+		Expect(contentsOf(artifact)).To(Equal("app"))
+	})
+
+	It("finds a stored artifact by its digest", func() {
+		store("https://example.com/app.jar", "app", "abc123")
+
+		artifact, found := cache.LookupDigest(S.Checksums{SHA256: "ABC123"}, "")
+		Expect(found).To(BeTrue())
+
+		Expect(artifact.URL).To(Equal("https://example.com/app.jar"))
+	})
+
+	It("does not find an artifact by its MD5 or SHA-1 digest alone", func() {
+		source := "/tmp/" + randomizer.StringRunes(10)
+		Expect(af.WriteFile(source, []byte("app"), 0600)).To(Succeed())
+		Expect(cache.Store(S.CachedArtifact{
+			URL:       "https://example.com/app.jar",
+			Checksums: S.Checksums{SHA256: "abc123", SHA1: "def456", MD5: "789abc"},
+			Size:      int64(len("app")),
+		}, source)).To(Succeed())
+
+		_, found := cache.LookupDigest(S.Checksums{SHA1: "def456", MD5: "789abc"}, "")
+		Expect(found).To(BeFalse())
+
+		_, found = cache.LookupDigest(S.Checksums{SHA256: "abc123", MD5: "789abc"}, "")
+		Expect(found).To(BeTrue())
+	})
+
+	It("only finds an artifact that was downloaded with credentials by the same credentials", func() {
+		source := "/tmp/" + randomizer.StringRunes(10)
+		Expect(af.WriteFile(source, []byte("app"), 0600)).To(Succeed())
+		Expect(cache.Store(S.CachedArtifact{
+			URL:         "https://example.com/app.jar",
+			Checksums:   S.Checksums{SHA256: "abc123"},
+			Size:        int64(len("app")),
+			Credentials: "production-credentials",
+		}, source)).To(Succeed())
+
+		_, found := cache.LookupDigest(S.Checksums{SHA256: "abc123"}, "")
+		Expect(found).To(BeFalse())
+
+		_, found = cache.LookupDigest(S.Checksums{SHA256: "abc123"}, "other-credentials")
+		Expect(found).To(BeFalse())
+
+		_, found = cache.LookupDigest(S.Checksums{SHA256: "abc123"}, "production-credentials")
+		Expect(found).To(BeTrue())
+	})
+
+	It("does not copy an artifact that was replaced after it was looked up", func() {
+		store("https://example.com/app.jar", "old", "abc123")
+		artifact, _ := cache.LookupURL("https://example.com/app.jar")
+
+		store("https://example.com/app.jar", "new", "def456")
+
+		err := cache.CopyTo(artifact, &bytes.Buffer{})
+		Expect(err).To(BeAssignableToTypeOf(ReadArtifactError{}))
+	})
+
+	It("does not copy an artifact that was evicted after it was looked up", func() {
+		store("https://example.com/app.jar", "app", "abc123")
+		artifact, _ := cache.LookupURL("https://example.com/app.jar")
+		time.Sleep(time.Millisecond)
+
+		store("https://example.com/large.jar", "0123456789", "def456")
+
+		err := cache.CopyTo(artifact, &bytes.Buffer{})
+		Expect(err).To(BeAssignableToTypeOf(ReadArtifactError{}))
+	})
+
+	It("does not find artifacts that were not stored", func() {
+		store("https://example.com/app.jar", "app", "abc123")
+
+		_, found := cache.LookupURL("https://example.com/other.jar")
+		Expect(found).To(BeFalse())
+
+		_, found = cache.LookupDigest(S.Checksums{SHA256: "def456"}, "")
+		Expect(found).To(BeFalse())
+
+		_, found = cache.LookupDigest(S.Checksums{}, "")
+		Expect(found).To(BeFalse())
+	})
+
+	It("replaces the artifact stored for a URL", func() {
+		store("https://example.com/app.jar", "old", "abc123")
+		store("https://example.com/app.jar", "new", "def456")
+
+		artifact, _ := cache.LookupURL("https://example.com/app.jar")
+
+		Expect(contentsOf(artifact)).To(Equal("new"))
+	})
+
+	It("evicts the least recently used artifacts when the cache is full", func() {
+		store("https://example.com/first.jar", "1234", "first")
+		time.Sleep(time.Millisecond)
+		store("https://example.com/second.jar", "1234", "second")
+		time.Sleep(time.Millisecond)
+
+		cache.LookupURL("https://example.com/first.jar")
+		time.Sleep(time.Millisecond)
+
+		store("https://example.com/third.jar", "1234", "third")
+
+		_, found := cache.LookupURL("https://example.com/second.jar")
+		Expect(found).To(BeFalse())
+
+		_, found = cache.LookupURL("https://example.com/first.jar")
+		Expect(found).To(BeTrue())
+
+		Eventually(logBuffer).Should(Say("evicting https://example.com/second.jar from the artifact cache"))
+	})
+
+	It("does not store artifacts larger than the cache", func() {
+		store("https://example.com/large.jar", "12345678901", "large")
+
+		_, found := cache.LookupURL("https://example.com/large.jar")
+		Expect(found).To(BeFalse())
+	})
+
+	It("keeps the artifacts when it is created again", func() {
+		store("https://example.com/app.jar", "app", "abc123")
+
+		reloaded, err := New(directory, 10, af, logger.DefaultLogger(logBuffer, logging.DEBUG, "artifactcache_test"))
+		Expect(err).ToNot(HaveOccurred())
+
+		artifact, found := reloaded.LookupDigest(S.Checksums{SHA256: "abc123"}, "")
+		Expect(found).To(BeTrue())
+
+		contents := &bytes.Buffer{}
+		Expect(reloaded.CopyTo(artifact, contents)).To(Succeed())
+		Expect(contents.String()).To(Equal("app"))
+	})
+})
@@ -0,0 +1,39 @@
+package artifactcache
+
+import "fmt"
+
+type CreateDirectoryError struct {
+	Directory string
+	Err       error
+}
+
+func (e CreateDirectoryError) Error() string {
+	return fmt.Sprintf("cannot create artifact cache directory %s: %s", e.Directory, e.Err)
+}
+
+type ReadDirectoryError struct {
+	Directory string
+	Err       error
+}
+
+func (e ReadDirectoryError) Error() string {
+	return fmt.Sprintf("cannot read artifact cache directory %s: %s", e.Directory, e.Err)
+}
+
+type ReadArtifactError struct {
+	URL string
+	Err error
+}
+
+func (e ReadArtifactError) Error() string {
+	return fmt.Sprintf("cannot read cached artifact %s: %s", e.URL, e.Err)
+}
+
+type WriteArtifactError struct {
+	URL string
+	Err error
+}
+
+func (e WriteArtifactError) Error() string {
+	return fmt.Sprintf("cannot cache artifact %s: %s", e.URL, e.Err)
+}
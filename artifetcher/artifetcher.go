@@ -2,91 +2,206 @@
 package artifetcher
 
 import (
+	"fmt"
 	"io"
-	"net"
 	"net/http"
-	"time"
+	"net/url"
+	"strings"
 
+	"github.com/compozed/deployadactyl/artifetcher/source"
+	"github.com/compozed/deployadactyl/config"
 	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
 	"github.com/spf13/afero"
 )
 
+// uploadedArchive names archives that were uploaded rather than downloaded in errors and logs.
+const uploadedArchive = "uploaded archive"
+
 // Artifetcher fetches artifacts within a file system with an Extractor.
+// Artifacts are downloaded from the Sources registered for the scheme of their URL,
+// or over http and https when there are no Sources. Failed downloads are retried with the Retry policy.
+// Downloaded artifacts are kept in the Cache when there is one.
+//
+// Artifacts are written and extracted in WorkingDirectory, or the OS temp dir when it is empty.
+// Artifacts larger than MaxArtifactSize are rejected, and the Disk checks there is enough
+// free space in the working directory before an artifact is written to it.
 type Artifetcher struct {
-	FileSystem *afero.Afero
-	Extractor  I.Extractor
-	Log        I.Logger
+	FileSystem       *afero.Afero
+	Extractor        I.Extractor
+	Log              I.Logger
+	Cache            I.ArtifactCache
+	Sources          map[string]I.ArtifactSource
+	Retry            RetryPolicy
+	WorkingDirectory string
+	MaxArtifactSize  int64
+	Disk             I.DiskChecker
 }
 
-// Fetch downloads an artifact located at URL.
+// Fetch downloads an artifact located at URL and hashes it while it is written to disk.
+// The first credentials of the environment for the host of the URL authenticate the download.
+// If the artifact does not match the expected checksums, or the signature is not from one of the
+// trusted keys of the environment, nothing is extracted.
 // It then passes it to the extractor with the manifest for unzipping.
+// An artifact with a Content-Length larger than MaxArtifactSize, or too large for the free space
+// in the working directory, is rejected before it is downloaded.
+//
+// When there is a Cache, an artifact with one of the expected checksums is used without
+// downloading it, and an artifact that was downloaded from the URL before is only downloaded
+// again if the server says it has changed. Whether the cache was used is written to the response.
 //
-// Returns a string to the unzipped artifacts path and an error.
-func (a *Artifetcher) Fetch(url, manifest string) (string, error) {
+// Returns a string to the unzipped artifacts path, the checksums of the artifact and an error.
+func (a *Artifetcher) Fetch(url, manifest string, expected S.Checksums, signature S.ArtifactSignature, environment config.Environment, response io.Writer) (string, S.Checksums, error) {
 	a.Log.Info("fetching artifact")
 	a.Log.Debugf("artifact URL: %s", url)
 
-	artifactFile, err := a.FileSystem.TempFile("", "deployadactyl-zip-")
+	if environment.RequireSignedArtifacts && signature == (S.ArtifactSignature{}) {
+		return "", S.Checksums{}, MissingSignatureError{url, environment.Name}
+	}
+
+	err := a.checkSpace(url, -1)
 	if err != nil {
-		return "", CreateTempFileError{err}
+		return "", S.Checksums{}, err
+	}
+
+	artifactFile, err := a.FileSystem.TempFile(a.WorkingDirectory, "deployadactyl-zip-")
+	if err != nil {
+		return "", S.Checksums{}, CreateTempFileError{err}
 	}
 	defer artifactFile.Close()
 	defer a.FileSystem.Remove(artifactFile.Name())
 
-	var client = &http.Client{
-		Timeout: 4 * time.Minute,
-		Transport: &http.Transport{
-			Dial: (&net.Dialer{
-				Timeout:   60 * time.Second,
-				KeepAlive: 60 * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout:   15 * time.Second,
-			ResponseHeaderTimeout: 15 * time.Second,
-			ExpectContinueTimeout: 2 * time.Second,
-		},
+	out := a.newOutput(artifactFile, url)
+
+	var (
+		copied     bool
+		downloaded *S.CachedArtifact
+	)
+
+	if cached, found := a.lookupDigest(url, expected, environment.ArtifactCredentials); found {
+		a.reportCache(response, "artifact cache hit for %s: using the cached artifact with the same checksum", url)
+
+		err = a.checkSpace(url, cached.Size)
+		if err != nil {
+			return "", S.Checksums{}, err
+		}
+
+		copied, err = a.copyFromCache(cached, expected, out)
+		if err != nil {
+			return "", S.Checksums{}, err
+		}
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	if !copied {
+		downloaded, err = a.download(url, environment.ArtifactCredentials, out, true, response)
+		if err != nil {
+			return "", S.Checksums{}, err
+		}
+	}
+
+	checksums := out.digester.checksums()
+
+	err = a.verify(url, expected, checksums, signature, environment, response)
 	if err != nil {
-		return "", ArtifactoryRequestError{err}
+		return "", checksums, err
+	}
+
+	if downloaded != nil {
+		downloaded.Checksums = checksums
+
+		err = a.Cache.Store(*downloaded, artifactFile.Name())
+		if err != nil {
+			a.Log.Errorf("could not cache %s: %s", url, err)
+		}
 	}
 
-	response, err := client.Do(req)
+	return a.unpack(artifactFile.Name(), manifest, checksums)
+}
+
+// FetchArchive writes an archive from a reader, such as a part of a multipart request, to disk
+// and hashes it. It is checked against the expected checksums and signature and extracted
+// in the same way as an artifact from Fetch.
+//
+// Returns a string to the unzipped artifacts path, the checksums of the archive and an error.
+func (a *Artifetcher) FetchArchive(archive io.Reader, manifest string, expected S.Checksums, signature S.ArtifactSignature, environment config.Environment, response io.Writer) (string, S.Checksums, error) {
+	a.Log.Info("fetching uploaded archive")
+
+	if environment.RequireSignedArtifacts && signature == (S.ArtifactSignature{}) {
+		return "", S.Checksums{}, MissingSignatureError{uploadedArchive, environment.Name}
+	}
+
+	err := a.checkSpace(uploadedArchive, -1)
 	if err != nil {
-		return "", GetUrlError{url, err}
+		return "", S.Checksums{}, err
 	}
-	defer response.Body.Close()
 
-	if response.StatusCode != http.StatusOK {
-		return "", GetStatusError{url, response.Status}
+	archiveFile, err := a.FileSystem.TempFile(a.WorkingDirectory, "deployadactyl-")
+	if err != nil {
+		return "", S.Checksums{}, CreateTempFileError{err}
 	}
+	defer archiveFile.Close()
+	defer a.FileSystem.Remove(archiveFile.Name())
 
-	_, err = io.Copy(artifactFile, response.Body)
+	digester := newDigester()
+	err = copyArchive(a.limit(io.MultiWriter(archiveFile, digester.writer()), uploadedArchive), archive)
 	if err != nil {
-		return "", WriteResponseError{err}
+		return "", S.Checksums{}, err
 	}
 
-	unzippedPath, err := a.FileSystem.TempDir("", "deployadactyl-unzipped-")
+	return a.extract(archiveFile.Name(), uploadedArchive, manifest, expected, digester.checksums(), signature, environment, response)
+}
+
+// extract checks the checksums and signature of an artifact on disk and extracts it into a temp directory.
+func (a *Artifetcher) extract(artifactPath, artifactName, manifest string, expected, checksums S.Checksums, signature S.ArtifactSignature, environment config.Environment, response io.Writer) (string, S.Checksums, error) {
+	err := a.verify(artifactName, expected, checksums, signature, environment, response)
 	if err != nil {
-		return "", CreateTempDirectoryError{err}
+		return "", checksums, err
 	}
 
-	err = a.Extractor.Unzip(artifactFile.Name(), unzippedPath, manifest)
+	return a.unpack(artifactPath, manifest, checksums)
+}
+
+// verify checks the checksums and signature of an artifact.
+func (a *Artifetcher) verify(artifactName string, expected, checksums S.Checksums, signature S.ArtifactSignature, environment config.Environment, response io.Writer) error {
+	a.Log.Infof("artifact sha256: %s", checksums.SHA256)
+
+	err := verifyChecksums(expected, checksums)
 	if err != nil {
-		a.FileSystem.RemoveAll(unzippedPath)
-		return "", UnzipError{err}
+		return err
+	}
+
+	return a.verifySignature(artifactName, checksums, signature, environment, response)
+}
 
+// unpack extracts an artifact that has been verified into a temp directory.
+func (a *Artifetcher) unpack(artifactPath, manifest string, checksums S.Checksums) (string, S.Checksums, error) {
+	unzippedPath, err := a.FileSystem.TempDir(a.WorkingDirectory, "deployadactyl-unzipped-")
+	if err != nil {
+		return "", checksums, CreateTempDirectoryError{err}
+	}
+
+	err = a.Extractor.Extract(artifactPath, unzippedPath, manifest)
+	if err != nil {
+		a.FileSystem.RemoveAll(unzippedPath)
+		return "", checksums, UnzipError{err}
 	}
 
 	a.Log.Debugf("fetched and unzipped to tempdir: %s", unzippedPath)
-	return unzippedPath, nil
+	return unzippedPath, checksums, nil
 }
 
-// FetchZipFromRequest fetches files from a compressed zip file in the request body.
+// FetchZipFromRequest fetches files from an archive in the request body.
+// Any archive the Extractor can detect is accepted, not only zip files.
+// The Content-Length of the request is checked in the same way as the size of a downloaded artifact.
 //
 // Returns a string to the unzipped application path and an error.
 func (a *Artifetcher) FetchZipFromRequest(req *http.Request) (string, error) {
-	zipFile, err := a.FileSystem.TempFile("", "deployadactyl-")
+	err := a.checkSpace(uploadedArchive, req.ContentLength)
+	if err != nil {
+		return "", err
+	}
+
+	zipFile, err := a.FileSystem.TempFile(a.WorkingDirectory, "deployadactyl-")
 	if err != nil {
 		return "", CreateTempFileError{err}
 	}
@@ -95,16 +210,17 @@ func (a *Artifetcher) FetchZipFromRequest(req *http.Request) (string, error) {
 
 	a.Log.Infof("fetching zip file %s", zipFile.Name())
 
-	if _, err = io.Copy(zipFile, req.Body); err != nil {
-		return "", WriteResponseError{err}
+	err = copyArchive(a.limit(zipFile, uploadedArchive), req.Body)
+	if err != nil {
+		return "", err
 	}
 
-	unzippedPath, err := a.FileSystem.TempDir("", "deployadactyl-")
+	unzippedPath, err := a.FileSystem.TempDir(a.WorkingDirectory, "deployadactyl-")
 	if err != nil {
 		return "", CreateTempDirectoryError{err}
 	}
 
-	err = a.Extractor.Unzip(zipFile.Name(), unzippedPath, "")
+	err = a.Extractor.Extract(zipFile.Name(), unzippedPath, "")
 	if err != nil {
 		a.FileSystem.RemoveAll(unzippedPath)
 		return "", UnzipError{err}
@@ -113,3 +229,57 @@ func (a *Artifetcher) FetchZipFromRequest(req *http.Request) (string, error) {
 	a.Log.Debugf("fetched and unzipped to tempdir %s", unzippedPath)
 	return unzippedPath, nil
 }
+
+func (a *Artifetcher) sources() map[string]I.ArtifactSource {
+	if a.Sources == nil {
+		client := source.NewClient(source.DefaultTimeout)
+		return map[string]I.ArtifactSource{
+			"http":  source.HTTP{Client: client},
+			"https": source.HTTP{Client: client},
+		}
+	}
+	return a.Sources
+}
+
+// credentialsFor returns the first credentials for the host of the URL, with or without its port.
+func credentialsFor(artifactURL *url.URL, credentials []config.ArtifactCredentials) config.ArtifactCredentials {
+	hostname := artifactURL.Host
+	if i := strings.LastIndex(hostname, ":"); i > strings.LastIndex(hostname, "]") {
+		hostname = hostname[:i]
+	}
+
+	for _, c := range credentials {
+		if strings.EqualFold(c.Host, artifactURL.Host) || strings.EqualFold(c.Host, hostname) {
+			return c
+		}
+	}
+
+	return config.ArtifactCredentials{}
+}
+
+// lookupDigest only finds cached artifacts by their SHA-256 digest, because MD5 and SHA-1 can be forged,
+// and only artifacts that were downloaded without credentials or with the credentials for the URL.
+func (a *Artifetcher) lookupDigest(artifactURL string, checksums S.Checksums, credentials []config.ArtifactCredentials) (S.CachedArtifact, bool) {
+	if a.Cache == nil || checksums.SHA256 == "" {
+		return S.CachedArtifact{}, false
+	}
+
+	parsedURL, err := url.Parse(artifactURL)
+	if err != nil {
+		return S.CachedArtifact{}, false
+	}
+
+	return a.Cache.LookupDigest(checksums, credentialsKey(credentialsFor(parsedURL, credentials)))
+}
+
+func (a *Artifetcher) lookupURL(url string) (S.CachedArtifact, bool) {
+	if a.Cache == nil {
+		return S.CachedArtifact{}, false
+	}
+	return a.Cache.LookupURL(url)
+}
+
+func (a *Artifetcher) reportCache(response io.Writer, format string, url string) {
+	a.Log.Infof(format, url)
+	fmt.Fprintf(response, format+"\n", url)
+}
@@ -1,21 +1,37 @@
 package artifetcher_test
 
 import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
 	"github.com/spf13/afero"
+	"golang.org/x/crypto/ed25519"
 
 	"github.com/op/go-logging"
 
 	. "github.com/compozed/deployadactyl/artifetcher"
+	"github.com/compozed/deployadactyl/config"
+	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/logger"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
 )
 
 var _ = Describe("Artifetcher", func() {
@@ -25,13 +41,19 @@ var _ = Describe("Artifetcher", func() {
 		extractor   *mocks.Extractor
 		testserver  *httptest.Server
 		manifest    string
+		response    *Buffer
 	)
 
 	BeforeEach(func() {
 		logger := logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "artifetcher_test")
 		af = &afero.Afero{Fs: afero.NewMemMapFs()}
 		extractor = &mocks.Extractor{}
-		artifetcher = &Artifetcher{af, extractor, logger}
+		response = NewBuffer()
+		artifetcher = &Artifetcher{
+			FileSystem: af,
+			Extractor:  extractor,
+			Log:        logger,
+		}
 		manifest = "manifest-" + randomizer.StringRunes(10)
 
 		testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -45,20 +67,20 @@ var _ = Describe("Artifetcher", func() {
 
 	Describe("fetching a zip file", func() {
 		It("can fetch a jar file", func() {
-			extractor.UnzipCall.Returns.Error = nil
+			extractor.ExtractCall.Returns.Error = nil
 
-			unzippedPath, err := artifetcher.Fetch(testserver.URL, "")
+			unzippedPath, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(af.IsDir(unzippedPath)).To(BeTrue())
 
-			Expect(extractor.UnzipCall.Received.Source).To(ContainSubstring("deployadactyl-zip"))
-			Expect(extractor.UnzipCall.Received.Destination).To(Equal(unzippedPath))
-			Expect(extractor.UnzipCall.Received.Manifest).To(BeEmpty())
+			Expect(extractor.ExtractCall.Received.Source).To(ContainSubstring("deployadactyl-zip"))
+			Expect(extractor.ExtractCall.Received.Destination).To(Equal(unzippedPath))
+			Expect(extractor.ExtractCall.Received.Manifest).To(BeEmpty())
 		})
 
 		It("returns an error when an invalid url is given", func() {
-			_, err := artifetcher.Fetch("example://example.example", manifest)
+			_, _, err := artifetcher.Fetch("example://example.example", manifest, S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
 			Expect(err).To(HaveOccurred())
 		})
 
@@ -67,24 +89,562 @@ var _ = Describe("Artifetcher", func() {
 				http.Error(w, "not found", 404)
 			}))
 
-			_, err := artifetcher.Fetch(testserver.URL, manifest)
+			_, _, err := artifetcher.Fetch(testserver.URL, manifest, S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
 			Expect(err).To(HaveOccurred())
 		})
 
+		Context("when checksums are given", func() {
+			var checksums S.Checksums
+
+			BeforeEach(func() {
+				artifact, err := ioutil.ReadFile("./fixtures/deployadactyl-fixture.jar")
+				Expect(err).ToNot(HaveOccurred())
+
+				sha256Sum := sha256.Sum256(artifact)
+				sha1Sum := sha1.Sum(artifact)
+				md5Sum := md5.Sum(artifact)
+
+				checksums = S.Checksums{
+					SHA256: hex.EncodeToString(sha256Sum[:]),
+					SHA1:   hex.EncodeToString(sha1Sum[:]),
+					MD5:    hex.EncodeToString(md5Sum[:]),
+				}
+			})
+
+			It("returns the checksums of the artifact", func() {
+				_, actual, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(actual).To(Equal(checksums))
+			})
+
+			It("extracts the artifact when the checksums match", func() {
+				_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{SHA256: strings.ToUpper(checksums.SHA256), MD5: checksums.MD5}, S.ArtifactSignature{}, config.Environment{}, response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(extractor.ExtractCall.Received.Source).ToNot(BeEmpty())
+			})
+
+			It("returns an error and does not extract the artifact when a checksum does not match", func() {
+				_, actual, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{SHA1: "0123456789abcdef"}, S.ArtifactSignature{}, config.Environment{}, response)
+				Expect(err).To(MatchError(ChecksumMismatchError{"sha1", "0123456789abcdef", checksums.SHA1}))
+
+				Expect(actual).To(Equal(checksums))
+				Expect(extractor.ExtractCall.Received.Source).To(BeEmpty())
+			})
+		})
+
 		Context("when extractor fails", func() {
 			It("returns an error", func() {
-				extractor.UnzipCall.Returns.Error = errors.New("unzip call failed")
+				extractor.ExtractCall.Returns.Error = errors.New("unzip call failed")
 
-				_, err := artifetcher.Fetch(testserver.URL, "")
+				_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
 
 				Expect(err).To(MatchError(UnzipError{errors.New("unzip call failed")}))
 			})
 		})
 	})
 
+	Describe("verifying signatures", func() {
+		var (
+			publicKey   ed25519.PublicKey
+			privateKey  ed25519.PrivateKey
+			signature   string
+			environment config.Environment
+		)
+
+		BeforeEach(func() {
+			var err error
+			publicKey, privateKey, err = ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			artifact, err := ioutil.ReadFile("./fixtures/deployadactyl-fixture.jar")
+			Expect(err).ToNot(HaveOccurred())
+
+			digest := sha256.Sum256(artifact)
+			signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, digest[:]))
+
+			environment = config.Environment{
+				Name:                   "production",
+				TrustedKeys:            []string{base64.StdEncoding.EncodeToString(publicKey)},
+				RequireSignedArtifacts: true,
+			}
+		})
+
+		It("extracts an artifact signed by a trusted key", func() {
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{Signature: signature}, environment, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(extractor.ExtractCall.Received.Source).ToNot(BeEmpty())
+			Expect(response).To(Say("artifact signature verified"))
+		})
+
+		It("checks the signature against every trusted key", func() {
+			otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			environment.TrustedKeys = append([]string{base64.StdEncoding.EncodeToString(otherKey)}, environment.TrustedKeys...)
+
+			_, _, err = artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{Signature: signature}, environment, response)
+
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("downloads the signature from the signature URL", func() {
+			signatureServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, signature)
+			}))
+			defer signatureServer.Close()
+
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{URL: signatureServer.URL}, environment, response)
+
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("does not extract an artifact signed by another key", func() {
+			_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			otherSignature := base64.StdEncoding.EncodeToString(ed25519.Sign(otherKey, []byte("digest")))
+
+			_, _, err = artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{Signature: otherSignature}, environment, response)
+
+			Expect(err).To(MatchError(InvalidSignatureError{testserver.URL, "production"}))
+			Expect(extractor.ExtractCall.Received.Source).To(BeEmpty())
+		})
+
+		It("returns an error when the signature cannot be decoded", func() {
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{Signature: "not a signature"}, environment, response)
+
+			Expect(err).To(MatchError(DecodeSignatureError{testserver.URL}))
+		})
+
+		It("does not download an unsigned artifact when signatures are required", func() {
+			_, _, err := artifetcher.Fetch("http://example.invalid/artifact.jar", "", S.Checksums{}, S.ArtifactSignature{}, environment, response)
+
+			Expect(err).To(MatchError(MissingSignatureError{"http://example.invalid/artifact.jar", "production"}))
+		})
+
+		It("extracts unsigned artifacts when signatures are not required", func() {
+			environment.RequireSignedArtifacts = false
+
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, environment, response)
+
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("retrying downloads", func() {
+		var (
+			artifact []byte
+			digest   string
+			requests []*http.Request
+		)
+
+		BeforeEach(func() {
+			var err error
+			artifact, err = ioutil.ReadFile("./fixtures/deployadactyl-fixture.jar")
+			Expect(err).ToNot(HaveOccurred())
+
+			sum := sha256.Sum256(artifact)
+			digest = hex.EncodeToString(sum[:])
+			requests = nil
+
+			artifetcher.Retry = RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+		})
+
+		// dropConnection sends the first half of the artifact and closes the connection.
+		dropConnection := func(w http.ResponseWriter) {
+			w.Header().Set("Content-Length", fmt.Sprint(len(artifact)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(artifact[:len(artifact)/2])
+			w.(http.Flusher).Flush()
+
+			conn, _, err := w.(http.Hijacker).Hijack()
+			Expect(err).ToNot(HaveOccurred())
+			conn.Close()
+		}
+
+		It("resumes with a range request after the connection is dropped", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+				w.Header().Set("ETag", `"artifact"`)
+
+				if len(requests) == 1 {
+					dropConnection(w)
+					return
+				}
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(artifact))
+			}))
+
+			_, checksums, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{SHA256: digest}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(checksums.SHA256).To(Equal(digest))
+			Expect(requests).To(HaveLen(2))
+			Expect(requests[1].Header.Get("Range")).To(Equal(fmt.Sprintf("bytes=%d-", len(artifact)/2)))
+			Expect(requests[1].Header.Get("If-Range")).To(Equal(`"artifact"`))
+			Expect(response).To(Say("retrying in 1ms \\(attempt 2 of 3\\)"))
+		})
+
+		It("skips the bytes it already has when the server does not support range requests", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+				w.Header().Set("ETag", `"artifact"`)
+
+				if len(requests) == 1 {
+					dropConnection(w)
+					return
+				}
+				w.Write(artifact)
+			}))
+
+			_, checksums, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{SHA256: digest}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(checksums.SHA256).To(Equal(digest))
+		})
+
+		It("returns an error when the artifact changes between attempts", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+				w.Header().Set("ETag", fmt.Sprintf(`"artifact-%d"`, len(requests)))
+
+				if len(requests) == 1 {
+					dropConnection(w)
+					return
+				}
+				w.Write(artifact)
+			}))
+
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+
+			Expect(err).To(MatchError(ArtifactChangedError{testserver.URL}))
+		})
+
+		It("retries server errors", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+
+				if len(requests) < 3 {
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				w.Write(artifact)
+			}))
+
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(requests).To(HaveLen(3))
+			Expect(response).To(Say("503 Service Unavailable: retrying in 1ms \\(attempt 2 of 3\\)"))
+			Expect(response).To(Say("503 Service Unavailable: retrying in 2ms \\(attempt 3 of 3\\)"))
+		})
+
+		It("returns the last error when every attempt fails", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			}))
+
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+
+			Expect(err).To(MatchError(GetStatusError{testserver.URL, "503 Service Unavailable"}))
+			Expect(requests).To(HaveLen(3))
+		})
+
+		It("does not retry client errors", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+				http.Error(w, "forbidden", http.StatusForbidden)
+			}))
+
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+
+			Expect(err).To(MatchError(GetStatusError{testserver.URL, "403 Forbidden"}))
+			Expect(requests).To(HaveLen(1))
+		})
+
+		It("writes how much was downloaded to the response", func() {
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(response).To(Say(`downloaded 97\.9 KB in .* \(.*/s\)`))
+		})
+	})
+
+	Describe("artifact sources", func() {
+		var source *mocks.ArtifactSource
+
+		BeforeEach(func() {
+			source = &mocks.ArtifactSource{}
+			source.GetCall.Returns.Response = &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader("artifact")),
+			}
+			artifetcher.Sources = map[string]I.ArtifactSource{"s3": source}
+		})
+
+		It("downloads from the source for the scheme of the URL", func() {
+			_, _, err := artifetcher.Fetch("s3://artifacts/app.jar", "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(source.GetCall.Received.Request.URL.String()).To(Equal("s3://artifacts/app.jar"))
+		})
+
+		It("uses the first credentials for the host of the URL", func() {
+			credentials := []config.ArtifactCredentials{
+				{Host: "other", Token: "other-token"},
+				{Host: "artifacts", AccessKeyID: "first"},
+				{Host: "artifacts", AccessKeyID: "second"},
+			}
+
+			_, _, err := artifetcher.Fetch("s3://artifacts/app.jar", "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{ArtifactCredentials: credentials}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(source.GetCall.Received.Credentials).To(Equal(credentials[1]))
+		})
+
+		It("matches credentials to hosts without their port", func() {
+			credentials := []config.ArtifactCredentials{{Host: "artifacts", Token: "token"}}
+
+			_, _, err := artifetcher.Fetch("s3://artifacts:9000/app.jar", "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{ArtifactCredentials: credentials}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(source.GetCall.Received.Credentials).To(Equal(credentials[0]))
+		})
+
+		It("returns an error when there is no source for the scheme", func() {
+			_, _, err := artifetcher.Fetch("ftp://example.com/app.jar", "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+
+			Expect(err).To(MatchError(UnsupportedSchemeError{"ftp://example.com/app.jar", "ftp"}))
+		})
+
+		It("returns an error when the source fails", func() {
+			source.GetCall.Returns.Error = errors.New("source error")
+
+			_, _, err := artifetcher.Fetch("s3://artifacts/app.jar", "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+
+			Expect(err).To(MatchError(GetUrlError{"s3://artifacts/app.jar", errors.New("source error")}))
+		})
+	})
+	Describe("fetching with an artifact cache", func() {
+		var (
+			cache    *mocks.ArtifactCache
+			requests []*http.Request
+			status   int
+		)
+
+		BeforeEach(func() {
+			cache = &mocks.ArtifactCache{}
+			artifetcher.Cache = cache
+			requests = nil
+			status = http.StatusOK
+
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+				w.Header().Set("ETag", `"etag-1"`)
+				w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+				w.WriteHeader(status)
+				if status == http.StatusOK {
+					w.Write([]byte("artifact contents"))
+				}
+			}))
+		})
+
+		It("downloads and stores the artifact on a cache miss", func() {
+			_, checksums, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(requests).To(HaveLen(1))
+			Expect(cache.StoreCall.Received.Artifact).To(Equal(S.CachedArtifact{
+				URL:          testserver.URL,
+				ETag:         `"etag-1"`,
+				LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+				Checksums:    checksums,
+				Size:         int64(len("artifact contents")),
+			}))
+			Expect(cache.StoreCall.Received.Source).To(Equal(extractor.ExtractCall.Received.Source))
+			Eventually(response).Should(Say("artifact cache miss for %s", testserver.URL))
+		})
+
+		It("uses the cached artifact without downloading it when a checksum matches", func() {
+			cachedSum := fmt.Sprintf("%x", sha256.Sum256([]byte("cached contents")))
+			cache.LookupDigestCall.Returns.Found = true
+			cache.LookupDigestCall.Returns.Artifact = S.CachedArtifact{URL: "https://example.com/other.jar"}
+			cache.CopyToCall.Returns.Contents = []byte("cached contents")
+
+			_, checksums, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{SHA256: cachedSum}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(requests).To(BeEmpty())
+			Expect(cache.LookupDigestCall.Received.Checksums).To(Equal(S.Checksums{SHA256: cachedSum}))
+			Expect(cache.CopyToCall.Received.Artifact.URL).To(Equal("https://example.com/other.jar"))
+			Expect(cache.StoreCall.TimesCalled).To(Equal(0))
+			Expect(checksums.MD5).To(Equal(fmt.Sprintf("%x", md5.Sum([]byte("cached contents")))))
+			Eventually(response).Should(Say("artifact cache hit for %s", testserver.URL))
+		})
+
+		It("downloads the artifact when the cached artifact cannot be copied", func() {
+			artifactSum := fmt.Sprintf("%x", sha256.Sum256([]byte("artifact contents")))
+			cache.LookupDigestCall.Returns.Found = true
+			cache.CopyToCall.Returns.Contents = []byte("partial")
+			cache.CopyToCall.Returns.Error = errors.New("it is no longer in the cache")
+
+			_, checksums, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{SHA256: artifactSum}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(requests).To(HaveLen(1))
+			Expect(checksums.SHA256).To(Equal(artifactSum))
+			Expect(cache.StoreCall.TimesCalled).To(Equal(1))
+		})
+
+		It("downloads the artifact when the cached artifact does not have the expected checksum", func() {
+			artifactSum := fmt.Sprintf("%x", sha256.Sum256([]byte("artifact contents")))
+			cache.LookupDigestCall.Returns.Found = true
+			cache.CopyToCall.Returns.Contents = []byte("cached contents")
+
+			_, checksums, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{SHA256: artifactSum}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(requests).To(HaveLen(1))
+			Expect(checksums.SHA256).To(Equal(artifactSum))
+		})
+
+		It("does not store an artifact that does not have the expected checksum", func() {
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{SHA256: "expected"}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(err).To(BeAssignableToTypeOf(ChecksumMismatchError{}))
+
+			Expect(requests).To(HaveLen(1))
+			Expect(cache.StoreCall.TimesCalled).To(Equal(0))
+			Expect(extractor.ExtractCall.Received.Source).To(BeEmpty())
+		})
+
+		It("does not store an artifact that is not signed by a trusted key", func() {
+			publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			digest := sha256.Sum256([]byte("artifact contents"))
+			signature := base64.StdEncoding.EncodeToString(ed25519.Sign(otherKey, digest[:]))
+			environment := config.Environment{
+				Name:                   "production",
+				TrustedKeys:            []string{base64.StdEncoding.EncodeToString(publicKey)},
+				RequireSignedArtifacts: true,
+			}
+
+			_, _, err = artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{Signature: signature}, environment, response)
+			Expect(err).To(HaveOccurred())
+
+			Expect(requests).To(HaveLen(1))
+
+			Expect(cache.StoreCall.TimesCalled).To(Equal(0))
+		})
+
+		It("only finds cached artifacts that were downloaded with the same credentials", func() {
+			environment := config.Environment{ArtifactCredentials: []config.ArtifactCredentials{
+				{Host: "127.0.0.1", Username: "username", Password: "password"},
+			}}
+			otherEnvironment := config.Environment{ArtifactCredentials: []config.ArtifactCredentials{
+				{Host: "127.0.0.1", Username: "other", Password: "password"},
+			}}
+
+			artifetcher.Fetch(testserver.URL, "", S.Checksums{SHA256: "expected"}, S.ArtifactSignature{}, environment, response)
+			credentials := cache.LookupDigestCall.Received.Credentials
+			Expect(credentials).ToNot(BeEmpty())
+			Expect(credentials).ToNot(ContainSubstring("password"))
+
+			artifetcher.Fetch(testserver.URL, "", S.Checksums{SHA256: "expected"}, S.ArtifactSignature{}, otherEnvironment, response)
+			Expect(cache.LookupDigestCall.Received.Credentials).ToNot(BeEmpty())
+			Expect(cache.LookupDigestCall.Received.Credentials).ToNot(Equal(credentials))
+
+			artifetcher.Fetch(testserver.URL, "", S.Checksums{SHA256: "expected"}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(cache.LookupDigestCall.Received.Credentials).To(BeEmpty())
+		})
+
+		It("stores the artifact with the credentials it was downloaded with", func() {
+			environment := config.Environment{ArtifactCredentials: []config.ArtifactCredentials{
+				{Host: "127.0.0.1", Token: "token"},
+			}}
+			artifactSum := fmt.Sprintf("%x", sha256.Sum256([]byte("artifact contents")))
+
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{SHA256: artifactSum}, S.ArtifactSignature{}, environment, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cache.StoreCall.Received.Artifact.Credentials).ToNot(BeEmpty())
+			Expect(cache.StoreCall.Received.Artifact.Credentials).To(Equal(cache.LookupDigestCall.Received.Credentials))
+		})
+
+		It("does not look up checksums that were not given", func() {
+			artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+
+			Expect(cache.LookupDigestCall.Received.Checksums).To(BeZero())
+		})
+
+		It("does not look up the cache by an MD5 or SHA-1 digest alone", func() {
+			cache.LookupDigestCall.Returns.Found = true
+			cache.LookupDigestCall.Returns.Artifact = S.CachedArtifact{URL: "https://example.com/other.jar"}
+
+			artifetcher.Fetch(testserver.URL, "", S.Checksums{MD5: "md5", SHA1: "sha1"}, S.ArtifactSignature{}, config.Environment{}, response)
+
+			Expect(cache.LookupDigestCall.Received.Checksums).To(BeZero())
+			Expect(cache.CopyToCall.Received.Artifact.URL).ToNot(Equal("https://example.com/other.jar"))
+			Expect(requests).To(HaveLen(1))
+		})
+
+		Context("when the artifact was downloaded from the URL before", func() {
+			BeforeEach(func() {
+				cache.LookupURLCall.Returns.Found = true
+				cache.LookupURLCall.Returns.Artifact = S.CachedArtifact{
+					URL:          testserver.URL,
+					ETag:         `"etag-1"`,
+					LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+				}
+				cache.CopyToCall.Returns.Contents = []byte("cached contents")
+			})
+
+			It("revalidates it with a conditional request", func() {
+				artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+
+				Expect(requests).To(HaveLen(1))
+				Expect(requests[0].Header.Get("If-None-Match")).To(Equal(`"etag-1"`))
+				Expect(requests[0].Header.Get("If-Modified-Since")).To(Equal("Mon, 02 Jan 2006 15:04:05 GMT"))
+			})
+
+			It("uses the cached artifact when it has not changed", func() {
+				status = http.StatusNotModified
+
+				_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cache.CopyToCall.TimesCalled).To(Equal(1))
+				Expect(cache.StoreCall.TimesCalled).To(Equal(0))
+				Eventually(response).Should(Say("artifact cache hit for %s", testserver.URL))
+			})
+
+			It("downloads the artifact again when the cached artifact cannot be copied", func() {
+				status = http.StatusNotModified
+				cache.CopyToCall.Returns.Error = errors.New("it is no longer in the cache")
+
+				artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+
+				Expect(requests).To(HaveLen(2))
+				Expect(requests[1].Header.Get("If-None-Match")).To(BeEmpty())
+				Expect(requests[1].Header.Get("If-Modified-Since")).To(BeEmpty())
+			})
+
+			It("downloads and stores the artifact when it has changed", func() {
+				_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cache.CopyToCall.TimesCalled).To(Equal(0))
+				Expect(cache.StoreCall.TimesCalled).To(Equal(1))
+			})
+		})
+	})
+
 	Describe("fetching a zip file from a request", func() {
 		It("returns the path to the unzipped directory", func() {
-			extractor.UnzipCall.Returns.Error = nil
+			extractor.ExtractCall.Returns.Error = nil
 
 			body, err := os.Open("./fixtures/artifact-with-manifest.jar")
 			Expect(err).ToNot(HaveOccurred())
@@ -97,13 +657,13 @@ var _ = Describe("Artifetcher", func() {
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(path).To(ContainSubstring("deployadactyl-"))
-			Expect(extractor.UnzipCall.Received.Destination).To(Equal(path))
+			Expect(extractor.ExtractCall.Received.Destination).To(Equal(path))
 		})
 
 		Context("when extractor fails", func() {
 			It("returns an error", func() {
 				errorMessage := "test extract fail"
-				extractor.UnzipCall.Returns.Error = errors.New(errorMessage)
+				extractor.ExtractCall.Returns.Error = errors.New(errorMessage)
 
 				body, err := os.Open("./fixtures/artifact-with-manifest.jar")
 				Expect(err).ToNot(HaveOccurred())
@@ -119,4 +679,123 @@ var _ = Describe("Artifetcher", func() {
 			})
 		})
 	})
+
+	Describe("fetching an uploaded archive", func() {
+		It("extracts the archive with the manifest and returns its checksums", func() {
+			unzippedPath, checksums, err := artifetcher.FetchArchive(strings.NewReader("archive"), manifest, S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(unzippedPath).To(ContainSubstring("deployadactyl-unzipped-"))
+			Expect(extractor.ExtractCall.Received.Destination).To(Equal(unzippedPath))
+			Expect(extractor.ExtractCall.Received.Manifest).To(Equal(manifest))
+			Expect(checksums.SHA256).To(Equal("0eb3e36bfb24dcd9bb1d1bece1531216b59539a8fde17ee80224af0653c92aa3"))
+		})
+
+		It("does not extract the archive when a checksum does not match", func() {
+			_, _, err := artifetcher.FetchArchive(strings.NewReader("archive"), "", S.Checksums{MD5: "0123"}, S.ArtifactSignature{}, config.Environment{}, response)
+
+			Expect(err).To(BeAssignableToTypeOf(ChecksumMismatchError{}))
+			Expect(extractor.ExtractCall.Received.Source).To(BeEmpty())
+		})
+
+		It("returns an error when the environment requires a signature and there is none", func() {
+			environment := config.Environment{Name: "production", RequireSignedArtifacts: true}
+
+			_, _, err := artifetcher.FetchArchive(strings.NewReader("archive"), "", S.Checksums{}, S.ArtifactSignature{}, environment, response)
+
+			Expect(err).To(MatchError(MissingSignatureError{"uploaded archive", "production"}))
+		})
+	})
+
+	Describe("limiting artifacts to the working directory", func() {
+		var (
+			disk     *mocks.DiskChecker
+			artifact []byte
+			requests int
+		)
+
+		BeforeEach(func() {
+			var err error
+			artifact, err = ioutil.ReadFile("./fixtures/deployadactyl-fixture.jar")
+			Expect(err).ToNot(HaveOccurred())
+
+			disk = &mocks.DiskChecker{}
+			artifetcher.Disk = disk
+			requests = 0
+
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				http.ServeFile(w, r, "./fixtures/deployadactyl-fixture.jar")
+			}))
+		})
+
+		It("writes and extracts artifacts in the working directory", func() {
+			artifetcher.WorkingDirectory = "/working-directory"
+			Expect(af.MkdirAll(artifetcher.WorkingDirectory, 0700)).To(Succeed())
+
+			unzippedPath, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(extractor.ExtractCall.Received.Source).To(HavePrefix("/working-directory/deployadactyl-zip-"))
+			Expect(unzippedPath).To(HavePrefix("/working-directory/deployadactyl-unzipped-"))
+		})
+
+		It("checks the free space before and after the size of the artifact is known", func() {
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(disk.CheckCall.Received.Required).To(Equal([]int64{0, 2 * int64(len(artifact))}))
+		})
+
+		It("does not download the artifact when there is not enough free space", func() {
+			disk.CheckCall.Returns.Error = errors.New("not enough free space")
+
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+
+			Expect(err).To(MatchError("not enough free space"))
+			Expect(requests).To(Equal(0))
+		})
+
+		It("rejects an artifact with a Content-Length larger than the maximum artifact size", func() {
+			artifetcher.MaxArtifactSize = int64(len(artifact)) - 1
+
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+
+			Expect(err).To(MatchError(ArtifactTooLargeError{testserver.URL, artifetcher.MaxArtifactSize}))
+			Expect(extractor.ExtractCall.Received.Source).To(BeEmpty())
+		})
+
+		It("stops downloading an artifact without a Content-Length once it is larger than the maximum artifact size", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(artifact[:len(artifact)/2])
+				w.(http.Flusher).Flush()
+				w.Write(artifact[len(artifact)/2:])
+			}))
+			artifetcher.MaxArtifactSize = int64(len(artifact)) - 1
+
+			_, _, err := artifetcher.Fetch(testserver.URL, "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+
+			Expect(err).To(MatchError(ArtifactTooLargeError{testserver.URL, artifetcher.MaxArtifactSize}))
+		})
+
+		It("stops writing an uploaded archive once it is larger than the maximum artifact size", func() {
+			artifetcher.MaxArtifactSize = 3
+
+			_, _, err := artifetcher.FetchArchive(strings.NewReader("archive"), "", S.Checksums{}, S.ArtifactSignature{}, config.Environment{}, response)
+
+			Expect(err).To(MatchError(ArtifactTooLargeError{"uploaded archive", 3}))
+		})
+
+		It("rejects a request with a Content-Length larger than the maximum artifact size", func() {
+			artifetcher.MaxArtifactSize = int64(len(artifact)) - 1
+
+			// for go 1.7 change this to httptest
+			req, err := http.NewRequest("POST", "https://example.com", bytes.NewReader(artifact))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = artifetcher.FetchZipFromRequest(req)
+
+			Expect(err).To(MatchError(ArtifactTooLargeError{"uploaded archive", artifetcher.MaxArtifactSize}))
+		})
+	})
 })
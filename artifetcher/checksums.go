@@ -0,0 +1,61 @@
+package artifetcher
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"strings"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// digester hashes an artifact as it is written to disk so it does not have to be read twice.
+type digester struct {
+	sha256 hash.Hash
+	sha1   hash.Hash
+	md5    hash.Hash
+}
+
+func newDigester() digester {
+	return digester{
+		sha256: sha256.New(),
+		sha1:   sha1.New(),
+		md5:    md5.New(),
+	}
+}
+
+func (d digester) writer() io.Writer {
+	return io.MultiWriter(d.sha256, d.sha1, d.md5)
+}
+
+func (d digester) checksums() S.Checksums {
+	return S.Checksums{
+		SHA256: hex.EncodeToString(d.sha256.Sum(nil)),
+		SHA1:   hex.EncodeToString(d.sha1.Sum(nil)),
+		MD5:    hex.EncodeToString(d.md5.Sum(nil)),
+	}
+}
+
+// verifyChecksums compares every expected digest with the digest of the artifact.
+func verifyChecksums(expected, actual S.Checksums) error {
+	digests := []struct {
+		algorithm string
+		expected  string
+		actual    string
+	}{
+		{"sha256", expected.SHA256, actual.SHA256},
+		{"sha1", expected.SHA1, actual.SHA1},
+		{"md5", expected.MD5, actual.MD5},
+	}
+
+	for _, digest := range digests {
+		if digest.expected != "" && !strings.EqualFold(strings.TrimSpace(digest.expected), digest.actual) {
+			return ChecksumMismatchError{digest.algorithm, digest.expected, digest.actual}
+		}
+	}
+
+	return nil
+}
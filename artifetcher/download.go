@@ -0,0 +1,326 @@
+package artifetcher
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/compozed/deployadactyl/config"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+const progressInterval = 10 * time.Second
+
+// RetryPolicy describes how artifact downloads are retried when the connection fails or the
+// server responds with a 5xx status. Attempts is the total number of times an artifact is
+// requested. The wait before each retry starts at Backoff and doubles after every retry,
+// up to MaxBackoff.
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func (r RetryPolicy) nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if r.MaxBackoff > 0 && backoff > r.MaxBackoff {
+		return r.MaxBackoff
+	}
+	return backoff
+}
+
+// transientError wraps errors that are worth retrying the download for.
+type transientError struct {
+	Err error
+}
+
+func (e transientError) Error() string {
+	return e.Err.Error()
+}
+
+// transfer is the state of a download that is kept between attempts.
+type transfer struct {
+	url          string
+	started      bool
+	written      int64
+	size         int64
+	etag         string
+	lastModified string
+}
+
+func (t *transfer) start(resp *http.Response) {
+	t.started = true
+	t.size = resp.ContentLength
+	t.etag = resp.Header.Get("ETag")
+	t.lastModified = resp.Header.Get("Last-Modified")
+}
+
+// unchanged reports whether a full response is for the same artifact as the first response.
+func (t *transfer) unchanged(resp *http.Response) bool {
+	if t.etag == "" && t.lastModified == "" {
+		return t.size >= 0 && resp.ContentLength == t.size
+	}
+	return resp.Header.Get("ETag") == t.etag && resp.Header.Get("Last-Modified") == t.lastModified
+}
+
+// download GETs the artifact into the output. When useCache is set, an artifact that is cached for
+// the URL is revalidated with a conditional request and copied from the cache if it has not changed.
+// It is downloaded again when it cannot be copied from the cache.
+//
+// A download that fails part way through is retried with a range request for the rest of the
+// artifact. Servers that do not support range requests send the whole artifact again and the
+// bytes that were already written are skipped. Progress is written to the response.
+//
+// Returns the artifact to store in the cache once it has been verified, or nil when there is
+// nothing to store.
+func (a *Artifetcher) download(artifactURL string, credentials []config.ArtifactCredentials, out *output, useCache bool, response io.Writer) (*S.CachedArtifact, error) {
+	parsedURL, err := url.Parse(artifactURL)
+	if err != nil {
+		return nil, ArtifactoryRequestError{err}
+	}
+
+	artifactSource, ok := a.sources()[parsedURL.Scheme]
+	if !ok {
+		return nil, UnsupportedSchemeError{artifactURL, parsedURL.Scheme}
+	}
+
+	sourceCredentials := credentialsFor(parsedURL, credentials)
+
+	var (
+		cached S.CachedArtifact
+		found  bool
+	)
+	if useCache {
+		cached, found = a.lookupURL(artifactURL)
+	}
+
+	t := &transfer{url: artifactURL, size: -1}
+	progress := &progress{response: response, started: time.Now(), total: -1}
+	backoff := a.Retry.Backoff
+
+	var notModified bool
+	for attempt := 1; ; attempt++ {
+		notModified, err = a.attempt(artifactSource, sourceCredentials, t, cached, found, out.writer, progress, response)
+		if err == nil {
+			break
+		}
+
+		transient, ok := err.(transientError)
+		if !ok {
+			return nil, err
+		}
+		if attempt >= a.Retry.Attempts {
+			return nil, transient.Err
+		}
+
+		message := fmt.Sprintf("downloading %s failed: %s: retrying in %s (attempt %d of %d)", artifactURL, transient.Err, backoff, attempt+1, a.Retry.Attempts)
+		if t.written > 0 {
+			message = fmt.Sprintf("%s from %s", message, formatBytes(t.written))
+		}
+		a.Log.Error(message)
+		fmt.Fprintln(response, message)
+
+		time.Sleep(backoff)
+		backoff = a.Retry.nextBackoff(backoff)
+	}
+
+	if notModified {
+		a.reportCache(response, "artifact cache hit for %s: the artifact has not changed", artifactURL)
+
+		err = a.checkSpace(artifactURL, cached.Size)
+		if err != nil {
+			return nil, err
+		}
+
+		copied, err := a.copyFromCache(cached, S.Checksums{}, out)
+		if err != nil {
+			return nil, err
+		}
+		if copied {
+			return nil, nil
+		}
+		return a.download(artifactURL, credentials, out, false, response)
+	}
+
+	progress.finish()
+
+	if a.Cache == nil {
+		return nil, nil
+	}
+
+	return &S.CachedArtifact{
+		URL:          artifactURL,
+		ETag:         t.etag,
+		LastModified: t.lastModified,
+		Size:         t.written,
+		Credentials:  credentialsKey(sourceCredentials),
+	}, nil
+}
+
+// attempt makes one request for the artifact and copies it into the destination after the
+// bytes written by earlier attempts. Errors worth retrying are returned as a transientError.
+//
+// Returns true when the cached artifact has not been modified.
+func (a *Artifetcher) attempt(artifactSource I.ArtifactSource, credentials config.ArtifactCredentials, t *transfer, cached S.CachedArtifact, found bool, destination io.Writer, progress *progress, response io.Writer) (bool, error) {
+	req, err := http.NewRequest("GET", t.url, nil)
+	if err != nil {
+		return false, ArtifactoryRequestError{err}
+	}
+
+	if !t.started && found {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	if t.written > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", t.written))
+		if t.etag != "" {
+			req.Header.Set("If-Range", t.etag)
+		} else if t.lastModified != "" {
+			req.Header.Set("If-Range", t.lastModified)
+		}
+	}
+
+	resp, err := artifactSource.Get(req, credentials)
+	if err != nil {
+		return false, transientError{GetUrlError{t.url, err}}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && found && !t.started:
+		return true, nil
+
+	case resp.StatusCode == http.StatusPartialContent && t.written > 0:
+		contentRange := resp.Header.Get("Content-Range")
+		if !strings.HasPrefix(contentRange, fmt.Sprintf("bytes %d-", t.written)) {
+			return false, ResumeDownloadError{t.url, t.written, contentRange}
+		}
+
+	case resp.StatusCode == http.StatusOK && t.written > 0:
+		if !t.unchanged(resp) {
+			return false, ArtifactChangedError{t.url}
+		}
+
+		_, err = io.CopyN(ioutil.Discard, resp.Body, t.written)
+		if err != nil {
+			return false, transientError{GetUrlError{t.url, err}}
+		}
+
+	case resp.StatusCode == http.StatusOK:
+		err = a.checkSpace(t.url, resp.ContentLength)
+		if err != nil {
+			return false, err
+		}
+
+		if !t.started && a.Cache != nil {
+			a.reportCache(response, "artifact cache miss for %s: downloading the artifact", t.url)
+		}
+		t.start(resp)
+		progress.total = t.size
+
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return false, transientError{GetStatusError{t.url, resp.Status}}
+
+	default:
+		return false, GetStatusError{t.url, resp.Status}
+	}
+
+	body := &bodyReader{reader: resp.Body}
+	written, err := io.Copy(io.MultiWriter(destination, progress), body)
+	t.written += written
+	if err != nil && err == body.err {
+		return false, transientError{GetUrlError{t.url, err}}
+	}
+	if tooLarge, ok := err.(ArtifactTooLargeError); ok {
+		return false, tooLarge
+	}
+	if err != nil {
+		return false, WriteResponseError{err}
+	}
+
+	if t.size >= 0 && t.written < t.size {
+		return false, transientError{IncompleteDownloadError{t.url, t.written, t.size}}
+	}
+
+	return false, nil
+}
+
+// bodyReader remembers the error from reading a response body, so it can be told apart from
+// an error writing the artifact to disk.
+type bodyReader struct {
+	reader io.Reader
+	err    error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+// progress writes how much of an artifact has been downloaded to the response.
+type progress struct {
+	response io.Writer
+	started  time.Time
+	reported time.Time
+	written  int64
+	total    int64
+}
+
+func (p *progress) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+
+	if time.Since(p.reported) >= progressInterval && time.Since(p.started) >= progressInterval {
+		p.reported = time.Now()
+
+		if p.total >= 0 {
+			fmt.Fprintf(p.response, "downloaded %s of %s (%s/s)\n", formatBytes(p.written), formatBytes(p.total), formatBytes(p.rate()))
+		} else {
+			fmt.Fprintf(p.response, "downloaded %s (%s/s)\n", formatBytes(p.written), formatBytes(p.rate()))
+		}
+	}
+
+	return len(b), nil
+}
+
+func (p *progress) finish() {
+	elapsed := time.Since(p.started)
+	fmt.Fprintf(p.response, "downloaded %s in %s (%s/s)\n", formatBytes(p.written), elapsed-elapsed%time.Millisecond, formatBytes(p.rate()))
+}
+
+func (p *progress) rate() int64 {
+	seconds := time.Since(p.started).Seconds()
+	if seconds <= 0 {
+		return p.written
+	}
+	return int64(float64(p.written) / seconds)
+}
+
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	value := float64(bytes)
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		value /= unit
+		if value < unit || suffix == "GB" {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+
+	return fmt.Sprintf("%d B", bytes)
+}
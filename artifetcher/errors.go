@@ -59,3 +59,85 @@ type UnzipError struct {
 func (e UnzipError) Error() string {
 	return fmt.Sprintf("cannot unzip artifact: %s", e.Err)
 }
+
+type ChecksumMismatchError struct {
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("artifact %s checksum does not match: expected %s but was %s", e.Algorithm, e.Expected, e.Actual)
+}
+
+type UnsupportedSchemeError struct {
+	Url    string
+	Scheme string
+}
+
+func (e UnsupportedSchemeError) Error() string {
+	return fmt.Sprintf("cannot fetch artifact: %s: the %s scheme is not supported", e.Url, e.Scheme)
+}
+
+type ResumeDownloadError struct {
+	Url          string
+	Offset       int64
+	ContentRange string
+}
+
+func (e ResumeDownloadError) Error() string {
+	return fmt.Sprintf("cannot resume download: %s: asked for bytes from %d but got %s", e.Url, e.Offset, e.ContentRange)
+}
+
+type ArtifactChangedError struct {
+	Url string
+}
+
+func (e ArtifactChangedError) Error() string {
+	return fmt.Sprintf("cannot resume download: %s: the artifact changed while it was downloading", e.Url)
+}
+
+type IncompleteDownloadError struct {
+	Url      string
+	Written  int64
+	Expected int64
+}
+
+func (e IncompleteDownloadError) Error() string {
+	return fmt.Sprintf("incomplete download: %s: got %d of %d bytes", e.Url, e.Written, e.Expected)
+}
+
+type MissingSignatureError struct {
+	Url         string
+	Environment string
+}
+
+func (e MissingSignatureError) Error() string {
+	return fmt.Sprintf("cannot fetch artifact: %s: environment %s requires an artifact_signature or artifact_signature_url", e.Url, e.Environment)
+}
+
+type DecodeSignatureError struct {
+	Url string
+}
+
+func (e DecodeSignatureError) Error() string {
+	return fmt.Sprintf("cannot decode the signature of artifact: %s: use a base64 encoded ed25519 signature of the artifact's sha256 digest", e.Url)
+}
+
+type InvalidSignatureError struct {
+	Url         string
+	Environment string
+}
+
+func (e InvalidSignatureError) Error() string {
+	return fmt.Sprintf("artifact signature is not valid: %s: it was not signed by a trusted key of environment %s", e.Url, e.Environment)
+}
+
+type ArtifactTooLargeError struct {
+	Url   string
+	Limit int64
+}
+
+func (e ArtifactTooLargeError) Error() string {
+	return fmt.Sprintf("artifact is too large: %s: the maximum artifact size is %s", e.Url, formatBytes(e.Limit))
+}
@@ -74,3 +74,81 @@ type WriteFileError struct {
 func (e WriteFileError) Error() string {
 	return fmt.Sprintf("cannot write to file: %s: %s", e.SavedLocation, e.Err)
 }
+
+type UnsupportedArchiveError struct {
+	Source string
+}
+
+func (e UnsupportedArchiveError) Error() string {
+	return fmt.Sprintf("cannot extract %s: it is not a zip, jar, war, tar, tar.gz or tar.bz2 archive", e.Source)
+}
+
+type OpenArchiveError struct {
+	Source string
+	Format string
+	Err    error
+}
+
+func (e OpenArchiveError) Error() string {
+	return fmt.Sprintf("cannot open %s archive: %s: %s", e.Format, e.Source, e.Err)
+}
+
+type ReadTarError struct {
+	Err error
+}
+
+func (e ReadTarError) Error() string {
+	return fmt.Sprintf("cannot read tar archive: %s", e.Err)
+}
+
+type AbsolutePathError struct {
+	FileName string
+}
+
+func (e AbsolutePathError) Error() string {
+	return fmt.Sprintf("cannot extract %s: archive entries must not have absolute paths", e.FileName)
+}
+
+type PathTraversalError struct {
+	FileName    string
+	Destination string
+}
+
+func (e PathTraversalError) Error() string {
+	return fmt.Sprintf("cannot extract %s: it would be written outside of %s", e.FileName, e.Destination)
+}
+
+type LinkEscapeError struct {
+	FileName string
+	Target   string
+}
+
+func (e LinkEscapeError) Error() string {
+	return fmt.Sprintf("cannot extract %s: it links to %s which is outside of the archive", e.FileName, e.Target)
+}
+
+type TooManyEntriesError struct {
+	Limit int
+}
+
+func (e TooManyEntriesError) Error() string {
+	return fmt.Sprintf("cannot extract archive: it has more than %d entries", e.Limit)
+}
+
+type ArchiveTooLargeError struct {
+	Limit int64
+}
+
+func (e ArchiveTooLargeError) Error() string {
+	return fmt.Sprintf("cannot extract archive: it is larger than %d bytes when uncompressed", e.Limit)
+}
+
+type CreateLinkError struct {
+	SavedLocation string
+	Target        string
+	Err           error
+}
+
+func (e CreateLinkError) Error() string {
+	return fmt.Sprintf("cannot create link: %s to %s: %s", e.SavedLocation, e.Target, e.Err)
+}
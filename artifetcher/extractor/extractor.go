@@ -1,26 +1,68 @@
-// Package extractor unzips artifacts.
+// Package extractor extracts artifacts.
 package extractor
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/spf13/afero"
 )
 
+const (
+	zipFormat     = "zip"
+	tarFormat     = "tar"
+	gzipFormat    = "tar.gz"
+	bzip2Format   = "tar.bz2"
+	unknownFormat = ""
+
+	tarMagicOffset = 257
+
+	// DefaultMaxEntries is the number of entries an archive may contain when MaxEntries is not set.
+	DefaultMaxEntries = 100000
+
+	// DefaultMaxBytes is the number of uncompressed bytes an archive may contain when MaxBytes is not set.
+	DefaultMaxBytes int64 = 2 << 30
+)
+
 // Extractor has a file system from which files are extracted from.
+// MaxEntries and MaxBytes limit the number of entries and uncompressed bytes
+// extracted from an archive, so an archive bomb cannot fill the disk.
 type Extractor struct {
 	Log        I.Logger
 	FileSystem *afero.Afero
+	MaxEntries int
+	MaxBytes   int64
+}
+
+// extraction tracks what has been extracted from a single archive.
+// links are the locations of the symbolic links that have been created, and traversed are
+// the locations that the targets of those links pass through, so no link can be created there later.
+type extraction struct {
+	destination string
+	entries     int
+	written     int64
+	maxEntries  int
+	maxBytes    int64
+	links       map[string]bool
+	traversed   map[string]bool
 }
 
-// Unzip unzips from source into destination.
-// If there is no manifest provided to this function, it will attempt to read a manifest file within the zip file.
-func (e *Extractor) Unzip(source, destination, manifest string) error {
+// Extract extracts the archive at source into destination. The format of the archive is detected
+// from its contents, so zip, jar and war files, tar files, and gzip or bzip2 compressed tar files
+// can be extracted whatever their names are.
+// If there is no manifest provided to this function, it will attempt to read a manifest file within the archive.
+func (e *Extractor) Extract(source, destination, manifest string) error {
 	e.Log.Info("extracting application")
 	e.Log.Debugf(`parameters for extractor:
 	source: %+v
@@ -37,21 +79,35 @@ func (e *Extractor) Unzip(source, destination, manifest string) error {
 	}
 	defer file.Close()
 
-	fileStat, err := file.Stat()
+	format, err := detectFormat(file)
 	if err != nil {
 		return err
 	}
+	e.Log.Debugf("detected %s archive", format)
 
-	reader, err := zip.NewReader(file, fileStat.Size())
-	if err != nil {
-		return OpenZipError{source, err}
-	}
+	state := e.newExtraction(destination)
 
-	for _, file := range reader.File {
-		err := e.unzipFile(destination, file)
+	switch format {
+	case zipFormat:
+		err = e.extractZip(file, source, state)
+	case tarFormat:
+		err = e.extractTar(file, state)
+	case gzipFormat:
+		var reader *gzip.Reader
+		reader, err = gzip.NewReader(file)
 		if err != nil {
-			return ExtractFileError{file.Name, err}
+			return OpenArchiveError{source, format, err}
 		}
+		defer reader.Close()
+
+		err = e.extractTar(reader, state)
+	case bzip2Format:
+		err = e.extractTar(bzip2.NewReader(file), state)
+	default:
+		return UnsupportedArchiveError{source}
+	}
+	if err != nil {
+		return err
 	}
 
 	if manifest != "" {
@@ -71,7 +127,92 @@ func (e *Extractor) Unzip(source, destination, manifest string) error {
 	return nil
 }
 
-func (e *Extractor) unzipFile(destination string, file *zip.File) error {
+// detectFormat reads the magic bytes at the start of an archive and rewinds it.
+// Compressed archives must contain a tar file.
+func detectFormat(file afero.File) (string, error) {
+	header := make([]byte, tarMagicOffset+5)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return unknownFormat, err
+	}
+	header = header[:n]
+
+	if _, err := file.Seek(0, 0); err != nil {
+		return unknownFormat, err
+	}
+
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return zipFormat, nil
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return gzipFormat, nil
+	case bytes.HasPrefix(header, []byte("BZh")):
+		return bzip2Format, nil
+	case isTar(header):
+		return tarFormat, nil
+	}
+
+	return unknownFormat, nil
+}
+
+func isTar(header []byte) bool {
+	return len(header) >= tarMagicOffset+5 && string(header[tarMagicOffset:tarMagicOffset+5]) == "ustar"
+}
+
+func (e *Extractor) newExtraction(destination string) *extraction {
+	state := &extraction{
+		destination: path.Clean(destination),
+		maxEntries:  e.MaxEntries,
+		maxBytes:    e.MaxBytes,
+		links:       map[string]bool{},
+		traversed:   map[string]bool{},
+	}
+
+	if state.maxEntries <= 0 {
+		state.maxEntries = DefaultMaxEntries
+	}
+	if state.maxBytes <= 0 {
+		state.maxBytes = DefaultMaxBytes
+	}
+
+	return state
+}
+
+func (e *Extractor) extractZip(file afero.File, source string, state *extraction) error {
+	fileStat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	reader, err := zip.NewReader(file, fileStat.Size())
+	if err != nil {
+		return OpenZipError{source, err}
+	}
+
+	if len(reader.File) > state.maxEntries {
+		return TooManyEntriesError{state.maxEntries}
+	}
+
+	for _, file := range reader.File {
+		err := e.unzipFile(state, file)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *Extractor) unzipFile(state *extraction, file *zip.File) error {
+	savedLocation, err := state.add(file.Name)
+	if err != nil {
+		return err
+	}
+
+	if file.UncompressedSize64 > uint64(state.maxBytes-state.written) {
+		return ArchiveTooLargeError{state.maxBytes}
+	}
+
 	contents, err := file.Open()
 	if err != nil {
 		return ExtractFileError{file.Name, err}
@@ -79,27 +220,291 @@ func (e *Extractor) unzipFile(destination string, file *zip.File) error {
 	defer contents.Close()
 
 	if file.FileInfo().IsDir() {
-		return nil
+		return e.makeDirectory(state, savedLocation, file.Mode())
+	}
+
+	if file.Mode()&os.ModeSymlink != 0 {
+		target, err := readLinkTarget(contents)
+		if err != nil {
+			return ExtractFileError{file.Name, err}
+		}
+
+		return e.makeLink(state, file.Name, savedLocation, target)
+	}
+
+	return e.writeFile(state, savedLocation, file.Mode(), contents)
+}
+
+func (e *Extractor) extractTar(source io.Reader, state *extraction) error {
+	reader := tar.NewReader(bufio.NewReader(source))
+
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return ReadTarError{err}
+		}
+
+		savedLocation, err := state.add(header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeReg, tar.TypeRegA:
+			err = e.writeFile(state, savedLocation, header.FileInfo().Mode(), reader)
+		case tar.TypeDir:
+			err = e.makeDirectory(state, savedLocation, header.FileInfo().Mode())
+		case tar.TypeSymlink:
+			err = e.makeLink(state, header.Name, savedLocation, header.Linkname)
+		case tar.TypeLink:
+			err = e.makeHardLink(state, header.Name, savedLocation, header.Linkname, header.FileInfo().Mode())
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// add counts an entry towards the limits and returns where it will be extracted.
+func (state *extraction) add(name string) (string, error) {
+	state.entries++
+	if state.entries > state.maxEntries {
+		return "", TooManyEntriesError{state.maxEntries}
+	}
+
+	return state.resolve(name)
+}
+
+// resolve rejects entries with absolute paths, paths outside of the destination and paths
+// beneath a symbolic link from the archive, since where those end up depends on the link.
+func (state *extraction) resolve(name string) (string, error) {
+	if path.IsAbs(name) || strings.HasPrefix(name, `\`) {
+		return "", AbsolutePathError{name}
+	}
+
+	savedLocation := path.Join(state.destination, name)
+	if !state.contains(savedLocation) {
+		return "", PathTraversalError{name, state.destination}
+	}
+
+	for parent := path.Dir(savedLocation); parent != state.destination && state.contains(parent); parent = path.Dir(parent) {
+		if state.links[parent] {
+			return "", PathTraversalError{name, state.destination}
+		}
+	}
+
+	return savedLocation, nil
+}
+
+// checkLink rejects symbolic links that point at an absolute path or outside of the destination.
+// The target is followed one element at a time, so it cannot pass through a link from the archive,
+// where it would end up somewhere else than its path says, and every element must stay inside of
+// the destination. The locations it passes through are returned.
+func (state *extraction) checkLink(name, target string) ([]string, error) {
+	if path.IsAbs(target) {
+		return nil, LinkEscapeError{name, target}
+	}
+
+	var (
+		location  = path.Join(state.destination, path.Dir(name))
+		elements  = strings.Split(target, "/")
+		traversed []string
+	)
+
+	for i, element := range elements {
+		switch element {
+		case "", ".":
+			continue
+		case "..":
+			location = path.Dir(location)
+		default:
+			location = path.Join(location, element)
+		}
+
+		if !state.contains(location) {
+			return nil, LinkEscapeError{name, target}
+		}
+
+		if i < len(elements)-1 {
+			if state.links[location] {
+				return nil, LinkEscapeError{name, target}
+			}
+			traversed = append(traversed, location)
+		}
+	}
+
+	return traversed, nil
+}
+
+// makeLink creates a symbolic link that stays inside of the destination.
+// File systems that cannot create links skip them.
+func (e *Extractor) makeLink(state *extraction, name, savedLocation, target string) error {
+	traversed, err := state.checkLink(name, target)
+	if err != nil {
+		return err
+	}
+
+	if state.traversed[savedLocation] {
+		return LinkEscapeError{name, target}
 	}
 
-	savedLocation := path.Join(destination, file.Name)
 	directory := path.Dir(savedLocation)
 	err = e.FileSystem.MkdirAll(directory, 0755)
 	if err != nil {
 		return MakeDirectoryError{directory, err}
 	}
 
-	mode := file.Mode()
-	newFile, err := e.FileSystem.OpenFile(savedLocation, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	var symlink func(oldname, newname string) error
+	switch fs := e.FileSystem.Fs.(type) {
+	case *afero.OsFs:
+		symlink = os.Symlink
+	case linker:
+		symlink = fs.SymlinkIfPossible
+	default:
+		e.Log.Infof("skipping link %s: the file system cannot create links", name)
+		return nil
+	}
+
+	e.FileSystem.Remove(savedLocation)
+
+	err = symlink(target, savedLocation)
+	if err != nil {
+		return CreateLinkError{savedLocation, target, err}
+	}
+
+	state.links[savedLocation] = true
+	for _, location := range traversed {
+		state.traversed[location] = true
+	}
+	return nil
+}
+
+// makeHardLink links an entry to a file that was extracted before it. File systems that
+// cannot create links get a copy of the file instead, so the file is not missing from the app.
+func (e *Extractor) makeHardLink(state *extraction, name, savedLocation, target string, mode os.FileMode) error {
+	targetLocation, err := state.resolve(target)
+	if err != nil {
+		return LinkEscapeError{name, target}
+	}
+	if targetLocation == savedLocation {
+		return nil
+	}
+
+	e.removeLink(state, savedLocation)
+
+	if _, ok := e.FileSystem.Fs.(*afero.OsFs); ok {
+		directory := path.Dir(savedLocation)
+		err = e.FileSystem.MkdirAll(directory, 0755)
+		if err != nil {
+			return MakeDirectoryError{directory, err}
+		}
+
+		e.FileSystem.Remove(savedLocation)
+
+		err = os.Link(targetLocation, savedLocation)
+		if err != nil {
+			return CreateLinkError{savedLocation, target, err}
+		}
+		return nil
+	}
+
+	source, err := e.FileSystem.Open(targetLocation)
+	if err != nil {
+		return CreateLinkError{savedLocation, target, err}
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return CreateLinkError{savedLocation, target, err}
+	}
+	if info.IsDir() {
+		return CreateLinkError{savedLocation, target, fmt.Errorf("%s is a directory", target)}
+	}
+
+	return e.writeFile(state, savedLocation, mode, source)
+}
+
+// removeLink removes a symbolic link from the archive that is where an entry is about to be
+// written, so the entry replaces the link instead of being written wherever the link points.
+func (e *Extractor) removeLink(state *extraction, savedLocation string) {
+	if state.links[savedLocation] {
+		e.FileSystem.Remove(savedLocation)
+		delete(state.links, savedLocation)
+	}
+}
+
+// linker is implemented by file systems that can create symbolic links.
+type linker interface {
+	SymlinkIfPossible(oldname, newname string) error
+}
+
+// makeDirectory creates a directory from the archive, so empty directories are kept.
+// The owner can always write to it so that the files inside of it can be extracted.
+func (e *Extractor) makeDirectory(state *extraction, savedLocation string, mode os.FileMode) error {
+	e.removeLink(state, savedLocation)
+
+	perm := mode.Perm() | 0700
+
+	err := e.FileSystem.MkdirAll(savedLocation, perm)
+	if err != nil {
+		return MakeDirectoryError{savedLocation, err}
+	}
+
+	err = e.FileSystem.Chmod(savedLocation, perm)
+	if err != nil {
+		return MakeDirectoryError{savedLocation, err}
+	}
+
+	return nil
+}
+
+func (state *extraction) contains(location string) bool {
+	return location == state.destination || strings.HasPrefix(location, state.destination+"/")
+}
+
+func readLinkTarget(contents io.Reader) (string, error) {
+	target, err := ioutil.ReadAll(io.LimitReader(contents, 4096))
+	return string(target), err
+}
+
+func (e *Extractor) writeFile(state *extraction, savedLocation string, mode os.FileMode, contents io.Reader) error {
+	e.removeLink(state, savedLocation)
+
+	directory := path.Dir(savedLocation)
+	err := e.FileSystem.MkdirAll(directory, 0755)
+	if err != nil {
+		return MakeDirectoryError{directory, err}
+	}
+
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+
+	newFile, err := e.FileSystem.OpenFile(savedLocation, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {
 		return OpenFileError{savedLocation, err}
 	}
 	defer newFile.Close()
 
-	_, err = io.Copy(newFile, contents)
+	err = e.FileSystem.Chmod(savedLocation, perm)
 	if err != nil {
+		return OpenFileError{savedLocation, err}
+	}
+
+	remaining := state.maxBytes - state.written
+	written, err := io.CopyN(newFile, contents, remaining+1)
+	state.written += written
+	if err != nil && err != io.EOF {
 		return WriteFileError{savedLocation, err}
 	}
+	if written > remaining {
+		return ArchiveTooLargeError{state.maxBytes}
+	}
 
 	return nil
 }
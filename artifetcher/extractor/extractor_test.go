@@ -1,7 +1,12 @@
 package extractor_test
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"io/ioutil"
+	"os"
 	"path"
 
 	. "github.com/onsi/ginkgo"
@@ -34,7 +39,7 @@ var _ = Describe("Extracting", func() {
 		file = "/artifact.jar"
 		destination = "../fixtures/deployadactyl-fixture"
 		af = &afero.Afero{Fs: afero.NewMemMapFs()}
-		extractor = Extractor{logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "extractor_test"), af}
+		extractor = Extractor{Log: logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "extractor_test"), FileSystem: af}
 
 		fileBytes, err := ioutil.ReadFile("../fixtures/deployadactyl-fixture.jar")
 		Expect(err).ToNot(HaveOccurred())
@@ -47,7 +52,7 @@ var _ = Describe("Extracting", func() {
 	})
 
 	It("unzips the artifact", func() {
-		Expect(extractor.Unzip(file, destination, "")).To(Succeed())
+		Expect(extractor.Extract(file, destination, "")).To(Succeed())
 
 		extractedFile, err := af.ReadFile(path.Join(destination, "index.html"))
 		Expect(err).ToNot(HaveOccurred())
@@ -57,7 +62,7 @@ var _ = Describe("Extracting", func() {
 
 	Context("when manifest is an empty string", func() {
 		It("leaves the manifest alone", func() {
-			Expect(extractor.Unzip(file, destination, "")).To(Succeed())
+			Expect(extractor.Extract(file, destination, "")).To(Succeed())
 
 			extractedManifest, err := af.ReadFile(path.Join(destination, "manifest.yml"))
 			Expect(err).ToNot(HaveOccurred())
@@ -69,7 +74,7 @@ var _ = Describe("Extracting", func() {
 	Context("when manifest is not an empty string", func() {
 		It("unzips the artifact and overwrites the manifest", func() {
 			manifestContents := "manifestContents-" + randomizer.StringRunes(10)
-			Expect(extractor.Unzip(file, destination, manifestContents)).To(Succeed())
+			Expect(extractor.Extract(file, destination, manifestContents)).To(Succeed())
 
 			extractedManifest, err := af.ReadFile(path.Join(destination, "manifest.yml"))
 			Expect(err).ToNot(HaveOccurred())
@@ -78,13 +83,396 @@ var _ = Describe("Extracting", func() {
 		})
 	})
 
+	Context("when the artifact is a tar archive", func() {
+		It("extracts a tar", func() {
+			Expect(af.WriteFile("/artifact.tar", tarball(), 0644)).To(Succeed())
+
+			Expect(extractor.Extract("/artifact.tar", destination, "")).To(Succeed())
+
+			extractedManifest, err := af.ReadFile(path.Join(destination, "manifest.yml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(extractedManifest).To(BeEquivalentTo(deployadactylManifest))
+		})
+
+		It("extracts a gzipped tar", func() {
+			compressed := &bytes.Buffer{}
+			gzipWriter := gzip.NewWriter(compressed)
+			_, err := gzipWriter.Write(tarball())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gzipWriter.Close()).To(Succeed())
+
+			Expect(af.WriteFile("/artifact.tgz", compressed.Bytes(), 0644)).To(Succeed())
+
+			Expect(extractor.Extract("/artifact.tgz", destination, "")).To(Succeed())
+
+			extractedFile, err := af.ReadFile(path.Join(destination, "index.html"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(extractedFile).To(ContainSubstring("public/assets/images/pterodactyl.png"))
+		})
+
+		It("extracts a bzipped tar", func() {
+			fileBytes, err := ioutil.ReadFile("../fixtures/deployadactyl-fixture.tar.bz2")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(af.WriteFile("/artifact.tar.bz2", fileBytes, 0644)).To(Succeed())
+
+			Expect(extractor.Extract("/artifact.tar.bz2", destination, "")).To(Succeed())
+
+			extractedFile, err := af.ReadFile(path.Join(destination, "index.html"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(extractedFile).To(ContainSubstring("public/assets/images/pterodactyl.png"))
+		})
+
+		It("overwrites the manifest", func() {
+			Expect(af.WriteFile("/artifact.tar", tarball(), 0644)).To(Succeed())
+
+			manifestContents := "manifestContents-" + randomizer.StringRunes(10)
+			Expect(extractor.Extract("/artifact.tar", destination, manifestContents)).To(Succeed())
+
+			extractedManifest, err := af.ReadFile(path.Join(destination, "manifest.yml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(extractedManifest).To(BeEquivalentTo(manifestContents))
+		})
+	})
+
+	It("returns an error when the archive format is not supported", func() {
+		Expect(af.WriteFile("/artifact.txt", []byte("not an archive"), 0644)).To(Succeed())
+
+		err := extractor.Extract("/artifact.txt", destination, "")
+
+		Expect(err).To(MatchError(UnsupportedArchiveError{"/artifact.txt"}))
+	})
+
+	Describe("protecting the file system", func() {
+		It("rejects zip entries that would be written outside of the destination", func() {
+			Expect(af.WriteFile("/evil.zip", zipball(zipEntry{"../../evil.txt", 0644, "evil"}), 0644)).To(Succeed())
+
+			err := extractor.Extract("/evil.zip", destination, "")
+
+			Expect(err).To(MatchError(PathTraversalError{"../../evil.txt", destination}))
+			Expect(af.Exists("../evil.txt")).To(BeFalse())
+		})
+
+		It("rejects zip entries with absolute paths", func() {
+			Expect(af.WriteFile("/evil.zip", zipball(zipEntry{"/etc/evil.txt", 0644, "evil"}), 0644)).To(Succeed())
+
+			err := extractor.Extract("/evil.zip", destination, "")
+
+			Expect(err).To(MatchError(AbsolutePathError{"/etc/evil.txt"}))
+			Expect(af.Exists("/etc/evil.txt")).To(BeFalse())
+		})
+
+		It("rejects zip symlinks that point outside of the destination", func() {
+			Expect(af.WriteFile("/evil.zip", zipball(zipEntry{"config/link", os.ModeSymlink | 0777, "../../../etc/passwd"}), 0644)).To(Succeed())
+
+			err := extractor.Extract("/evil.zip", destination, "")
+
+			Expect(err).To(MatchError(LinkEscapeError{"config/link", "../../../etc/passwd"}))
+		})
+
+		It("allows symlinks that stay inside of the destination", func() {
+			Expect(af.WriteFile("/safe.zip", zipball(zipEntry{"config/link", os.ModeSymlink | 0777, "../index.html"}), 0644)).To(Succeed())
+
+			Expect(extractor.Extract("/safe.zip", destination, "")).To(Succeed())
+		})
+
+		It("rejects tar entries that would be written outside of the destination", func() {
+			Expect(af.WriteFile("/evil.tar", tarball(&tar.Header{Name: "app/../../evil.txt", Mode: 0644}), 0644)).To(Succeed())
+
+			err := extractor.Extract("/evil.tar", destination, "")
+
+			Expect(err).To(MatchError(PathTraversalError{"app/../../evil.txt", destination}))
+		})
+
+		It("rejects tar symlinks with absolute targets", func() {
+			Expect(af.WriteFile("/evil.tar", tarball(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}), 0644)).To(Succeed())
+
+			err := extractor.Extract("/evil.tar", destination, "")
+
+			Expect(err).To(MatchError(LinkEscapeError{"link", "/etc/passwd"}))
+		})
+
+		It("copies the file of a tar hard link when the file system cannot create links", func() {
+			body := `<html><img src="public/assets/images/pterodactyl.png"></html>`
+			archive := tarball(
+				&tar.Header{Name: "index.html", Mode: 0644, Size: int64(len(body))},
+				&tar.Header{Name: "public/index.html", Typeflag: tar.TypeLink, Linkname: "index.html"},
+			)
+			Expect(af.WriteFile("/app.tar", archive, 0644)).To(Succeed())
+
+			Expect(extractor.Extract("/app.tar", destination, "")).To(Succeed())
+
+			contents, err := af.ReadFile(path.Join(destination, "public/index.html"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(contents)).To(Equal(body))
+		})
+
+		It("returns an error for tar hard links to files that were not extracted", func() {
+			Expect(af.WriteFile("/app.tar", tarball(&tar.Header{Name: "link", Typeflag: tar.TypeLink, Linkname: "missing.txt"}), 0644)).To(Succeed())
+
+			err := extractor.Extract("/app.tar", destination, "")
+
+			Expect(err).To(BeAssignableToTypeOf(CreateLinkError{}))
+		})
+
+		It("rejects tar hard links to files outside of the destination", func() {
+			Expect(af.WriteFile("/evil.tar", tarball(&tar.Header{Name: "link", Typeflag: tar.TypeLink, Linkname: "../evil.txt"}), 0644)).To(Succeed())
+
+			err := extractor.Extract("/evil.tar", destination, "")
+
+			Expect(err).To(MatchError(LinkEscapeError{"link", "../evil.txt"}))
+		})
+	})
+
+	Describe("preserving the contents of archives", func() {
+		It("keeps the mode of files in zips", func() {
+			Expect(af.WriteFile("/app.zip", zipball(zipEntry{"bin/run", 0755, "#!/bin/sh"}, zipEntry{"config.yml", 0600, "secret"}), 0644)).To(Succeed())
+
+			Expect(extractor.Extract("/app.zip", destination, "")).To(Succeed())
+
+			info, err := af.Stat(path.Join(destination, "bin/run"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+
+			info, err = af.Stat(path.Join(destination, "config.yml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		})
+
+		It("keeps the mode of files in tars", func() {
+			Expect(af.WriteFile("/app.tar", tarball(&tar.Header{Name: "bin/run", Mode: 0755}), 0644)).To(Succeed())
+
+			Expect(extractor.Extract("/app.tar", destination, "")).To(Succeed())
+
+			info, err := af.Stat(path.Join(destination, "bin/run"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+		})
+
+		It("creates empty directories", func() {
+			Expect(af.WriteFile("/app.zip", zipball(zipEntry{"tmp/", os.ModeDir | 0755, ""}), 0644)).To(Succeed())
+			Expect(af.WriteFile("/app.tar", tarball(&tar.Header{Name: "logs/", Typeflag: tar.TypeDir, Mode: 0750}), 0644)).To(Succeed())
+
+			Expect(extractor.Extract("/app.zip", destination, "")).To(Succeed())
+			Expect(extractor.Extract("/app.tar", destination, "")).To(Succeed())
+
+			Expect(af.IsDir(path.Join(destination, "tmp"))).To(BeTrue())
+
+			info, err := af.Stat(path.Join(destination, "logs"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.IsDir()).To(BeTrue())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0750)))
+		})
+
+		Context("when the file system can create links", func() {
+			BeforeEach(func() {
+				var err error
+				destination, err = ioutil.TempDir("", "extractor-test-")
+				Expect(err).ToNot(HaveOccurred())
+
+				osFileSystem := &afero.Afero{Fs: afero.NewOsFs()}
+				extractor = Extractor{Log: logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "extractor_test"), FileSystem: osFileSystem}
+
+				af = osFileSystem
+			})
+
+			It("recreates symlinks from zips", func() {
+				archive := zipball(zipEntry{"index.html", 0644, "hello"}, zipEntry{"public/index.html", os.ModeSymlink | 0777, "../index.html"})
+				Expect(af.WriteFile(path.Join(destination, "app.zip"), archive, 0644)).To(Succeed())
+
+				Expect(extractor.Extract(path.Join(destination, "app.zip"), path.Join(destination, "app"), "")).To(Succeed())
+
+				target, err := os.Readlink(path.Join(destination, "app/public/index.html"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(target).To(Equal("../index.html"))
+			})
+
+			It("recreates symlinks from tars", func() {
+				archive := tarball(&tar.Header{Name: "current", Typeflag: tar.TypeSymlink, Linkname: "releases/1"})
+				Expect(af.WriteFile(path.Join(destination, "app.tar"), archive, 0644)).To(Succeed())
+
+				Expect(extractor.Extract(path.Join(destination, "app.tar"), path.Join(destination, "app"), "")).To(Succeed())
+
+				target, err := os.Readlink(path.Join(destination, "app/current"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(target).To(Equal("releases/1"))
+			})
+
+			It("rejects symlinks whose targets pass through another symlink", func() {
+				archive := tarball(
+					&tar.Header{Name: "d/l", Typeflag: tar.TypeSymlink, Linkname: ".."},
+					&tar.Header{Name: "d/e", Typeflag: tar.TypeSymlink, Linkname: "l/../pwned.txt"},
+					&tar.Header{Name: "d/e", Typeflag: tar.TypeReg, Mode: 0644},
+				)
+				Expect(af.WriteFile(path.Join(destination, "app.tar"), archive, 0644)).To(Succeed())
+
+				err := extractor.Extract(path.Join(destination, "app.tar"), path.Join(destination, "app"), "")
+
+				Expect(err).To(MatchError(LinkEscapeError{"d/e", "l/../pwned.txt"}))
+				Expect(af.Exists(path.Join(destination, "pwned.txt"))).To(BeFalse())
+			})
+
+			It("rejects symlinks where the target of an earlier symlink passes through", func() {
+				archive := tarball(
+					&tar.Header{Name: "d/e", Typeflag: tar.TypeSymlink, Linkname: "x/../../pwned.txt"},
+					&tar.Header{Name: "d/x", Typeflag: tar.TypeSymlink, Linkname: ".."},
+				)
+				Expect(af.WriteFile(path.Join(destination, "app.tar"), archive, 0644)).To(Succeed())
+
+				err := extractor.Extract(path.Join(destination, "app.tar"), path.Join(destination, "app"), "")
+
+				Expect(err).To(MatchError(LinkEscapeError{"d/x", ".."}))
+			})
+
+			It("replaces a symlink with a later entry at the same path instead of writing through it", func() {
+				archive := tarball(
+					&tar.Header{Name: "config", Typeflag: tar.TypeSymlink, Linkname: "shared"},
+					&tar.Header{Name: "config", Typeflag: tar.TypeDir, Mode: 0755},
+					&tar.Header{Name: "settings.yml", Typeflag: tar.TypeSymlink, Linkname: "config/secret.yml"},
+					&tar.Header{Name: "settings.yml", Typeflag: tar.TypeReg, Mode: 0644},
+				)
+				Expect(af.WriteFile(path.Join(destination, "app.tar"), archive, 0644)).To(Succeed())
+
+				Expect(extractor.Extract(path.Join(destination, "app.tar"), path.Join(destination, "app"), "")).To(Succeed())
+
+				info, err := os.Lstat(path.Join(destination, "app/config"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.IsDir()).To(BeTrue())
+
+				info, err = os.Lstat(path.Join(destination, "app/settings.yml"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.Mode().IsRegular()).To(BeTrue())
+
+				Expect(af.Exists(path.Join(destination, "app/shared"))).To(BeFalse())
+				Expect(af.Exists(path.Join(destination, "app/config/secret.yml"))).To(BeFalse())
+			})
+
+			It("recreates hard links from tars", func() {
+				archive := tarball(
+					&tar.Header{Name: "index.html", Mode: 0644, Size: int64(len(`<html><img src="public/assets/images/pterodactyl.png"></html>`))},
+					&tar.Header{Name: "public/index.html", Typeflag: tar.TypeLink, Linkname: "index.html"},
+				)
+				Expect(af.WriteFile(path.Join(destination, "app.tar"), archive, 0644)).To(Succeed())
+
+				Expect(extractor.Extract(path.Join(destination, "app.tar"), path.Join(destination, "app"), "")).To(Succeed())
+
+				original, err := os.Stat(path.Join(destination, "app/index.html"))
+				Expect(err).ToNot(HaveOccurred())
+				linked, err := os.Stat(path.Join(destination, "app/public/index.html"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(os.SameFile(original, linked)).To(BeTrue())
+			})
+
+			It("rejects entries beneath a symlink", func() {
+				archive := zipball(zipEntry{"up", os.ModeSymlink | 0777, "."}, zipEntry{"up/escape", os.ModeSymlink | 0777, ".."})
+				Expect(af.WriteFile(path.Join(destination, "app.zip"), archive, 0644)).To(Succeed())
+
+				err := extractor.Extract(path.Join(destination, "app.zip"), path.Join(destination, "app"), "")
+
+				Expect(err).To(MatchError(PathTraversalError{"up/escape", path.Join(destination, "app")}))
+			})
+		})
+	})
+
+	Describe("limiting the size of archives", func() {
+		It("rejects archives with more entries than the limit", func() {
+			extractor.MaxEntries = 1
+
+			err := extractor.Extract(file, destination, "")
+
+			Expect(err).To(MatchError(TooManyEntriesError{1}))
+		})
+
+		It("rejects tar archives with more entries than the limit", func() {
+			extractor.MaxEntries = 1
+			Expect(af.WriteFile("/artifact.tar", tarball(), 0644)).To(Succeed())
+
+			err := extractor.Extract("/artifact.tar", destination, "")
+
+			Expect(err).To(MatchError(TooManyEntriesError{1}))
+		})
+
+		It("rejects archives that are larger than the limit when uncompressed", func() {
+			extractor.MaxBytes = 10
+
+			err := extractor.Extract(file, destination, "")
+
+			Expect(err).To(MatchError(ArchiveTooLargeError{10}))
+		})
+
+		It("counts the bytes written rather than the sizes in tar headers", func() {
+			extractor.MaxBytes = 10
+			Expect(af.WriteFile("/artifact.tar", tarball(), 0644)).To(Succeed())
+
+			err := extractor.Extract("/artifact.tar", destination, "")
+
+			Expect(err).To(MatchError(ArchiveTooLargeError{10}))
+		})
+	})
+
 	It("can not unzip an invalid file", func() {
 		file := "../fixtures/bad-deployadactyl-fixture.tgz"
 		destination = "../fixtures/bad-deployadactyl-fixture"
 		af = &afero.Afero{Fs: afero.NewMemMapFs()}
 
-		extractor := Extractor{logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "extractor_test"), af}
+		extractor := Extractor{Log: logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "extractor_test"), FileSystem: af}
 
-		Expect(extractor.Unzip(file, destination, "")).ToNot(Succeed())
+		Expect(extractor.Extract(file, destination, "")).ToNot(Succeed())
 	})
 })
+
+// tarball builds a tar archive from headers, or from the deployadactyl fixture when no headers are given.
+func tarball(headers ...*tar.Header) []byte {
+	buffer := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buffer)
+
+	files := []struct {
+		name string
+		body string
+	}{
+		{"index.html", `<html><img src="public/assets/images/pterodactyl.png"></html>`},
+		{"manifest.yml", deployadactylManifest},
+	}
+
+	if len(headers) == 0 {
+		for _, file := range files {
+			headers = append(headers, &tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.body))})
+		}
+	}
+
+	for i, header := range headers {
+		Expect(tarWriter.WriteHeader(header)).To(Succeed())
+
+		if header.Size > 0 {
+			_, err := tarWriter.Write([]byte(files[i].body))
+			Expect(err).ToNot(HaveOccurred())
+		}
+	}
+	Expect(tarWriter.Close()).To(Succeed())
+
+	return buffer.Bytes()
+}
+
+type zipEntry struct {
+	name string
+	mode os.FileMode
+	body string
+}
+
+func zipball(entries ...zipEntry) []byte {
+	buffer := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buffer)
+
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name}
+		header.SetMode(entry.mode)
+
+		writer, err := zipWriter.CreateHeader(header)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = writer.Write([]byte(entry.body))
+		Expect(err).ToNot(HaveOccurred())
+	}
+	Expect(zipWriter.Close()).To(Succeed())
+
+	return buffer.Bytes()
+}
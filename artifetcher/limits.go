@@ -0,0 +1,61 @@
+package artifetcher
+
+import "io"
+
+// checkSpace rejects an artifact that is larger than MaxArtifactSize, or that would leave too
+// little free space in the working directory once it is downloaded and extracted. The extracted
+// files are assumed to be about as large as the artifact. Artifacts of unknown size have a
+// negative size, and only the free space that must be left is checked for them.
+func (a *Artifetcher) checkSpace(artifactName string, size int64) error {
+	if size < 0 {
+		size = 0
+	}
+
+	if a.MaxArtifactSize > 0 && size > a.MaxArtifactSize {
+		return ArtifactTooLargeError{artifactName, a.MaxArtifactSize}
+	}
+
+	if a.Disk == nil {
+		return nil
+	}
+
+	return a.Disk.Check(2 * size)
+}
+
+// limit stops writing an artifact to disk once it is larger than MaxArtifactSize, so an
+// artifact without a Content-Length, or with a wrong one, cannot fill the disk.
+func (a *Artifetcher) limit(writer io.Writer, artifactName string) io.Writer {
+	if a.MaxArtifactSize <= 0 {
+		return writer
+	}
+	return &limitWriter{writer: writer, artifactName: artifactName, limit: a.MaxArtifactSize}
+}
+
+type limitWriter struct {
+	writer       io.Writer
+	artifactName string
+	limit        int64
+	written      int64
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if l.written+int64(len(p)) > l.limit {
+		return 0, ArtifactTooLargeError{l.artifactName, l.limit}
+	}
+
+	n, err := l.writer.Write(p)
+	l.written += int64(n)
+	return n, err
+}
+
+// copyArchive writes an uploaded archive to disk, keeping the error when it is too large.
+func copyArchive(destination io.Writer, archive io.Reader) error {
+	_, err := io.Copy(destination, archive)
+	if tooLarge, ok := err.(ArtifactTooLargeError); ok {
+		return tooLarge
+	}
+	if err != nil {
+		return WriteResponseError{err}
+	}
+	return nil
+}
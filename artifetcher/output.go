@@ -0,0 +1,90 @@
+package artifetcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/compozed/deployadactyl/config"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/spf13/afero"
+)
+
+// output is the temp file an artifact is written to. The artifact is hashed and its size is
+// limited while it is written.
+type output struct {
+	file         afero.File
+	artifactName string
+	digester     digester
+	writer       io.Writer
+	limit        func(io.Writer, string) io.Writer
+}
+
+func (a *Artifetcher) newOutput(file afero.File, artifactName string) *output {
+	o := &output{file: file, artifactName: artifactName, limit: a.limit}
+	o.start()
+	return o
+}
+
+func (o *output) start() {
+	o.digester = newDigester()
+	o.writer = o.limit(io.MultiWriter(o.file, o.digester.writer()), o.artifactName)
+}
+
+// reset throws away what has been written, so the artifact can be written again from the start.
+func (o *output) reset() error {
+	err := o.file.Truncate(0)
+	if err != nil {
+		return CreateTempFileError{err}
+	}
+
+	_, err = o.file.Seek(0, os.SEEK_SET)
+	if err != nil {
+		return CreateTempFileError{err}
+	}
+
+	o.start()
+	return nil
+}
+
+// copyFromCache writes a cached artifact to the output. When it cannot be read from the cache, or it
+// does not have the expected SHA-256 digest, the output is reset so the artifact can be downloaded instead.
+//
+// Returns true when the cached artifact was written.
+func (a *Artifetcher) copyFromCache(cached S.CachedArtifact, expected S.Checksums, out *output) (bool, error) {
+	err := a.Cache.CopyTo(cached, out.writer)
+	if err == nil && (expected.SHA256 == "" || strings.EqualFold(strings.TrimSpace(expected.SHA256), out.digester.checksums().SHA256)) {
+		return true, nil
+	}
+
+	if err != nil {
+		a.Log.Errorf("could not copy the cached artifact for %s: %s: downloading it instead", out.artifactName, err)
+	} else {
+		a.Log.Errorf("the cached artifact for %s does not have the expected checksum: downloading it instead", out.artifactName)
+	}
+
+	return false, out.reset()
+}
+
+// credentialsKey identifies the credentials an artifact is downloaded with, without keeping them.
+// It is empty when there are no credentials.
+func credentialsKey(credentials config.ArtifactCredentials) string {
+	if credentials == (config.ArtifactCredentials{}) {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strings.ToLower(credentials.Host),
+		credentials.Username,
+		credentials.Password,
+		credentials.Token,
+		credentials.AccessKeyID,
+		credentials.SecretAccessKey,
+		credentials.Region,
+		credentials.Endpoint,
+	}, "\x00")))
+
+	return hex.EncodeToString(sum[:])
+}
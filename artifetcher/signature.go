@@ -0,0 +1,103 @@
+package artifetcher
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/compozed/deployadactyl/config"
+	S "github.com/compozed/deployadactyl/structs"
+	"golang.org/x/crypto/ed25519"
+)
+
+// maxSignatureSize is more than enough for a base64 encoded signature and a trailing newline.
+const maxSignatureSize = 1024
+
+// verifySignature checks the signature of the artifact against the trusted keys of the environment.
+// The signature is an ed25519 signature of the raw SHA-256 digest of the artifact, so artifacts
+// of any size can be checked with the digest computed while they were downloaded.
+// Signatures are not checked in environments without trusted keys.
+func (a *Artifetcher) verifySignature(artifactURL string, checksums S.Checksums, signature S.ArtifactSignature, environment config.Environment, response io.Writer) error {
+	if signature == (S.ArtifactSignature{}) {
+		return nil
+	}
+
+	if len(environment.TrustedKeys) == 0 {
+		a.Log.Infof("not checking the signature of %s: environment %s has no trusted keys", artifactURL, environment.Name)
+		return nil
+	}
+
+	encoded := signature.Signature
+	if encoded == "" {
+		var err error
+		encoded, err = a.downloadSignature(signature.URL, environment.ArtifactCredentials)
+		if err != nil {
+			return err
+		}
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(decoded) != ed25519.SignatureSize {
+		return DecodeSignatureError{artifactURL}
+	}
+
+	digest, err := hex.DecodeString(checksums.SHA256)
+	if err != nil {
+		return DecodeSignatureError{artifactURL}
+	}
+
+	for _, trustedKey := range environment.TrustedKeys {
+		key, err := base64.StdEncoding.DecodeString(trustedKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			continue
+		}
+
+		if ed25519.Verify(ed25519.PublicKey(key), digest, decoded) {
+			a.Log.Infof("artifact %s is signed by trusted key %s", artifactURL, trustedKey)
+			fmt.Fprintf(response, "artifact signature verified with trusted key %s\n", trustedKey)
+			return nil
+		}
+	}
+
+	return InvalidSignatureError{artifactURL, environment.Name}
+}
+
+// downloadSignature GETs a detached signature from the source for the scheme of its URL.
+func (a *Artifetcher) downloadSignature(signatureURL string, credentials []config.ArtifactCredentials) (string, error) {
+	parsedURL, err := url.Parse(signatureURL)
+	if err != nil {
+		return "", ArtifactoryRequestError{err}
+	}
+
+	artifactSource, ok := a.sources()[parsedURL.Scheme]
+	if !ok {
+		return "", UnsupportedSchemeError{signatureURL, parsedURL.Scheme}
+	}
+
+	req, err := http.NewRequest("GET", signatureURL, nil)
+	if err != nil {
+		return "", ArtifactoryRequestError{err}
+	}
+
+	resp, err := artifactSource.Get(req, credentialsFor(parsedURL, credentials))
+	if err != nil {
+		return "", GetUrlError{signatureURL, err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", GetStatusError{signatureURL, resp.Status}
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSignatureSize))
+	if err != nil {
+		return "", GetUrlError{signatureURL, err}
+	}
+
+	return string(body), nil
+}
@@ -0,0 +1,29 @@
+package source
+
+import "fmt"
+
+type InvalidS3URLError struct {
+	URL string
+}
+
+func (e InvalidS3URLError) Error() string {
+	return fmt.Sprintf("cannot download %s: s3 artifact URLs must look like s3://bucket/key", e.URL)
+}
+
+type InvalidS3EndpointError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e InvalidS3EndpointError) Error() string {
+	return fmt.Sprintf("cannot parse s3 endpoint: %s: %s", e.Endpoint, e.Err)
+}
+
+type FileOutsideRootError struct {
+	Path string
+	Root string
+}
+
+func (e FileOutsideRootError) Error() string {
+	return fmt.Sprintf("cannot read %s: file artifacts must be in %s", e.Path, e.Root)
+}
@@ -0,0 +1,78 @@
+package source
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/spf13/afero"
+)
+
+// File serves artifacts from file URLs under Root, such as a volume shared with a build server.
+type File struct {
+	FileSystem *afero.Afero
+	Root       string
+}
+
+// Get opens the file the request points to. A file that has not been modified since the
+// If-Modified-Since header of the request is not opened.
+//
+// Returns a response with a status of 200, 304 or 404 and an error when the file is outside of Root.
+func (f File) Get(request *http.Request, credentials config.ArtifactCredentials) (*http.Response, error) {
+	root := path.Clean(f.Root)
+	location := path.Clean(request.URL.Path)
+
+	if location != root && !strings.HasPrefix(location, strings.TrimSuffix(root, "/")+"/") {
+		return nil, FileOutsideRootError{location, root}
+	}
+
+	info, err := f.FileSystem.Stat(location)
+	if os.IsNotExist(err) {
+		return response(request, http.StatusNotFound), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return response(request, http.StatusNotFound), nil
+	}
+
+	lastModified := info.ModTime().UTC().Format(http.TimeFormat)
+
+	if since, err := time.Parse(http.TimeFormat, request.Header.Get("If-Modified-Since")); err == nil && !info.ModTime().Truncate(time.Second).After(since) {
+		resp := response(request, http.StatusNotModified)
+		resp.Header.Set("Last-Modified", lastModified)
+		return resp, nil
+	}
+
+	file, err := f.FileSystem.Open(location)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := response(request, http.StatusOK)
+	resp.Body = file
+	resp.ContentLength = info.Size()
+	resp.Header.Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	resp.Header.Set("Last-Modified", lastModified)
+
+	return resp, nil
+}
+
+func response(request *http.Request, statusCode int) *http.Response {
+	return &http.Response{
+		Status:     strconv.Itoa(statusCode) + " " + http.StatusText(statusCode),
+		StatusCode: statusCode,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    request,
+	}
+}
@@ -0,0 +1,69 @@
+package source_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+
+	. "github.com/compozed/deployadactyl/artifetcher/source"
+	"github.com/compozed/deployadactyl/config"
+)
+
+var _ = Describe("File", func() {
+	var (
+		source File
+		af     *afero.Afero
+	)
+
+	BeforeEach(func() {
+		af = &afero.Afero{Fs: afero.NewMemMapFs()}
+		source = File{FileSystem: af, Root: "/shared/artifacts"}
+
+		Expect(af.WriteFile("/shared/artifacts/app.jar", []byte("artifact"), 0644)).To(Succeed())
+		Expect(af.WriteFile("/etc/passwd", []byte("root"), 0644)).To(Succeed())
+	})
+
+	It("reads artifacts under the root", func() {
+		request, _ := http.NewRequest("GET", "file:///shared/artifacts/app.jar", nil)
+
+		resp, err := source.Get(request, config.ArtifactCredentials{})
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(string(body)).To(Equal("artifact"))
+		Expect(resp.Header.Get("Last-Modified")).ToNot(BeEmpty())
+	})
+
+	It("returns not found for missing artifacts", func() {
+		request, _ := http.NewRequest("GET", "file:///shared/artifacts/missing.jar", nil)
+
+		resp, err := source.Get(request, config.ArtifactCredentials{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns not modified when the artifact has not changed", func() {
+		request, _ := http.NewRequest("GET", "file:///shared/artifacts/app.jar", nil)
+		request.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+
+		resp, err := source.Get(request, config.ArtifactCredentials{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(resp.StatusCode).To(Equal(http.StatusNotModified))
+	})
+
+	It("does not read files outside of the root", func() {
+		request, _ := http.NewRequest("GET", "file:///shared/artifacts/../../etc/passwd", nil)
+
+		_, err := source.Get(request, config.ArtifactCredentials{})
+
+		Expect(err).To(MatchError(FileOutsideRootError{"/etc/passwd", "/shared/artifacts"}))
+	})
+})
@@ -0,0 +1,24 @@
+package source
+
+import (
+	"net/http"
+
+	"github.com/compozed/deployadactyl/config"
+)
+
+// HTTP downloads artifacts from http and https URLs.
+type HTTP struct {
+	Client *http.Client
+}
+
+// Get sends the request with basic auth when the credentials have a username,
+// or with a bearer token when they have a token.
+func (h HTTP) Get(request *http.Request, credentials config.ArtifactCredentials) (*http.Response, error) {
+	if credentials.Username != "" {
+		request.SetBasicAuth(credentials.Username, credentials.Password)
+	} else if credentials.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+credentials.Token)
+	}
+
+	return h.Client.Do(request)
+}
@@ -0,0 +1,64 @@
+package source_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/compozed/deployadactyl/artifetcher/source"
+	"github.com/compozed/deployadactyl/config"
+)
+
+var _ = Describe("HTTP", func() {
+	var (
+		source        HTTP
+		testserver    *httptest.Server
+		authorization string
+	)
+
+	BeforeEach(func() {
+		source = HTTP{Client: NewClient(DefaultTimeout)}
+		authorization = ""
+
+		testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+		}))
+	})
+
+	AfterEach(func() {
+		testserver.Close()
+	})
+
+	It("downloads anonymously without credentials", func() {
+		request, _ := http.NewRequest("GET", testserver.URL, nil)
+
+		resp, err := source.Get(request, config.ArtifactCredentials{})
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(authorization).To(BeEmpty())
+	})
+
+	It("uses basic auth when there is a username", func() {
+		request, _ := http.NewRequest("GET", testserver.URL, nil)
+
+		resp, err := source.Get(request, config.ArtifactCredentials{Username: "username", Password: "password"})
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(authorization).To(Equal("Basic dXNlcm5hbWU6cGFzc3dvcmQ="))
+	})
+
+	It("uses a bearer token when there is a token", func() {
+		request, _ := http.NewRequest("GET", testserver.URL, nil)
+
+		resp, err := source.Get(request, config.ArtifactCredentials{Token: "token"})
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(authorization).To(Equal("Bearer token"))
+	})
+})
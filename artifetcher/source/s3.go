@@ -0,0 +1,168 @@
+package source
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/compozed/deployadactyl/config"
+)
+
+const (
+	defaultS3Region = "us-east-1"
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+
+	// emptyPayloadHash is the SHA256 of the empty body of a GET request.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// S3 downloads artifacts from s3://bucket/key URLs. Requests are made to the Endpoint of the
+// credentials with path style addressing, so any S3 compatible store can be used, and are
+// signed with AWS signature version 4 when the credentials have an access key.
+type S3 struct {
+	Client *http.Client
+	Now    func() time.Time
+}
+
+// Get rewrites the request to the bucket and key on the endpoint, signs it and sends it.
+func (s S3) Get(request *http.Request, credentials config.ArtifactCredentials) (*http.Response, error) {
+	bucket := request.URL.Host
+	key := strings.TrimPrefix(request.URL.Path, "/")
+	if bucket == "" || key == "" {
+		return nil, InvalidS3URLError{request.URL.String()}
+	}
+
+	region := credentials.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+
+	endpoint, err := url.Parse(s3Endpoint(credentials.Endpoint, region))
+	if err != nil {
+		return nil, InvalidS3EndpointError{credentials.Endpoint, err}
+	}
+
+	path := strings.TrimSuffix(endpoint.Path, "/") + "/" + bucket + "/" + key
+	request.URL = &url.URL{
+		Scheme:   endpoint.Scheme,
+		Host:     endpoint.Host,
+		Path:     path,
+		RawPath:  awsEscapePath(path),
+		RawQuery: request.URL.RawQuery,
+	}
+	request.Host = endpoint.Host
+
+	if credentials.AccessKeyID != "" {
+		s.sign(request, credentials, region)
+	}
+
+	return s.Client.Do(request)
+}
+
+func s3Endpoint(endpoint, region string) string {
+	if endpoint != "" {
+		return endpoint
+	}
+	if region == defaultS3Region {
+		return "https://s3.amazonaws.com"
+	}
+	return fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+}
+
+// sign adds the headers of AWS signature version 4 to the request.
+// Only the host and x-amz headers are signed, so conditional and range headers can be added
+// to a signed request.
+func (s S3) sign(request *http.Request, credentials config.ArtifactCredentials, region string) {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+
+	timestamp := now().UTC().Format("20060102T150405Z")
+	date := timestamp[:8]
+
+	request.Header.Set("X-Amz-Date", timestamp)
+	request.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", request.Host, emptyPayloadHash, timestamp)
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		awsEscapePath(request.URL.Path),
+		canonicalQuery(request.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, "s3", "aws4_request"}, "/")
+	hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3Algorithm, timestamp, scope, hex.EncodeToString(hashedRequest[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parameters []string
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			parameters = append(parameters, awsEscape(key)+"="+awsEscape(value))
+		}
+	}
+
+	return strings.Join(parameters, "&")
+}
+
+// awsEscapePath URI encodes each segment of a path the way AWS signature version 4 does.
+// The path is sent the same way, so the path that is signed is the path the server sees.
+func awsEscapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsEscape URI encodes every byte of a value except the unreserved characters
+// A-Z, a-z, 0-9, '-', '.', '_' and '~'.
+func awsEscape(value string) string {
+	var escaped bytes.Buffer
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
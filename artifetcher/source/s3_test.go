@@ -0,0 +1,105 @@
+package source_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/compozed/deployadactyl/artifetcher/source"
+	"github.com/compozed/deployadactyl/config"
+)
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (r roundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	return r(request)
+}
+
+var _ = Describe("S3", func() {
+	var (
+		source      S3
+		received    *http.Request
+		credentials config.ArtifactCredentials
+	)
+
+	BeforeEach(func() {
+		received = nil
+		credentials = config.ArtifactCredentials{
+			Host:            "artifacts",
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			Endpoint:        "https://s3.example.com",
+		}
+
+		source = S3{
+			Client: &http.Client{Transport: roundTripper(func(request *http.Request) (*http.Response, error) {
+				received = request
+				return nil, errors.New("stop")
+			})},
+			Now: func() time.Time { return time.Date(2017, 3, 23, 12, 0, 0, 0, time.UTC) },
+		}
+	})
+
+	It("requests the bucket and key from the endpoint", func() {
+		request, _ := http.NewRequest("GET", "s3://artifacts/releases/app.jar", nil)
+
+		source.Get(request, credentials)
+
+		Expect(received.URL.String()).To(Equal("https://s3.example.com/artifacts/releases/app.jar"))
+	})
+
+	It("signs the request with AWS signature version 4", func() {
+		request, _ := http.NewRequest("GET", "s3://artifacts/releases/app.jar", nil)
+
+		source.Get(request, credentials)
+
+		Expect(received.Header.Get("X-Amz-Date")).To(Equal("20170323T120000Z"))
+		Expect(received.Header.Get("Authorization")).To(Equal("AWS4-HMAC-SHA256 " +
+			"Credential=AKIDEXAMPLE/20170323/us-east-1/s3/aws4_request, " +
+			"SignedHeaders=host;x-amz-content-sha256;x-amz-date, " +
+			"Signature=24b69665b0fd99bd6b27c12ca5a3b35c38cc969ad19f46fef292c8093240041d"))
+	})
+
+	It("URI encodes every character of the key except unreserved characters", func() {
+		request, _ := http.NewRequest("GET", "s3://artifacts/releases/app+1=2,3;4@5!6$7%208*(9)'~.jar", nil)
+
+		source.Get(request, credentials)
+
+		Expect(received.URL.EscapedPath()).To(Equal("/artifacts/releases/app%2B1%3D2%2C3%3B4%405%216%247%208%2A%289%29%27~.jar"))
+		Expect(received.Header.Get("Authorization")).To(Equal("AWS4-HMAC-SHA256 " +
+			"Credential=AKIDEXAMPLE/20170323/us-east-1/s3/aws4_request, " +
+			"SignedHeaders=host;x-amz-content-sha256;x-amz-date, " +
+			"Signature=dccccdaadd310302bc28af7e73b92fe01860425257bba1fd3fcdfc07b2df7e13"))
+	})
+
+	It("uses the endpoint of the region when there is no endpoint", func() {
+		credentials.Endpoint = ""
+		credentials.Region = "eu-west-1"
+		request, _ := http.NewRequest("GET", "s3://artifacts/app.jar", nil)
+
+		source.Get(request, credentials)
+
+		Expect(received.URL.String()).To(Equal("https://s3.eu-west-1.amazonaws.com/artifacts/app.jar"))
+		Expect(received.Header.Get("Authorization")).To(ContainSubstring("/eu-west-1/s3/aws4_request"))
+	})
+
+	It("does not sign requests without an access key", func() {
+		request, _ := http.NewRequest("GET", "s3://artifacts/app.jar", nil)
+
+		source.Get(request, config.ArtifactCredentials{})
+
+		Expect(received.URL.String()).To(Equal("https://s3.amazonaws.com/artifacts/app.jar"))
+		Expect(received.Header.Get("Authorization")).To(BeEmpty())
+	})
+
+	It("returns an error when the URL has no key", func() {
+		request, _ := http.NewRequest("GET", "s3://artifacts", nil)
+
+		_, err := source.Get(request, credentials)
+
+		Expect(err).To(MatchError(InvalidS3URLError{"s3://artifacts"}))
+	})
+})
@@ -0,0 +1,47 @@
+// Package source downloads artifacts over the URL schemes deployadactyl supports.
+package source
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/spf13/afero"
+)
+
+// DefaultTimeout is how long a request for an artifact can take when no timeout is configured.
+const DefaultTimeout = 4 * time.Minute
+
+// NewClient returns the client used to download artifacts. A request for an artifact,
+// including reading the artifact, fails after timeout. A timeout of zero means no timeout.
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Dial: (&net.Dialer{
+				Timeout:   60 * time.Second,
+				KeepAlive: 60 * time.Second,
+			}).Dial,
+			TLSHandshakeTimeout:   15 * time.Second,
+			ResponseHeaderTimeout: 15 * time.Second,
+			ExpectContinueTimeout: 2 * time.Second,
+		},
+	}
+}
+
+// Defaults returns the artifact sources for the http, https and s3 schemes.
+// The file scheme is only supported when there is a fileRoot to serve artifacts from.
+func Defaults(client *http.Client, fileSystem *afero.Afero, fileRoot string) map[string]I.ArtifactSource {
+	sources := map[string]I.ArtifactSource{
+		"http":  HTTP{Client: client},
+		"https": HTTP{Client: client},
+		"s3":    S3{Client: client},
+	}
+
+	if fileRoot != "" {
+		sources["file"] = File{FileSystem: fileSystem, Root: fileRoot}
+	}
+
+	return sources
+}
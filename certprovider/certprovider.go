@@ -0,0 +1,73 @@
+// Package certprovider keeps the TLS certificate of the server and reloads it from its files.
+package certprovider
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// Provider returns the current certificate to TLS handshakes. The certificate and key files are
+// read again by Reload, and the new certificate is used for new connections once it has been loaded.
+type Provider struct {
+	CertFile string
+	KeyFile  string
+	Log      I.Logger
+	mutex    sync.RWMutex
+	current  *tls.Certificate
+}
+
+// New reads the certificate and key files and returns a Provider for them.
+func New(certFile, keyFile string, log I.Logger) (*Provider, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		Log:      log,
+		current:  &certificate,
+	}, nil
+}
+
+// GetCertificate returns the current certificate. It is used as the GetCertificate of a tls.Config.
+func (p *Provider) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.current, nil
+}
+
+// Reload reads the certificate and key files and replaces the current certificate with them.
+// The current certificate is kept when they cannot be loaded.
+func (p *Provider) Reload() error {
+	certificate, err := tls.LoadX509KeyPair(p.CertFile, p.KeyFile)
+	if err != nil {
+		p.Log.Errorf("keeping the current certificate: cannot reload %s: %s", p.CertFile, err)
+		return ReloadError{p.CertFile, err}
+	}
+
+	p.mutex.Lock()
+	p.current = &certificate
+	p.mutex.Unlock()
+
+	p.Log.Infof("reloaded certificate from %s", p.CertFile)
+	return nil
+}
+
+// ReloadOn reloads the certificate every time a signal is received, until stop is closed.
+func (p *Provider) ReloadOn(signals <-chan os.Signal, stop <-chan struct{}) {
+	for {
+		select {
+		case sig := <-signals:
+			p.Log.Infof("received %s: reloading certificate from %s", sig, p.CertFile)
+			p.Reload()
+		case <-stop:
+			return
+		}
+	}
+}
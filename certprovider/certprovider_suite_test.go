@@ -0,0 +1,13 @@
+package certprovider_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCertprovider(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Certprovider Suite")
+}
@@ -0,0 +1,130 @@
+package certprovider_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"syscall"
+	"time"
+
+	. "github.com/compozed/deployadactyl/certprovider"
+	"github.com/compozed/deployadactyl/logger"
+	logging "github.com/op/go-logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Certprovider", func() {
+	var (
+		directory string
+		certFile  string
+		keyFile   string
+		logBuffer *Buffer
+		provider  *Provider
+	)
+
+	writeCertificate := func(commonName string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: commonName},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+
+		certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		Expect(err).ToNot(HaveOccurred())
+
+		keyBytes, err := x509.MarshalECPrivateKey(key)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600)).To(Succeed())
+	}
+
+	commonName := func() string {
+		certificate, err := provider.GetCertificate(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		Expect(err).ToNot(HaveOccurred())
+
+		return leaf.Subject.CommonName
+	}
+
+	BeforeEach(func() {
+		var err error
+		directory, err = ioutil.TempDir("", "deployadactyl-certprovider-")
+		Expect(err).ToNot(HaveOccurred())
+
+		certFile = path.Join(directory, "server.crt")
+		keyFile = path.Join(directory, "server.key")
+		writeCertificate("first")
+
+		logBuffer = NewBuffer()
+
+		provider, err = New(certFile, keyFile, logger.DefaultLogger(logBuffer, logging.DEBUG, "certprovider_test"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(directory)
+	})
+
+	It("provides the certificate from the files", func() {
+		Expect(commonName()).To(Equal("first"))
+	})
+
+	It("returns an error when the files cannot be loaded at startup", func() {
+		_, err := New(path.Join(directory, "missing.crt"), keyFile, logger.DefaultLogger(logBuffer, logging.DEBUG, "certprovider_test"))
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("reloading", func() {
+		It("replaces the certificate with the certificate in the files", func() {
+			writeCertificate("second")
+
+			Expect(provider.Reload()).To(Succeed())
+
+			Expect(commonName()).To(Equal("second"))
+			Eventually(logBuffer).Should(Say("reloaded certificate from %s", certFile))
+		})
+
+		It("keeps the current certificate when the files cannot be loaded", func() {
+			Expect(ioutil.WriteFile(keyFile, []byte("not a key"), 0600)).To(Succeed())
+
+			err := provider.Reload()
+
+			Expect(err).To(BeAssignableToTypeOf(ReloadError{}))
+			Expect(commonName()).To(Equal("first"))
+			Eventually(logBuffer).Should(Say("keeping the current certificate"))
+		})
+	})
+
+	Describe("reloading on a signal", func() {
+		It("reloads the certificate every time a signal is received", func() {
+			signals := make(chan os.Signal)
+			stop := make(chan struct{})
+			defer close(stop)
+
+			go provider.ReloadOn(signals, stop)
+
+			writeCertificate("second")
+			signals <- syscall.SIGHUP
+
+			Eventually(commonName).Should(Equal("second"))
+		})
+	})
+})
@@ -0,0 +1,12 @@
+package certprovider
+
+import "fmt"
+
+type ReloadError struct {
+	Path string
+	Err  error
+}
+
+func (e ReloadError) Error() string {
+	return fmt.Sprintf("cannot reload certificate from %s: %s: the current certificate is still in use", e.Path, e.Err)
+}
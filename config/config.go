@@ -2,83 +2,402 @@
 package config
 
 import (
+	"crypto/x509"
+	"encoding/base64"
 	"fmt"
 	"io/ioutil"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cloudfoundry-incubator/candiedyaml"
 	"github.com/compozed/deployadactyl/geterrors"
+	"golang.org/x/crypto/ed25519"
 )
 
-const defaultConfigPath = "./config.yml"
+// DefaultPath is where the config file is read from when no path is given.
+const DefaultPath = "./config.yml"
+
+const (
+	defaultDomainCacheTTL   = 5 * time.Minute
+	defaultSessionTTL       = 10 * time.Minute
+	defaultArtifactCacheMB  = 1024
+	defaultRetryAttempts    = 3
+	defaultRetryBackoff     = 2 * time.Second
+	defaultRetryMax         = 30 * time.Second
+	defaultDownloadTimeout  = 4 * time.Minute
+	defaultMaxArtifactMB    = 2048
+	defaultMaxExtractedMB   = 2048
+	defaultMinFreeSpaceMB   = 512
+	defaultFoundationWeight = 1
+)
+
+const (
+	// BlueGreenStrategy pushes a new application next to the existing one and renames it once every foundation succeeds.
+	BlueGreenStrategy = "blue-green"
+	// RollingStrategy uses the native rolling deployments of Cloud Foundry to replace the instances of the existing application.
+	RollingStrategy = "rolling"
+)
+
+// defaultTransientErrors match the output of Cloud Foundry commands that fail because of
+// a temporary problem with the foundation rather than a problem with the deployment.
+var defaultTransientErrors = []string{
+	`502 Bad Gateway`,
+	`503 Service Unavailable`,
+	`504 Gateway Timeout`,
+	`i/o timeout`,
+	`connection reset by peer`,
+	`(?i)error refreshing (oauth )?token`,
+	`(?i)invalid auth token`,
+}
 
 // Config is a representation of a config yaml. It can contain multiple Environments.
 type Config struct {
-	Username     string
-	Password     string
-	Environments map[string]Environment
-	Port         int
+	Username         string
+	Password         string
+	Environments     map[string]Environment
+	Port             int
+	DomainCacheTTL   time.Duration
+	SessionTTL       time.Duration
+	Retry            Retry
+	ArtifactCache    ArtifactCache
+	ArtifactSource   ArtifactSource
+	WorkingDirectory WorkingDirectory
+	Server           Server
+}
+
+// WorkingDirectory is where artifacts are downloaded and extracted and where the cf cli keeps
+// its settings during a deploy. The OS temp dir is used when Path is empty. Artifacts larger
+// than MaxArtifactSize bytes are rejected, and so are deploys that would leave less than
+// MinFreeSpace bytes free in the working directory. Archives stop being extracted once more
+// than MaxExtractedSize bytes have been extracted from them.
+type WorkingDirectory struct {
+	Path             string
+	MaxArtifactSize  int64
+	MaxExtractedSize int64
+	MinFreeSpace     int64
+}
+
+// ArtifactSource is how artifacts are downloaded. Credentials are used for every environment,
+// after the credentials of the environment. Artifacts can only be read from file URLs under FileRoot.
+// Each request for an artifact can take Timeout, and failed downloads are retried
+// with the same Attempts, Backoff and MaxBackoff as Retry.
+type ArtifactSource struct {
+	Credentials []ArtifactCredentials
+	FileRoot    string
+	Timeout     time.Duration
+	Attempts    int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// ArtifactCredentials authenticate requests for artifacts on a host.
+// Host is the host of an http or https artifact URL, or the bucket of an s3 artifact URL.
+// Username and Password are sent with basic auth and Token as a bearer token.
+// AccessKeyID, SecretAccessKey, Region and Endpoint sign requests to S3 compatible stores.
+type ArtifactCredentials struct {
+	Host            string
+	Username        string
+	Password        string
+	Token           string
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	Region          string
+	Endpoint        string
+}
+
+// ArtifactCache is where downloaded artifacts are kept and how many bytes they can use.
+// Artifacts are not cached when Directory is empty.
+type ArtifactCache struct {
+	Directory string
+	MaxSize   int64
+}
+
+// Retry is the policy used to retry Cloud Foundry commands that fail with a transient error.
+// TransientErrors are compiled when the config is read.
+type Retry struct {
+	Attempts        int
+	Backoff         time.Duration
+	MaxBackoff      time.Duration
+	TransientErrors []*regexp.Regexp
 }
 
 // Environment is representation of a single environment configuration.
 type Environment struct {
-	Name         string
-	Domain       string
-	Foundations  []string `yaml:",flow"`
-	Authenticate bool
-	SkipSSL      bool `yaml:"skip_ssl"`
-	Instances    uint16
+	Name                string
+	Domain              string
+	Foundations         []string `yaml:"-"`
+	Authenticate        bool
+	SkipSSL             bool `yaml:"skip_ssl"`
+	Instances           uint16
+	Strategy            string
+	ArtifactCredentials []ArtifactCredentials `yaml:"artifact_credentials"`
+
+	// TrustedKeys are base64 encoded ed25519 public keys. Artifacts with a signature are checked
+	// against them, and artifacts without one are rejected when RequireSignedArtifacts is set.
+	TrustedKeys            []string `yaml:"trusted_keys"`
+	RequireSignedArtifacts bool     `yaml:"require_signed_artifacts"`
+
+	// Credentials log in to the foundations of the environment when a deploy does not use basic auth,
+	// instead of CF_USERNAME and CF_PASSWORD. FoundationCredentials are used instead of them for
+	// single foundations and are keyed by foundation URL.
+	Credentials           Credentials            `yaml:"credentials"`
+	FoundationCredentials map[string]Credentials `yaml:"foundation_credentials"`
+
+	// Handlers are the event handlers that receive the events of deployments to the environment.
+	Handlers Handlers `yaml:"handlers"`
+
+	// Policy limits the orgs, spaces, instances and times of the deployments to the environment.
+	Policy Policy `yaml:"policy"`
+
+	// Extends is the template or environment that the environment inherits its settings from.
+	Extends string `yaml:"extends"`
+
+	// FoundationDetails are the settings of the foundations that are configured with a map instead
+	// of only a URL, keyed by foundation URL. Use Foundation to get the settings of any foundation.
+	FoundationDetails map[string]Foundation `yaml:"-"`
+}
+
+// Handlers enable the event handlers of an environment and hold their settings.
+type Handlers struct {
+	EnvironmentVariables Handler            `yaml:"environment_variables"`
+	HealthCheck          HealthCheckHandler `yaml:"health_check"`
+	RouteMapper          Handler            `yaml:"route_mapper"`
+}
+
+// Handler enables an event handler that has no settings.
+type Handler struct {
+	Enabled bool
+}
+
+// HealthCheckHandler enables the health checker. The domain of the temporary health check route is made
+// by replacing OldURL with NewURL in the foundation URL, such as api.cf with apps, when they are set.
+type HealthCheckHandler struct {
+	Enabled bool
+	OldURL  string `yaml:"old_url"`
+	NewURL  string `yaml:"new_url"`
+}
+
+// Foundation is a Cloud Foundry instance of an environment. Name, Region and Labels describe it
+// in the output of a deploy and in events. Domain and SkipSSL are used instead of the settings of
+// the environment, and AppsDomain is used for the temporary health check route instead of a domain
+// found in the foundation. CACert is a PEM bundle used to verify the certificate of the foundation
+// when it is checked before a deploy and by the cf cli.
+// Weight is not used by Deployadactyl and is there for event handlers, such as load balancer updates.
+type Foundation struct {
+	URL        string
+	Name       string
+	Region     string
+	Labels     map[string]string
+	Domain     string
+	AppsDomain string
+	SkipSSL    bool
+	CACert     string
+	Weight     int
+}
+
+// Foundation returns the settings of the foundation with the URL. Settings that are not
+// configured for the foundation come from the environment.
+func (e Environment) Foundation(url string) Foundation {
+	if foundation, ok := e.FoundationDetails[url]; ok {
+		return foundation
+	}
+
+	return Foundation{
+		URL:     url,
+		Name:    url,
+		Domain:  e.Domain,
+		SkipSSL: e.SkipSSL,
+		Weight:  defaultFoundationWeight,
+	}
+}
+
+// Credentials log in to Cloud Foundry. The username and password are read from the environment
+// variables or files that are named in the config file, so they are not kept in it.
+// Username and Password are set when the config is read.
+type Credentials struct {
+	UsernameEnv  string `yaml:"username_env"`
+	PasswordEnv  string `yaml:"password_env"`
+	UsernameFile string `yaml:"username_file"`
+	PasswordFile string `yaml:"password_file"`
+	Username     string `yaml:"-"`
+	Password     string `yaml:"-"`
+}
+
+func (c Credentials) configured() bool {
+	return c.UsernameEnv != "" || c.PasswordEnv != "" || c.UsernameFile != "" || c.PasswordFile != ""
 }
 
 type configYaml struct {
-	Environments []Environment `yaml:",flow"`
+	DomainCacheTTL   string               `yaml:"domain_cache_ttl"`
+	SessionTTL       string               `yaml:"session_ttl"`
+	Retry            retryYaml            `yaml:"cf_retry"`
+	ArtifactCache    artifactCacheYaml    `yaml:"artifact_cache"`
+	ArtifactSource   artifactSourceYaml   `yaml:"artifact_source"`
+	WorkingDirectory workingDirectoryYaml `yaml:"working_directory"`
+	Server           serverYaml           `yaml:"server"`
+	Environments     []Environment        `yaml:",flow"`
+
+	// Foundations are the foundations of each environment in the same order as Environments.
+	// A foundation is either a URL or a map, so they are parsed separately.
+	Foundations [][]interface{} `yaml:"-"`
+}
+
+type foundationSettingsYaml struct {
+	URL        string
+	Name       string
+	Region     string
+	Labels     map[string]string
+	Domain     string
+	AppsDomain string `yaml:"apps_domain"`
+	SkipSSL    *bool  `yaml:"skip_ssl"`
+	CACert     string `yaml:"ca_cert"`
+	Weight     *int
+}
+
+type workingDirectoryYaml struct {
+	Path               string
+	MaxArtifactSizeMB  int64 `yaml:"max_artifact_size_mb"`
+	MaxExtractedSizeMB int64 `yaml:"max_extracted_size_mb"`
+	MinFreeSpaceMB     int64 `yaml:"min_free_space_mb"`
 }
 
-type foundationYaml struct {
-	Foundations []string
+type artifactSourceYaml struct {
+	Credentials []ArtifactCredentials
+	FileRoot    string `yaml:"file_root"`
+	Timeout     string
+	Attempts    int
+	Backoff     string
+	MaxBackoff  string `yaml:"max_backoff"`
+}
+
+type artifactCacheYaml struct {
+	Directory string
+	MaxSizeMB int64 `yaml:"max_size_mb"`
+}
+
+type retryYaml struct {
+	Attempts        int
+	Backoff         string
+	MaxBackoff      string   `yaml:"max_backoff"`
+	TransientErrors []string `yaml:"transient_errors"`
+}
+
+type foundationsYaml struct {
+	Environments []struct {
+		Foundations []interface{}
+	}
 }
 
 // Default returns a new Config struct with information from environment variables and the default config file (./config.yml).
 func Default(getenv func(string) string) (Config, error) {
-	environments, err := getEnvironmentsFromFile(defaultConfigPath)
-	if err != nil {
-		return Config{}, err
-	}
-	return createConfig(getenv, environments)
+	return Custom(getenv, DefaultPath)
 }
 
 // Custom returns a new Config struct with information from environment variables and a custom config file.
 func Custom(getenv func(string) string, configPath string) (Config, error) {
-	environments, err := getEnvironmentsFromFile(configPath)
+	data, secrets, err := readConfigFile(getenv, configPath)
 	if err != nil {
 		return Config{}, err
 	}
-	return createConfig(getenv, environments)
+
+	foundationConfig, err := parseYamlFromBody(data)
+	if err != nil {
+		return Config{}, redact(err, secrets)
+	}
+
+	config, err := createConfig(getenv, foundationConfig)
+	if err != nil {
+		return Config{}, redact(err, secrets)
+	}
+	return config, nil
 }
 
-func createConfig(getenv func(string) string, environments map[string]Environment) (Config, error) {
+func createConfig(getenv func(string) string, foundationConfig configYaml) (Config, error) {
+	config, errs := buildConfig(getenv, foundationConfig, true)
+	if len(errs) > 0 {
+		return Config{}, errs[0]
+	}
+	return config, nil
+}
+
+// buildConfig returns the config and every problem with it, in the order they are found.
+// The credentials are only read when checkCredentials is true.
+func buildConfig(getenv func(string) string, foundationConfig configYaml, checkCredentials bool) (Config, []error) {
+	var errs []error
+
+	environments, environmentErrs := getEnvironments(foundationConfig)
+	errs = append(errs, environmentErrs...)
+
 	getter := geterrors.WrapFunc(getenv)
 
 	username := getter.Get("CF_USERNAME")
 	password := getter.Get("CF_PASSWORD")
 
-	if err := getter.Err("missing environment variables"); err != nil {
-		return Config{}, err
+	if checkCredentials {
+		if err := getter.Err("missing environment variables"); err != nil && needsSharedCredentials(environments) {
+			errs = append(errs, err)
+		}
+
+		if err := getCredentials(getenv, environments); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
 	port, err := getPortFromEnv(getenv)
 	if err != nil {
-		return Config{}, err
+		errs = append(errs, err)
+	}
+
+	domainCacheTTL, err := getDuration("domain_cache_ttl", foundationConfig.DomainCacheTTL, defaultDomainCacheTTL)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	sessionTTL, err := getDuration("session_ttl", foundationConfig.SessionTTL, defaultSessionTTL)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	retry, err := getRetry(foundationConfig.Retry)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	artifactCache, err := getArtifactCache(foundationConfig.ArtifactCache)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	artifactSource, err := getArtifactSource(foundationConfig.ArtifactSource, environments)
+	if err != nil {
+		errs = append(errs, err)
 	}
 
+	workingDirectory, err := getWorkingDirectory(foundationConfig.WorkingDirectory)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	server, serverErrs := getServer(foundationConfig.Server)
+	errs = append(errs, serverErrs...)
+
 	config := Config{
-		Username:     username,
-		Password:     password,
-		Port:         port,
-		Environments: environments,
+		Username:         username,
+		Password:         password,
+		Port:             port,
+		Environments:     environments,
+		DomainCacheTTL:   domainCacheTTL,
+		SessionTTL:       sessionTTL,
+		Retry:            retry,
+		ArtifactCache:    artifactCache,
+		ArtifactSource:   artifactSource,
+		WorkingDirectory: workingDirectory,
+		Server:           server,
 	}
-	return config, nil
+	return config, errs
 }
 
 func getPortFromEnv(getenv func(string) string) (int, error) {
@@ -95,44 +414,528 @@ func getPortFromEnv(getenv func(string) string) (int, error) {
 	return cfgPort, nil
 }
 
-func getEnvironmentsFromFile(filename string) (map[string]Environment, error) {
-	file, err := ioutil.ReadFile(filename)
+func getDuration(key, value string, defaultDuration time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultDuration, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, InvalidDurationError{key, value}
+	}
+
+	return duration, nil
+}
+
+func getArtifactCache(cacheConfig artifactCacheYaml) (ArtifactCache, error) {
+	if cacheConfig.MaxSizeMB < 0 {
+		return ArtifactCache{}, InvalidArtifactCacheSizeError{cacheConfig.MaxSizeMB}
+	}
+
+	maxSizeMB := cacheConfig.MaxSizeMB
+	if maxSizeMB == 0 {
+		maxSizeMB = defaultArtifactCacheMB
+	}
+
+	return ArtifactCache{
+		Directory: cacheConfig.Directory,
+		MaxSize:   maxSizeMB * 1024 * 1024,
+	}, nil
+}
+
+func getWorkingDirectory(directoryConfig workingDirectoryYaml) (WorkingDirectory, error) {
+	if directoryConfig.MaxArtifactSizeMB < 0 {
+		return WorkingDirectory{}, InvalidWorkingDirectorySizeError{"max_artifact_size_mb", directoryConfig.MaxArtifactSizeMB}
+	}
+	if directoryConfig.MaxExtractedSizeMB < 0 {
+		return WorkingDirectory{}, InvalidWorkingDirectorySizeError{"max_extracted_size_mb", directoryConfig.MaxExtractedSizeMB}
+	}
+	if directoryConfig.MinFreeSpaceMB < 0 {
+		return WorkingDirectory{}, InvalidWorkingDirectorySizeError{"min_free_space_mb", directoryConfig.MinFreeSpaceMB}
+	}
+
+	maxArtifactSizeMB := directoryConfig.MaxArtifactSizeMB
+	if maxArtifactSizeMB == 0 {
+		maxArtifactSizeMB = defaultMaxArtifactMB
+	}
+
+	maxExtractedSizeMB := directoryConfig.MaxExtractedSizeMB
+	if maxExtractedSizeMB == 0 {
+		maxExtractedSizeMB = defaultMaxExtractedMB
+	}
+
+	minFreeSpaceMB := directoryConfig.MinFreeSpaceMB
+	if minFreeSpaceMB == 0 {
+		minFreeSpaceMB = defaultMinFreeSpaceMB
+	}
+
+	return WorkingDirectory{
+		Path:             directoryConfig.Path,
+		MaxArtifactSize:  maxArtifactSizeMB * 1024 * 1024,
+		MaxExtractedSize: maxExtractedSizeMB * 1024 * 1024,
+		MinFreeSpace:     minFreeSpaceMB * 1024 * 1024,
+	}, nil
+}
+
+// getArtifactSource validates the artifact credentials and adds the shared credentials
+// to the credentials of every environment. The download timeout and retries default to
+// the values used before they could be configured.
+func getArtifactSource(sourceConfig artifactSourceYaml, environments map[string]Environment) (ArtifactSource, error) {
+	if err := validateArtifactCredentials(sourceConfig.Credentials); err != nil {
+		return ArtifactSource{}, err
+	}
+
+	for key, environment := range environments {
+		if err := validateArtifactCredentials(environment.ArtifactCredentials); err != nil {
+			return ArtifactSource{}, err
+		}
+
+		if len(sourceConfig.Credentials) > 0 {
+			credentials := append([]ArtifactCredentials{}, environment.ArtifactCredentials...)
+			environment.ArtifactCredentials = append(credentials, sourceConfig.Credentials...)
+			environments[key] = environment
+		}
+	}
+
+	if sourceConfig.Attempts < 0 {
+		return ArtifactSource{}, InvalidDownloadAttemptsError{sourceConfig.Attempts}
+	}
+
+	attempts := sourceConfig.Attempts
+	if attempts == 0 {
+		attempts = defaultRetryAttempts
+	}
+
+	timeout, err := getDuration("artifact_source.timeout", sourceConfig.Timeout, defaultDownloadTimeout)
+	if err != nil {
+		return ArtifactSource{}, err
+	}
+
+	backoff, err := getDuration("artifact_source.backoff", sourceConfig.Backoff, defaultRetryBackoff)
+	if err != nil {
+		return ArtifactSource{}, err
+	}
+
+	maxBackoff, err := getDuration("artifact_source.max_backoff", sourceConfig.MaxBackoff, defaultRetryMax)
+	if err != nil {
+		return ArtifactSource{}, err
+	}
+
+	return ArtifactSource{
+		Credentials: sourceConfig.Credentials,
+		FileRoot:    sourceConfig.FileRoot,
+		Timeout:     timeout,
+		Attempts:    attempts,
+		Backoff:     backoff,
+		MaxBackoff:  maxBackoff,
+	}, nil
+}
+
+func validateArtifactCredentials(credentials []ArtifactCredentials) error {
+	for _, c := range credentials {
+		switch {
+		case c.Host == "":
+			return InvalidArtifactCredentialsError{c.Host, "a host is required"}
+		case c.Username != "" && c.Token != "":
+			return InvalidArtifactCredentialsError{c.Host, "use either a username and password or a token"}
+		case (c.AccessKeyID == "") != (c.SecretAccessKey == ""):
+			return InvalidArtifactCredentialsError{c.Host, "an access_key_id and secret_access_key are required together"}
+		}
+	}
+
+	return nil
+}
+
+func getRetry(retryConfig retryYaml) (Retry, error) {
+	if retryConfig.Attempts < 0 {
+		return Retry{}, InvalidRetryAttemptsError{retryConfig.Attempts}
+	}
+
+	attempts := retryConfig.Attempts
+	if attempts == 0 {
+		attempts = defaultRetryAttempts
+	}
+
+	backoff, err := getDuration("cf_retry.backoff", retryConfig.Backoff, defaultRetryBackoff)
 	if err != nil {
-		return nil, err
+		return Retry{}, err
 	}
 
-	foundationConfig, err := parseYamlFromBody(file)
+	maxBackoff, err := getDuration("cf_retry.max_backoff", retryConfig.MaxBackoff, defaultRetryMax)
 	if err != nil {
-		return nil, err
+		return Retry{}, err
+	}
+
+	transientErrors := retryConfig.TransientErrors
+	if len(transientErrors) == 0 {
+		transientErrors = defaultTransientErrors
 	}
 
+	var patterns []*regexp.Regexp
+	for _, transientError := range transientErrors {
+		pattern, err := regexp.Compile(transientError)
+		if err != nil {
+			return Retry{}, InvalidTransientErrorError{transientError, err}
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	return Retry{
+		Attempts:        attempts,
+		Backoff:         backoff,
+		MaxBackoff:      maxBackoff,
+		TransientErrors: patterns,
+	}, nil
+}
+
+// getEnvironments returns the environments that are valid and the problems with the others.
+// Environment names are not case sensitive, so two environments cannot have names that only differ in case.
+func getEnvironments(foundationConfig configYaml) (map[string]Environment, []error) {
 	if foundationConfig.Environments == nil || len(foundationConfig.Environments) == 0 {
-		return nil, EnvironmentsNotSpecifiedError{}
+		return nil, []error{EnvironmentsNotSpecifiedError{}}
 	}
 
+	var errs []error
+
+	names := map[string]bool{}
 	environments := map[string]Environment{}
-	for _, environment := range foundationConfig.Environments {
-		if environment.Name == "" || environment.Foundations == nil || len(environment.Foundations) == 0 {
-			return nil, MissingParameterError{}
+	for i, environment := range foundationConfig.Environments {
+		var foundations []interface{}
+		if i < len(foundationConfig.Foundations) {
+			foundations = foundationConfig.Foundations[i]
 		}
 
-		if environment.Instances < 1 {
-			environment.Instances = 1
+		environment, environmentErrs := getEnvironment(i, environment, foundations)
+		errs = append(errs, environmentErrs...)
+
+		key := strings.ToLower(environment.Name)
+		if key != "" && names[key] {
+			errs = append(errs, DuplicateEnvironmentError{environment.Name})
+			continue
 		}
+		names[key] = true
 
-		environments[strings.ToLower(environment.Name)] = environment
+		if len(environmentErrs) == 0 {
+			environments[key] = environment
+		}
 	}
 
-	return environments, nil
+	return environments, errs
+}
+
+// getEnvironment sets the foundations and defaults of the environment at index i of environments
+// and returns every problem with it.
+func getEnvironment(i int, environment Environment, foundations []interface{}) (Environment, []error) {
+	errs := getFoundations(&environment, foundations)
+
+	location := fmt.Sprintf("environments[%d]", i)
+	if environment.Name != "" {
+		location = "environment " + environment.Name
+	}
+
+	if environment.Name == "" {
+		errs = append(errs, MissingParameterError{location, "name"})
+	}
+	if len(environment.Foundations) == 0 && len(foundations) == 0 {
+		errs = append(errs, MissingParameterError{location, "foundations"})
+	}
+
+	if environment.Instances < 1 {
+		environment.Instances = 1
+	}
+
+	if err := validateTrustedKeys(environment); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch environment.Strategy {
+	case "":
+		environment.Strategy = BlueGreenStrategy
+	case BlueGreenStrategy, RollingStrategy:
+	default:
+		errs = append(errs, InvalidStrategyError{environment.Name, environment.Strategy})
+	}
+
+	errs = append(errs, getPolicy(&environment)...)
+
+	healthCheck := environment.Handlers.HealthCheck
+	if (healthCheck.OldURL == "") != (healthCheck.NewURL == "") {
+		errs = append(errs, InvalidHandlerError{environment.Name, "health_check", "old_url and new_url are required together"})
+	}
+
+	return environment, errs
+}
+
+// getFoundations sets the URLs and settings of the foundations of an environment. A foundation
+// is either its URL or a map with a url and its settings.
+func getFoundations(environment *Environment, foundations []interface{}) []error {
+	var errs []error
+
+	for _, foundation := range foundations {
+		switch value := foundation.(type) {
+		case string:
+			if reason := validateFoundationURL(value); reason != "" {
+				errs = append(errs, InvalidFoundationError{environment.Name, value, reason})
+				continue
+			}
+
+			environment.Foundations = append(environment.Foundations, value)
+
+		case map[interface{}]interface{}, map[string]interface{}:
+			details, err := getFoundationDetails(*environment, value)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			if environment.FoundationDetails == nil {
+				environment.FoundationDetails = map[string]Foundation{}
+			}
+			if _, ok := environment.FoundationDetails[details.URL]; ok {
+				errs = append(errs, InvalidFoundationError{environment.Name, details.URL, "it is configured more than once"})
+				continue
+			}
+
+			environment.Foundations = append(environment.Foundations, details.URL)
+			environment.FoundationDetails[details.URL] = details
+
+		default:
+			errs = append(errs, InvalidFoundationError{environment.Name, fmt.Sprint(value), "use a URL or a map with a url"})
+		}
+	}
+
+	return errs
+}
+
+// validateFoundationURL returns why a foundation URL is malformed, or an empty string when it is not.
+// The URL can leave out its scheme, which is https for the Cloud Foundry CLI.
+func validateFoundationURL(foundationURL string) string {
+	if strings.ContainsAny(foundationURL, " \t\n") {
+		return "the url contains whitespace"
+	}
+
+	if !strings.Contains(foundationURL, "://") {
+		foundationURL = "https://" + foundationURL
+	}
+
+	u, err := url.Parse(foundationURL)
+	switch {
+	case err != nil:
+		return err.Error()
+	case u.Scheme != "http" && u.Scheme != "https":
+		return fmt.Sprintf("unsupported scheme %s: use http or https", u.Scheme)
+	case u.Host == "":
+		return "the url has no host"
+	}
+
+	return ""
+}
+
+// getFoundationDetails reads the settings of a foundation from a map. The settings that are
+// not in the map come from the environment.
+func getFoundationDetails(environment Environment, foundation interface{}) (Foundation, error) {
+	var settings foundationSettingsYaml
+
+	data, err := candiedyaml.Marshal(foundation)
+	if err == nil {
+		err = candiedyaml.Unmarshal(data, &settings)
+	}
+	if err != nil {
+		return Foundation{}, InvalidFoundationError{environment.Name, fmt.Sprint(foundation), err.Error()}
+	}
+
+	if settings.URL == "" {
+		return Foundation{}, InvalidFoundationError{environment.Name, settings.Name, "a url is required"}
+	}
+	if reason := validateFoundationURL(settings.URL); reason != "" {
+		return Foundation{}, InvalidFoundationError{environment.Name, settings.URL, reason}
+	}
+
+	details := environment.Foundation(settings.URL)
+	details.Region = settings.Region
+	details.Labels = settings.Labels
+	details.AppsDomain = settings.AppsDomain
+	details.CACert = settings.CACert
+
+	if settings.Name != "" {
+		details.Name = settings.Name
+	}
+	if settings.Domain != "" {
+		details.Domain = settings.Domain
+	}
+	if settings.SkipSSL != nil {
+		details.SkipSSL = *settings.SkipSSL
+	}
+
+	if settings.Weight != nil {
+		if *settings.Weight < 0 {
+			return Foundation{}, InvalidFoundationError{environment.Name, settings.URL, fmt.Sprintf("invalid weight %d: it must not be negative", *settings.Weight)}
+		}
+		details.Weight = *settings.Weight
+	}
+
+	if settings.CACert != "" {
+		pem, err := ioutil.ReadFile(settings.CACert)
+		if err != nil {
+			return Foundation{}, InvalidFoundationError{environment.Name, settings.URL, fmt.Sprintf("cannot read ca_cert: %s", err)}
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return Foundation{}, InvalidFoundationError{environment.Name, settings.URL, fmt.Sprintf("no certificates found in ca_cert %s", settings.CACert)}
+		}
+	}
+
+	return details, nil
+}
+
+// needsSharedCredentials reports whether an environment has no credentials of its own,
+// so CF_USERNAME and CF_PASSWORD are required.
+func needsSharedCredentials(environments map[string]Environment) bool {
+	for _, environment := range environments {
+		if !environment.Credentials.configured() {
+			return true
+		}
+	}
+	return false
+}
+
+// getCredentials reads the username and password of the credentials of every environment and
+// foundation. Foundation credentials must be for one of the foundations of their environment.
+func getCredentials(getenv func(string) string, environments map[string]Environment) error {
+	for key, environment := range environments {
+		if environment.Credentials.configured() {
+			credentials, err := readCredentials(getenv, "environment "+environment.Name, environment.Credentials)
+			if err != nil {
+				return err
+			}
+			environment.Credentials = credentials
+		}
+
+		if len(environment.FoundationCredentials) > 0 {
+			foundationCredentials, err := readFoundationCredentials(getenv, environment)
+			if err != nil {
+				return err
+			}
+			environment.FoundationCredentials = foundationCredentials
+		}
+
+		environments[key] = environment
+	}
+
+	return nil
+}
+
+func readFoundationCredentials(getenv func(string) string, environment Environment) (map[string]Credentials, error) {
+	foundationCredentials := map[string]Credentials{}
+	for foundationURL, c := range environment.FoundationCredentials {
+		owner := fmt.Sprintf("foundation %s of environment %s", foundationURL, environment.Name)
+
+		if !hasFoundation(environment, foundationURL) {
+			return nil, InvalidCredentialsError{owner, "it is not one of the foundations of the environment"}
+		}
+
+		credentials, err := readCredentials(getenv, owner, c)
+		if err != nil {
+			return nil, err
+		}
+		foundationCredentials[foundationURL] = credentials
+	}
+
+	return foundationCredentials, nil
+}
+
+func readCredentials(getenv func(string) string, owner string, credentials Credentials) (Credentials, error) {
+	username, err := readSecret(getenv, owner, "username", credentials.UsernameEnv, credentials.UsernameFile)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	password, err := readSecret(getenv, owner, "password", credentials.PasswordEnv, credentials.PasswordFile)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	credentials.Username = username
+	credentials.Password = password
+	return credentials, nil
+}
+
+// readSecret reads a username or password from the environment variable or file that is named for it.
+// Whitespace around the contents of a file, such as a trailing newline, is removed.
+func readSecret(getenv func(string) string, owner, key, envName, file string) (string, error) {
+	switch {
+	case envName != "" && file != "":
+		return "", InvalidCredentialsError{owner, fmt.Sprintf("use either %s_env or %s_file", key, key)}
+
+	case envName != "":
+		value := getenv(envName)
+		if value == "" {
+			return "", MissingCredentialsError{owner, fmt.Sprintf("environment variable %s is not set", envName)}
+		}
+		return value, nil
+
+	case file != "":
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", ReadCredentialsFileError{owner, file, err}
+		}
+
+		value := strings.TrimSpace(string(contents))
+		if value == "" {
+			return "", MissingCredentialsError{owner, fmt.Sprintf("file %s is empty", file)}
+		}
+		return value, nil
+	}
+
+	return "", InvalidCredentialsError{owner, fmt.Sprintf("%s_env or %s_file is required", key, key)}
+}
+
+func hasFoundation(environment Environment, foundationURL string) bool {
+	for _, foundation := range environment.Foundations {
+		if foundation == foundationURL {
+			return true
+		}
+	}
+	return false
+}
+
+func validateTrustedKeys(environment Environment) error {
+	if environment.RequireSignedArtifacts && len(environment.TrustedKeys) == 0 {
+		return MissingTrustedKeysError{environment.Name}
+	}
+
+	for _, key := range environment.TrustedKeys {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(decoded) != ed25519.PublicKeySize {
+			return InvalidTrustedKeyError{environment.Name, key}
+		}
+	}
+
+	return nil
 }
 
 func parseYamlFromBody(data []byte) (configYaml, error) {
 	var foundationConfig configYaml
 
-	err := candiedyaml.Unmarshal(data, &foundationConfig)
+	data, err := resolveExtends(data)
+	if err != nil {
+		return configYaml{}, err
+	}
+
+	err = candiedyaml.Unmarshal(data, &foundationConfig)
+	if err != nil {
+		return configYaml{}, ParseYamlError{err}
+	}
+
+	var foundations foundationsYaml
+
+	err = candiedyaml.Unmarshal(data, &foundations)
 	if err != nil {
 		return configYaml{}, ParseYamlError{err}
 	}
 
+	for _, environment := range foundations.Environments {
+		foundationConfig.Foundations = append(foundationConfig.Foundations, environment.Foundations)
+	}
+
 	return foundationConfig, nil
 }
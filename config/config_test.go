@@ -1,8 +1,18 @@
 package config_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"io/ioutil"
+	"math/big"
 	"os"
+	"path"
+	"regexp"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -55,6 +65,7 @@ var _ = Describe("Config", func() {
 				Domain:      "test.example.com",
 				SkipSSL:     true,
 				Instances:   3,
+				Strategy:    "blue-green",
 			},
 			"prod": {
 				Name:        "Prod",
@@ -62,6 +73,7 @@ var _ = Describe("Config", func() {
 				Domain:      "example.com",
 				SkipSSL:     false,
 				Instances:   1,
+				Strategy:    "blue-green",
 			},
 		}
 
@@ -102,6 +114,1109 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	Context("when domain_cache_ttl is not in the config", func() {
+		It("caches domains for five minutes", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.DomainCacheTTL).To(Equal(5 * time.Minute))
+		})
+	})
+
+	Context("when domain_cache_ttl is in the config", func() {
+		It("uses the value as the domain cache TTL", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"domain_cache_ttl: 90s\n"), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.DomainCacheTTL).To(Equal(90 * time.Second))
+		})
+
+		It("returns an error when the value is not a duration", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"domain_cache_ttl: soon\n"), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidDurationError{"domain_cache_ttl", "soon"}))
+		})
+	})
+
+	Context("when session_ttl is not in the config", func() {
+		It("keeps cf sessions for ten minutes", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.SessionTTL).To(Equal(10 * time.Minute))
+		})
+	})
+
+	Context("when session_ttl is in the config", func() {
+		It("uses the value as the session TTL", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"session_ttl: 0s\n"), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.SessionTTL).To(BeZero())
+		})
+	})
+
+	Context("when artifact_cache is in the config", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("uses the directory and size of the artifact cache", func() {
+			cacheConfig := "artifact_cache:\n  directory: /var/cache/deployadactyl\n  max_size_mb: 20\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+cacheConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ArtifactCache).To(Equal(ArtifactCache{Directory: "/var/cache/deployadactyl", MaxSize: 20 * 1024 * 1024}))
+		})
+
+		It("defaults the size of the artifact cache to one gigabyte", func() {
+			cacheConfig := "artifact_cache:\n  directory: /var/cache/deployadactyl\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+cacheConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ArtifactCache.MaxSize).To(Equal(int64(1024 * 1024 * 1024)))
+		})
+
+		It("returns an error when the size is negative", func() {
+			cacheConfig := "artifact_cache:\n  directory: /var/cache/deployadactyl\n  max_size_mb: -1\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+cacheConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidArtifactCacheSizeError{-1}))
+		})
+	})
+
+	Context("when working_directory is in the config", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("uses the path and limits of the working directory", func() {
+			directoryConfig := "working_directory:\n  path: /var/vcap/data/deployadactyl\n  max_artifact_size_mb: 100\n  max_extracted_size_mb: 300\n  min_free_space_mb: 10\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+directoryConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.WorkingDirectory).To(Equal(WorkingDirectory{
+				Path:             "/var/vcap/data/deployadactyl",
+				MaxArtifactSize:  100 * 1024 * 1024,
+				MaxExtractedSize: 300 * 1024 * 1024,
+				MinFreeSpace:     10 * 1024 * 1024,
+			}))
+		})
+
+		It("defaults to the OS temp dir, two gigabyte artifacts and extractions and half a gigabyte of free space", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.WorkingDirectory).To(Equal(WorkingDirectory{
+				MaxArtifactSize:  2048 * 1024 * 1024,
+				MaxExtractedSize: 2048 * 1024 * 1024,
+				MinFreeSpace:     512 * 1024 * 1024,
+			}))
+		})
+
+		It("returns an error when a size is negative", func() {
+			directoryConfig := "working_directory:\n  min_free_space_mb: -1\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+directoryConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidWorkingDirectorySizeError{"min_free_space_mb", -1}))
+		})
+
+		It("returns an error when the extracted size is negative", func() {
+			directoryConfig := "working_directory:\n  max_extracted_size_mb: -1\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+directoryConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidWorkingDirectorySizeError{"max_extracted_size_mb", -1}))
+		})
+	})
+
+	Context("when server is in the config", func() {
+		var directory string
+
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			var err error
+			directory, err = ioutil.TempDir("", "deployadactyl-config-")
+			Expect(err).ToNot(HaveOccurred())
+
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			template := &x509.Certificate{
+				SerialNumber: big.NewInt(1),
+				Subject:      pkix.Name{CommonName: "deployadactyl.example.com"},
+				NotBefore:    time.Now().Add(-time.Hour),
+				NotAfter:     time.Now().Add(time.Hour),
+			}
+
+			certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+			Expect(err).ToNot(HaveOccurred())
+
+			keyBytes, err := x509.MarshalECPrivateKey(key)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ioutil.WriteFile(path.Join(directory, "server.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(path.Join(directory, "server.key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(directory)
+		})
+
+		It("uses the address, timeouts and tls of the server", func() {
+			serverConfig := "server:\n  address: 127.0.0.1\n  read_timeout: 1m\n  write_timeout: 0s\n  idle_timeout: 30s\n  tls:\n" +
+				"    cert_file: " + path.Join(directory, "server.crt") + "\n" +
+				"    key_file: " + path.Join(directory, "server.key") + "\n" +
+				"    client_ca_file: " + path.Join(directory, "server.crt") + "\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+serverConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Server).To(Equal(Server{
+				Address:      "127.0.0.1",
+				ReadTimeout:  time.Minute,
+				WriteTimeout: 0,
+				IdleTimeout:  30 * time.Second,
+				TLS: ServerTLS{
+					CertFile:     path.Join(directory, "server.crt"),
+					KeyFile:      path.Join(directory, "server.key"),
+					ClientCAFile: path.Join(directory, "server.crt"),
+				},
+			}))
+			Expect(config.Server.TLS.Enabled()).To(BeTrue())
+		})
+
+		It("defaults to every address, a ten minute read timeout, an hour write timeout, a two minute idle timeout and no tls", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Server).To(Equal(Server{
+				Address:      "0.0.0.0",
+				ReadTimeout:  10 * time.Minute,
+				WriteTimeout: time.Hour,
+				IdleTimeout:  2 * time.Minute,
+			}))
+			Expect(config.Server.TLS.Enabled()).To(BeFalse())
+		})
+
+		It("returns every problem with the server", func() {
+			serverConfig := "server:\n  address: 127.0.0.1:8080\n  read_timeout: soon\n  tls:\n" +
+				"    cert_file: " + path.Join(directory, "server.crt") + "\n" +
+				"    client_ca_file: " + path.Join(directory, "server.key") + "\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+serverConfig), 0644)).To(Succeed())
+
+			Expect(Validate(env.Get, customConfigPath, false)).To(Equal([]error{
+				InvalidServerError{"address 127.0.0.1:8080 must not have a port: the port is read from PORT"},
+				InvalidDurationError{"server.read_timeout", "soon"},
+				InvalidServerError{"tls.cert_file and tls.key_file must be set together"},
+			}))
+		})
+
+		It("returns an error when the client CAs have no certificates", func() {
+			serverConfig := "server:\n  tls:\n" +
+				"    cert_file: " + path.Join(directory, "server.crt") + "\n" +
+				"    key_file: " + path.Join(directory, "server.key") + "\n" +
+				"    client_ca_file: " + path.Join(directory, "server.key") + "\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+serverConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidServerError{"no certificates found in tls.client_ca_file " + path.Join(directory, "server.key")}))
+		})
+	})
+
+	Context("when artifact_source is in the config", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("adds the shared credentials after the credentials of each environment", func() {
+			sourceConfig := `---
+artifact_source:
+  file_root: /shared/artifacts
+  credentials:
+  - host: artifactory.example.com
+    token: shared-token
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  artifact_credentials:
+  - host: artifactory.example.com
+    username: production-user
+    password: production-password
+  - host: artifacts
+    access_key_id: AKIDEXAMPLE
+    secret_access_key: secret
+    region: eu-west-1
+`
+			Expect(ioutil.WriteFile(badConfigPath, []byte(sourceConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, badConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ArtifactSource.FileRoot).To(Equal("/shared/artifacts"))
+			Expect(config.ArtifactSource.Credentials).To(Equal([]ArtifactCredentials{{Host: "artifactory.example.com", Token: "shared-token"}}))
+			Expect(config.Environments["production"].ArtifactCredentials).To(Equal([]ArtifactCredentials{
+				{Host: "artifactory.example.com", Username: "production-user", Password: "production-password"},
+				{Host: "artifacts", AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", Region: "eu-west-1"},
+				{Host: "artifactory.example.com", Token: "shared-token"},
+			}))
+		})
+
+		It("uses the timeout and retries for downloads", func() {
+			sourceConfig := "artifact_source:\n  timeout: 10m\n  attempts: 5\n  backoff: 1s\n  max_backoff: 10s\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+sourceConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ArtifactSource.Timeout).To(Equal(10 * time.Minute))
+			Expect(config.ArtifactSource.Attempts).To(Equal(5))
+			Expect(config.ArtifactSource.Backoff).To(Equal(time.Second))
+			Expect(config.ArtifactSource.MaxBackoff).To(Equal(10 * time.Second))
+		})
+
+		It("defaults to a four minute timeout and three attempts", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ArtifactSource.Timeout).To(Equal(4 * time.Minute))
+			Expect(config.ArtifactSource.Attempts).To(Equal(3))
+			Expect(config.ArtifactSource.Backoff).To(Equal(2 * time.Second))
+			Expect(config.ArtifactSource.MaxBackoff).To(Equal(30 * time.Second))
+		})
+
+		It("returns an error when the timeout is not a duration", func() {
+			sourceConfig := "artifact_source:\n  timeout: forever\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+sourceConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidDurationError{"artifact_source.timeout", "forever"}))
+		})
+
+		It("returns an error when attempts is negative", func() {
+			sourceConfig := "artifact_source:\n  attempts: -1\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+sourceConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidDownloadAttemptsError{-1}))
+		})
+
+		It("returns an error when credentials have no host", func() {
+			sourceConfig := "artifact_source:\n  credentials:\n  - token: token\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+sourceConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidArtifactCredentialsError{"", "a host is required"}))
+		})
+
+		It("returns an error when credentials have both a username and a token", func() {
+			sourceConfig := "artifact_source:\n  credentials:\n  - host: example.com\n    username: username\n    token: token\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+sourceConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidArtifactCredentialsError{"example.com", "use either a username and password or a token"}))
+		})
+	})
+
+	Context("when cf_retry is not in the config", func() {
+		It("retries transient failures three times", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Retry.Attempts).To(Equal(3))
+			Expect(config.Retry.Backoff).To(Equal(2 * time.Second))
+			Expect(config.Retry.MaxBackoff).To(Equal(30 * time.Second))
+			Expect(config.Retry.TransientErrors).To(ContainElement(regexp.MustCompile("502 Bad Gateway")))
+		})
+	})
+
+	Context("when cf_retry is in the config", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("uses the values as the retry policy", func() {
+			retryConfig := `cf_retry:
+  attempts: 5
+  backoff: 1s
+  max_backoff: 10s
+  transient_errors:
+  - "status code: 5\\d\\d"
+`
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+retryConfig), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Retry).To(Equal(Retry{
+				Attempts:        5,
+				Backoff:         time.Second,
+				MaxBackoff:      10 * time.Second,
+				TransientErrors: []*regexp.Regexp{regexp.MustCompile(`status code: 5\d\d`)},
+			}))
+		})
+
+		It("returns an error when attempts is negative", func() {
+			retryConfig := "cf_retry:\n  attempts: -1\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+retryConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidRetryAttemptsError{-1}))
+		})
+
+		It("returns an error when the backoff is not a duration", func() {
+			retryConfig := "cf_retry:\n  backoff: later\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+retryConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidDurationError{"cf_retry.backoff", "later"}))
+		})
+
+		It("returns an error when a transient error is not a regular expression", func() {
+			retryConfig := "cf_retry:\n  transient_errors:\n  - \"(502\"\n"
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+retryConfig), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(BeAssignableToTypeOf(InvalidTransientErrorError{}))
+		})
+	})
+
+	Context("when environments have their own credentials", func() {
+		var secretsDirectory string
+
+		BeforeEach(func() {
+			var err error
+			secretsDirectory, err = ioutil.TempDir("", "deployadactyl-secrets-")
+			Expect(err).ToNot(HaveOccurred())
+
+			env.GetCall.Returns.Values["PROD_USERNAME"] = "prod-username"
+			env.GetCall.Returns.Values["PROD_PASSWORD"] = "prod-password"
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(secretsDirectory)).To(Succeed())
+		})
+
+		credentialsConfig := func(testCredentials, prodCredentials string) string {
+			return `---
+environments:
+- name: Test
+  foundations:
+  - api1.example.com
+  - api2.example.com
+` + testCredentials + `
+- name: Prod
+  foundations:
+  - api3.example.com
+` + prodCredentials
+		}
+
+		It("reads the credentials from environment variables and files", func() {
+			passwordFile := secretsDirectory + "/password"
+			Expect(ioutil.WriteFile(passwordFile, []byte("test-password\n"), 0600)).To(Succeed())
+			env.GetCall.Returns.Values["TEST_USERNAME"] = "test-username"
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(credentialsConfig(`
+  credentials:
+    username_env: TEST_USERNAME
+    password_file: `+passwordFile, `
+  credentials:
+    username_env: PROD_USERNAME
+    password_env: PROD_PASSWORD
+`)), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["test"].Credentials.Username).To(Equal("test-username"))
+			Expect(config.Environments["test"].Credentials.Password).To(Equal("test-password"))
+			Expect(config.Environments["prod"].Credentials.Username).To(Equal("prod-username"))
+			Expect(config.Environments["prod"].Credentials.Password).To(Equal("prod-password"))
+		})
+
+		It("does not require CF_USERNAME and CF_PASSWORD when every environment has credentials", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(credentialsConfig(`
+  credentials:
+    username_env: PROD_USERNAME
+    password_env: PROD_PASSWORD`, `
+  credentials:
+    username_env: PROD_USERNAME
+    password_env: PROD_PASSWORD
+`)), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("requires CF_USERNAME and CF_PASSWORD when an environment has no credentials", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(credentialsConfig("", `
+  credentials:
+    username_env: PROD_USERNAME
+    password_env: PROD_PASSWORD
+`)), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError("missing environment variables: CF_USERNAME, CF_PASSWORD"))
+		})
+
+		It("reads the credentials of single foundations", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(credentialsConfig(`
+  foundation_credentials:
+    api2.example.com:
+      username_env: PROD_USERNAME
+      password_env: PROD_PASSWORD`, "")), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["test"].FoundationCredentials).To(HaveLen(1))
+			Expect(config.Environments["test"].FoundationCredentials["api2.example.com"].Username).To(Equal("prod-username"))
+			Expect(config.Environments["test"].FoundationCredentials["api2.example.com"].Password).To(Equal("prod-password"))
+		})
+
+		It("returns an error when foundation credentials are not for a foundation of the environment", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(credentialsConfig(`
+  foundation_credentials:
+    api3.example.com:
+      username_env: PROD_USERNAME
+      password_env: PROD_PASSWORD`, "")), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidCredentialsError{"foundation api3.example.com of environment Test", "it is not one of the foundations of the environment"}))
+		})
+
+		It("returns an error when an environment variable is not set", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(credentialsConfig(`
+  credentials:
+    username_env: MISSING_USERNAME
+    password_env: PROD_PASSWORD`, "")), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(MissingCredentialsError{"environment Test", "environment variable MISSING_USERNAME is not set"}))
+		})
+
+		It("returns an error when a file cannot be read", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(credentialsConfig(`
+  credentials:
+    username_env: PROD_USERNAME
+    password_file: `+secretsDirectory+`/missing`, "")), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(BeAssignableToTypeOf(ReadCredentialsFileError{}))
+		})
+
+		It("returns an error when a username or password is named twice or not at all", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(credentialsConfig(`
+  credentials:
+    username_env: PROD_USERNAME
+    username_file: /secrets/username`, "")), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidCredentialsError{"environment Test", "use either username_env or username_file"}))
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(credentialsConfig(`
+  credentials:
+    username_env: PROD_USERNAME`, "")), 0644)).To(Succeed())
+
+			_, err = Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidCredentialsError{"environment Test", "password_env or password_file is required"}))
+		})
+	})
+
+	Context("when foundations are configured with maps", func() {
+		foundationsConfig := func(foundations string) string {
+			return `---
+environments:
+- name: Test
+  domain: test.example.com
+  skip_ssl: true
+  foundations:
+` + foundations
+		}
+
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("reads the settings of each foundation next to plain URLs", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(foundationsConfig(`
+  - api1.example.com
+  - url: api2.example.com
+    name: east
+    region: us-east
+    labels:
+      tier: gold
+    domain: east.example.com
+    apps_domain: apps.east.example.com
+    skip_ssl: false
+    weight: 3`)), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			environment := config.Environments["test"]
+			Expect(environment.Foundations).To(Equal([]string{"api1.example.com", "api2.example.com"}))
+			Expect(environment.FoundationDetails).To(HaveLen(1))
+			Expect(environment.Foundation("api2.example.com")).To(Equal(Foundation{
+				URL:        "api2.example.com",
+				Name:       "east",
+				Region:     "us-east",
+				Labels:     map[string]string{"tier": "gold"},
+				Domain:     "east.example.com",
+				AppsDomain: "apps.east.example.com",
+				SkipSSL:    false,
+				Weight:     3,
+			}))
+		})
+
+		It("uses the settings of the environment for the settings that are not given", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(foundationsConfig(`
+  - api1.example.com
+  - url: api2.example.com`)), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			environment := config.Environments["test"]
+			for _, url := range []string{"api1.example.com", "api2.example.com"} {
+				Expect(environment.Foundation(url)).To(Equal(Foundation{
+					URL:     url,
+					Name:    url,
+					Domain:  "test.example.com",
+					SkipSSL: true,
+					Weight:  1,
+				}))
+			}
+		})
+
+		It("returns an error when a foundation has no url", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(foundationsConfig(`
+  - name: east`)), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidFoundationError{"Test", "east", "a url is required"}))
+		})
+
+		It("returns an error when a foundation is configured twice", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(foundationsConfig(`
+  - url: api1.example.com
+  - url: api1.example.com`)), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidFoundationError{"Test", "api1.example.com", "it is configured more than once"}))
+		})
+
+		It("returns an error when a foundation is not a URL or a map", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(foundationsConfig(`
+  - [api1.example.com]`)), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(BeAssignableToTypeOf(InvalidFoundationError{}))
+		})
+
+		It("returns an error when the weight is negative", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(foundationsConfig(`
+  - url: api1.example.com
+    weight: -1`)), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidFoundationError{"Test", "api1.example.com", "invalid weight -1: it must not be negative"}))
+		})
+
+		It("returns an error when the ca_cert has no certificates", func() {
+			caCert, err := ioutil.TempFile("", "deployadactyl-ca-")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.Remove(caCert.Name())
+			caCert.Close()
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(foundationsConfig(`
+  - url: api1.example.com
+    ca_cert: `+caCert.Name())), 0644)).To(Succeed())
+
+			_, err = Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidFoundationError{"Test", "api1.example.com", "no certificates found in ca_cert " + caCert.Name()}))
+		})
+	})
+
+	Context("when the config is interpolated", func() {
+		var secretsDirectory string
+
+		BeforeEach(func() {
+			var err error
+			secretsDirectory, err = ioutil.TempDir("", "deployadactyl-secrets-")
+			Expect(err).ToNot(HaveOccurred())
+
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(secretsDirectory)).To(Succeed())
+		})
+
+		It("replaces environment variables and files", func() {
+			env.GetCall.Returns.Values["DATA_CENTER"] = "east"
+			Expect(ioutil.WriteFile(secretsDirectory+"/token", []byte("artifact-token\n"), 0600)).To(Succeed())
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(`---
+artifact_source:
+  credentials:
+  - host: artifacts.example.com
+    token: ${file:`+secretsDirectory+`/token}
+environments:
+- name: Test
+  domain: $${DATA_CENTER}.example.com
+  foundations:
+  - https://api.${DATA_CENTER}.example.com
+`), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["test"].Domain).To(Equal("${DATA_CENTER}.example.com"))
+			Expect(config.Environments["test"].Foundations).To(Equal([]string{"https://api.east.example.com"}))
+			Expect(config.ArtifactSource.Credentials[0].Token).To(Equal("artifact-token"))
+		})
+
+		It("inserts values as they are, whatever characters they have", func() {
+			env.GetCall.Returns.Values["DOMAIN"] = "apps.example.com #dc1"
+			env.GetCall.Returns.Values["USERNAME"] = "'&user: *name"
+			Expect(ioutil.WriteFile(secretsDirectory+"/password", []byte("-----BEGIN TOKEN-----\nabc: def\n-----END TOKEN-----\n"), 0600)).To(Succeed())
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(`---
+artifact_source:
+  credentials:
+  - host: artifacts.example.com
+    username: ${USERNAME}
+    password: ${file:`+secretsDirectory+`/password}
+environments:
+- name: Test
+  domain: ${DOMAIN}
+  foundations:
+  - https://api.example.com
+`), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["test"].Domain).To(Equal("apps.example.com #dc1"))
+			Expect(config.ArtifactSource.Credentials[0].Username).To(Equal("'&user: *name"))
+			Expect(config.ArtifactSource.Credentials[0].Password).To(Equal("-----BEGIN TOKEN-----\nabc: def\n-----END TOKEN-----"))
+		})
+
+		It("uses values that are booleans or whole numbers for settings of those types", func() {
+			env.GetCall.Returns.Values["INSTANCES"] = "3"
+			env.GetCall.Returns.Values["SKIP_SSL"] = "true"
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(`---
+environments:
+- name: Test
+  domain: example.com
+  instances: ${INSTANCES}
+  skip_ssl: ${SKIP_SSL}
+  foundations:
+  - https://api.example.com
+`), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["test"].Instances).To(Equal(uint16(3)))
+			Expect(config.Environments["test"].SkipSSL).To(BeTrue())
+		})
+
+		It("does not interpolate comments", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+`
+# session_ttl: ${SESSION_TTL}
+`), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("does not show the values of environment variables in errors", func() {
+			env.GetCall.Returns.Values["SESSION_TTL"] = "secret-value"
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+`
+session_ttl: ${SESSION_TTL}
+`), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError("invalid duration for session_ttl: [REDACTED]: use a positive duration such as 30s or 5m"))
+		})
+
+		It("reports every missing environment variable", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(`---
+environments:
+- name: ${ENVIRONMENT_NAME}
+  domain: ${DATA_CENTER}.example.com
+  foundations:
+  - https://api.${DATA_CENTER}.example.com
+`), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError("missing environment variables in config: DATA_CENTER, DATA_CENTER, ENVIRONMENT_NAME"))
+		})
+
+		It("returns an error when a file cannot be read", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(`---
+session_ttl: ${file:`+secretsDirectory+`/missing}
+`), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(BeAssignableToTypeOf(InterpolateFileError{}))
+		})
+
+		It("returns an error when an expression is not a variable or a file", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+`
+session_ttl: ${not a variable}
+`), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidInterpolationError{"${not a variable}"}))
+		})
+
+		It("does not show the contents of files in errors", func() {
+			Expect(ioutil.WriteFile(secretsDirectory+"/ttl", []byte("secret-value"), 0600)).To(Succeed())
+
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+`
+session_ttl: ${file:`+secretsDirectory+`/ttl}
+`), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError("invalid duration for session_ttl: [REDACTED]: use a positive duration such as 30s or 5m"))
+		})
+	})
+
+	Context("when environments have handlers", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("reads which handlers are enabled and their settings", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(`---
+environments:
+- name: Test
+  foundations:
+  - https://api.cf.example.com
+  handlers:
+    environment_variables:
+      enabled: true
+    health_check:
+      enabled: true
+      old_url: api.cf
+      new_url: apps
+- name: Prod
+  foundations:
+  - https://api.cf.example.com
+`), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["test"].Handlers).To(Equal(Handlers{
+				EnvironmentVariables: Handler{Enabled: true},
+				HealthCheck:          HealthCheckHandler{Enabled: true, OldURL: "api.cf", NewURL: "apps"},
+			}))
+			Expect(config.Environments["prod"].Handlers).To(Equal(Handlers{}))
+		})
+
+		It("returns an error when only one of old_url and new_url is given", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(`---
+environments:
+- name: Test
+  foundations:
+  - https://api.cf.example.com
+  handlers:
+    health_check:
+      enabled: true
+      old_url: api.cf
+`), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidHandlerError{"Test", "health_check", "old_url and new_url are required together"}))
+		})
+	})
+
+	Context("when environments have a policy", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		policyConfig := func(policy string) string {
+			return `---
+environments:
+- name: Test
+  foundations:
+  - https://api.example.com
+  instances: 2
+  policy:
+` + policy
+		}
+
+		It("reads the policy", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(policyConfig(`
+    allowed_orgs: [team-*]
+    allowed_spaces: [prod]
+    max_instances: 4
+    timezone: America/Chicago
+    deploy_windows:
+    - days: [mon, Tue]
+      start: "09:00"
+      end: "17:30"
+    freezes:
+    - start: "2026-12-20"
+      end: "2027-01-02"
+      reason: holidays`)), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			policy := config.Environments["test"].Policy
+			Expect(policy.AllowedOrgs).To(Equal([]string{"team-*"}))
+			Expect(policy.AllowedSpaces).To(Equal([]string{"prod"}))
+			Expect(policy.MaxInstances).To(Equal(uint16(4)))
+			Expect(policy.Location.String()).To(Equal("America/Chicago"))
+
+			Expect(policy.DeployWindows[0].Weekdays).To(Equal([]time.Weekday{time.Monday, time.Tuesday}))
+			Expect(policy.DeployWindows[0].Opens).To(Equal(9 * time.Hour))
+			Expect(policy.DeployWindows[0].Closes).To(Equal(17*time.Hour + 30*time.Minute))
+
+			Expect(policy.Freezes[0].Begins).To(Equal(time.Date(2026, time.December, 20, 0, 0, 0, 0, policy.Location)))
+			Expect(policy.Freezes[0].Ends).To(Equal(time.Date(2027, time.January, 3, 0, 0, 0, 0, policy.Location)))
+			Expect(policy.Freezes[0].Reason).To(Equal("holidays"))
+		})
+
+		It("returns an error when the instances of the environment are more than max_instances", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(policyConfig(`
+    max_instances: 1`)), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(InvalidPolicyError{"Test", "instances 2 is more than max_instances 1"}))
+		})
+
+		It("returns an error for every value that cannot be used", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(policyConfig(`
+    allowed_orgs: ["team-["]
+    timezone: Mars/Olympus_Mons
+    deploy_windows:
+    - days: [someday]
+      start: "9am"
+      end: "17:00"
+    freezes:
+    - start: "2027-01-02"
+      end: "2026-12-20"`)), 0644)).To(Succeed())
+
+			Expect(Validate(env.Get, customConfigPath, false)).To(Equal([]error{
+				InvalidPolicyError{"Test", "invalid glob team-[: syntax error in pattern"},
+				InvalidPolicyError{"Test", "unknown timezone Mars/Olympus_Mons"},
+				InvalidPolicyError{"Test", "invalid start 9am of deploy_windows[0]: use a time such as 09:00"},
+				InvalidPolicyError{"Test", "invalid day someday of deploy_windows[0]: use sun, mon, tue, wed, thu, fri or sat"},
+				InvalidPolicyError{"Test", "freezes[0] ends before it starts"},
+			}))
+		})
+	})
+
+	Context("when environments extend templates or other environments", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("inherits the settings that are not overridden", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(`---
+templates:
+- name: base
+  skip_ssl: true
+  authenticate: true
+  instances: 2
+  handlers:
+    health_check:
+      enabled: true
+      old_url: api.cf
+      new_url: apps
+    route_mapper:
+      enabled: true
+  policy:
+    allowed_orgs: [team-*]
+- name: Secure
+  extends: base
+  skip_ssl: false
+environments:
+- name: Preproduction
+  extends: base
+  domain: preproduction.example.com
+  foundations:
+  - https://api.cf.preproduction.example.com
+  instances: 1
+  handlers:
+    route_mapper:
+      enabled: false
+- name: Production
+  extends: secure
+  domain: production.example.com
+  foundations:
+  - https://api.cf.production.example.com
+- name: Production-2
+  extends: production
+  foundations:
+  - https://api.cf.production-2.example.com
+`), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			preproduction := config.Environments["preproduction"]
+			Expect(preproduction.Extends).To(Equal("base"))
+			Expect(preproduction.SkipSSL).To(BeTrue())
+			Expect(preproduction.Authenticate).To(BeTrue())
+			Expect(preproduction.Instances).To(Equal(uint16(1)))
+			Expect(preproduction.Foundations).To(Equal([]string{"https://api.cf.preproduction.example.com"}))
+			Expect(preproduction.Handlers).To(Equal(Handlers{
+				HealthCheck: HealthCheckHandler{Enabled: true, OldURL: "api.cf", NewURL: "apps"},
+				RouteMapper: Handler{Enabled: false},
+			}))
+			Expect(preproduction.Policy.AllowedOrgs).To(Equal([]string{"team-*"}))
+
+			production := config.Environments["production"]
+			Expect(production.SkipSSL).To(BeFalse())
+			Expect(production.Authenticate).To(BeTrue())
+			Expect(production.Instances).To(Equal(uint16(2)))
+			Expect(production.Handlers.RouteMapper.Enabled).To(BeTrue())
+
+			production2 := config.Environments["production-2"]
+			Expect(production2.Name).To(Equal("Production-2"))
+			Expect(production2.Domain).To(Equal("production.example.com"))
+			Expect(production2.SkipSSL).To(BeFalse())
+			Expect(production2.Instances).To(Equal(uint16(2)))
+			Expect(production2.Foundations).To(Equal([]string{"https://api.cf.production-2.example.com"}))
+
+			Expect(config.Environments).To(HaveLen(3))
+		})
+
+		It("returns an error when an environment extends something that does not exist", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(`---
+environments:
+- name: production
+  extends: base
+  foundations:
+  - https://api.example.com
+`), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(UnknownExtendsError{"environment production", "base"}))
+		})
+
+		It("returns an error when an environment extends itself", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(`---
+templates:
+- name: base
+  extends: production
+environments:
+- name: production
+  extends: base
+  foundations:
+  - https://api.example.com
+`), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(ExtendsCycleError{"environment production", []string{"production", "base", "production"}}))
+			Expect(err).To(MatchError("environment production extends itself: production -> base -> production"))
+		})
+
+		It("returns an error when a template has no name", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(`---
+templates:
+- skip_ssl: true
+environments:
+- name: production
+  foundations:
+  - https://api.example.com
+`), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, customConfigPath)
+			Expect(err).To(MatchError(MissingParameterError{"templates[0]", "name"}))
+		})
+
+		It("reports unknown keys in templates", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(`---
+templates:
+- name: base
+  skip_sll: true
+environments:
+- name: production
+  extends: base
+  foundations:
+  - https://api.example.com
+`), 0644)).To(Succeed())
+
+			Expect(Validate(env.Get, customConfigPath, false)).To(Equal([]error{UnknownKeyError{"templates[0].skip_sll"}}))
+		})
+	})
+
 	Context("when an environment variable is missing", func() {
 		It("returns an error", func() {
 			env.GetCall.Returns.Values["CF_USERNAME"] = ""
@@ -139,7 +1254,7 @@ environments:
 				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
 
 				badConfig, err := Custom(env.Get, badConfigPath)
-				Expect(err).To(MatchError(MissingParameterError{}))
+				Expect(err).To(MatchError(MissingParameterError{"environments[0]", "name"}))
 
 				Expect(badConfig.Environments).To(BeEmpty())
 			})
@@ -153,7 +1268,7 @@ environments:
 				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
 
 				badConfig, err := Custom(env.Get, badConfigPath)
-				Expect(err).To(MatchError(MissingParameterError{}))
+				Expect(err).To(MatchError(MissingParameterError{"environment production", "foundations"}))
 
 				Expect(badConfig.Environments).To(BeEmpty())
 			})
@@ -183,5 +1298,201 @@ environments:
 
 			})
 		})
+
+		Context("when the strategy is rolling", func() {
+			It("uses rolling deployments for the environment", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				rollingConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  strategy: rolling
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(rollingConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].Strategy).To(Equal("rolling"))
+			})
+		})
+
+		Context("when an environment has trusted keys", func() {
+			BeforeEach(func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+			})
+
+			It("keeps the keys and whether signed artifacts are required", func() {
+				signedConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  require_signed_artifacts: true
+  trusted_keys:
+  - 11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=
+`
+				Expect(ioutil.WriteFile(badConfigPath, []byte(signedConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].RequireSignedArtifacts).To(BeTrue())
+				Expect(config.Environments["production"].TrustedKeys).To(Equal([]string{"11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="}))
+			})
+
+			It("returns an error when a key is not an ed25519 public key", func() {
+				signedConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  trusted_keys:
+  - bm90IGEga2V5
+`
+				Expect(ioutil.WriteFile(badConfigPath, []byte(signedConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(InvalidTrustedKeyError{"production", "bm90IGEga2V5"}))
+			})
+
+			It("returns an error when signed artifacts are required without trusted keys", func() {
+				signedConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  require_signed_artifacts: true
+`
+				Expect(ioutil.WriteFile(badConfigPath, []byte(signedConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(MissingTrustedKeysError{"production"}))
+			})
+		})
+
+		Context("when the strategy is unknown", func() {
+			It("returns an error", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  strategy: canary
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(InvalidStrategyError{"production", "canary"}))
+			})
+		})
+
+		Context("when two environments have the same name", func() {
+			It("returns an error instead of using the last one", func() {
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+- name: Production
+  foundations:
+  - api2.example.com
+`
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(DuplicateEnvironmentError{"Production"}))
+			})
+		})
+
+		Context("when a foundation url is malformed", func() {
+			It("returns an error", func() {
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - ftp://api1.example.com
+`
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(InvalidFoundationError{"production", "ftp://api1.example.com", "unsupported scheme ftp: use http or https"}))
+			})
+		})
+	})
+
+	Describe("Validate", func() {
+		It("returns no problems for a valid config", func() {
+			Expect(Validate(env.Get, customConfigPath, false)).To(BeEmpty())
+		})
+
+		It("returns every problem with its location", func() {
+			testBadConfig := `---
+session_ttl: forever
+cf_retry:
+  atempts: 2
+environments:
+- name: production
+  foundations:
+  - https://api1.example.com
+  - url: api 2.example.com
+  - url: https://api3.example.com
+    regoin: east
+  strategy: canary
+- domain: example.com
+  foundations:
+  - https://api4.example.com
+- name: Production
+  foundations:
+  - https://api5.example.com
+`
+			Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+			Expect(Validate(env.Get, badConfigPath, false)).To(Equal([]error{
+				UnknownKeyError{"cf_retry.atempts"},
+				UnknownKeyError{"environments[0].foundations[2].regoin"},
+				InvalidFoundationError{"production", "api 2.example.com", "the url contains whitespace"},
+				InvalidStrategyError{"production", "canary"},
+				MissingParameterError{"environments[1]", "name"},
+				DuplicateEnvironmentError{"Production"},
+				InvalidDurationError{"session_ttl", "forever"},
+			}))
+		})
+
+		It("reports duplicate environments", func() {
+			testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - https://api1.example.com
+- name: Production
+  foundations:
+  - https://api2.example.com
+`
+			Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+			Expect(Validate(env.Get, badConfigPath, false)).To(Equal([]error{DuplicateEnvironmentError{"Production"}}))
+		})
+
+		It("only checks the credentials when it is asked to", func() {
+			Expect(Validate(env.Get, customConfigPath, true)).To(ConsistOf(MatchError("missing environment variables: CF_USERNAME, CF_PASSWORD")))
+		})
+
+		It("returns an error when the config cannot be parsed", func() {
+			Expect(ioutil.WriteFile(badConfigPath, []byte("environments: [\n"), 0644)).To(Succeed())
+
+			errs := Validate(env.Get, badConfigPath, false)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0]).To(BeAssignableToTypeOf(ParseYamlError{}))
+		})
 	})
 })
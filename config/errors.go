@@ -1,6 +1,9 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type EnvironmentsNotSpecifiedError struct{}
 
@@ -8,10 +11,29 @@ func (e EnvironmentsNotSpecifiedError) Error() string {
 	return "environments key not specified in the configuration"
 }
 
-type MissingParameterError struct{}
+type MissingParameterError struct {
+	Location  string
+	Parameter string
+}
 
 func (e MissingParameterError) Error() string {
-	return "missing required parameter in the environments key"
+	return fmt.Sprintf("missing required parameter %s in %s", e.Parameter, e.Location)
+}
+
+type DuplicateEnvironmentError struct {
+	Name string
+}
+
+func (e DuplicateEnvironmentError) Error() string {
+	return fmt.Sprintf("environment %s is configured more than once: environment names are not case sensitive", e.Name)
+}
+
+type UnknownKeyError struct {
+	Location string
+}
+
+func (e UnknownKeyError) Error() string {
+	return fmt.Sprintf("unknown key %s", e.Location)
 }
 
 type ParseYamlError struct {
@@ -21,3 +43,197 @@ type ParseYamlError struct {
 func (e ParseYamlError) Error() string {
 	return fmt.Sprintf("cannot parse yaml file: %s", e.Err)
 }
+
+type InvalidDurationError struct {
+	Key   string
+	Value string
+}
+
+func (e InvalidDurationError) Error() string {
+	return fmt.Sprintf("invalid duration for %s: %s: use a positive duration such as 30s or 5m", e.Key, e.Value)
+}
+
+type InvalidRetryAttemptsError struct {
+	Attempts int
+}
+
+func (e InvalidRetryAttemptsError) Error() string {
+	return fmt.Sprintf("invalid attempts for cf_retry: %d: use a positive number of attempts", e.Attempts)
+}
+
+type InvalidTransientErrorError struct {
+	Pattern string
+	Err     error
+}
+
+func (e InvalidTransientErrorError) Error() string {
+	return fmt.Sprintf("invalid transient error pattern for cf_retry: %s: %s", e.Pattern, e.Err)
+}
+
+type InvalidStrategyError struct {
+	Environment string
+	Strategy    string
+}
+
+func (e InvalidStrategyError) Error() string {
+	return fmt.Sprintf("invalid strategy for environment %s: %s: use blue-green or rolling", e.Environment, e.Strategy)
+}
+
+type InvalidArtifactCacheSizeError struct {
+	MaxSizeMB int64
+}
+
+func (e InvalidArtifactCacheSizeError) Error() string {
+	return fmt.Sprintf("invalid max_size_mb for artifact_cache: %d: use a positive number of megabytes", e.MaxSizeMB)
+}
+
+type InvalidArtifactCredentialsError struct {
+	Host   string
+	Reason string
+}
+
+func (e InvalidArtifactCredentialsError) Error() string {
+	return fmt.Sprintf("invalid artifact credentials for host %s: %s", e.Host, e.Reason)
+}
+
+type InvalidDownloadAttemptsError struct {
+	Attempts int
+}
+
+func (e InvalidDownloadAttemptsError) Error() string {
+	return fmt.Sprintf("invalid attempts for artifact_source: %d: use a positive number of attempts", e.Attempts)
+}
+
+type InvalidTrustedKeyError struct {
+	Environment string
+	Key         string
+}
+
+func (e InvalidTrustedKeyError) Error() string {
+	return fmt.Sprintf("invalid trusted key for environment %s: %s: use a base64 encoded ed25519 public key", e.Environment, e.Key)
+}
+
+type MissingTrustedKeysError struct {
+	Environment string
+}
+
+func (e MissingTrustedKeysError) Error() string {
+	return fmt.Sprintf("environment %s requires signed artifacts but has no trusted_keys", e.Environment)
+}
+
+type InvalidWorkingDirectorySizeError struct {
+	Key  string
+	Size int64
+}
+
+func (e InvalidWorkingDirectorySizeError) Error() string {
+	return fmt.Sprintf("invalid %s for working_directory: %d: use a positive number of megabytes", e.Key, e.Size)
+}
+
+type InvalidCredentialsError struct {
+	Owner  string
+	Reason string
+}
+
+func (e InvalidCredentialsError) Error() string {
+	return fmt.Sprintf("invalid credentials for %s: %s", e.Owner, e.Reason)
+}
+
+type MissingCredentialsError struct {
+	Owner  string
+	Reason string
+}
+
+func (e MissingCredentialsError) Error() string {
+	return fmt.Sprintf("missing credentials for %s: %s", e.Owner, e.Reason)
+}
+
+type ReadCredentialsFileError struct {
+	Owner string
+	File  string
+	Err   error
+}
+
+func (e ReadCredentialsFileError) Error() string {
+	return fmt.Sprintf("cannot read credentials for %s from %s: %s", e.Owner, e.File, e.Err)
+}
+
+type InvalidFoundationError struct {
+	Environment string
+	Foundation  string
+	Reason      string
+}
+
+func (e InvalidFoundationError) Error() string {
+	return fmt.Sprintf("invalid foundation %s in environment %s: %s", e.Foundation, e.Environment, e.Reason)
+}
+
+type InterpolateFileError struct {
+	File string
+	Err  error
+}
+
+func (e InterpolateFileError) Error() string {
+	return fmt.Sprintf("cannot interpolate file %s in config: %s", e.File, e.Err)
+}
+
+type InvalidInterpolationError struct {
+	Expression string
+}
+
+func (e InvalidInterpolationError) Error() string {
+	return fmt.Sprintf("invalid interpolation %s in config: use ${VAR} or ${file:/path}", e.Expression)
+}
+
+type RedactedError struct {
+	Message string
+}
+
+func (e RedactedError) Error() string {
+	return e.Message
+}
+
+type InvalidHandlerError struct {
+	Environment string
+	Handler     string
+	Reason      string
+}
+
+func (e InvalidHandlerError) Error() string {
+	return fmt.Sprintf("invalid %s handler in environment %s: %s", e.Handler, e.Environment, e.Reason)
+}
+
+type InvalidPolicyError struct {
+	Environment string
+	Reason      string
+}
+
+func (e InvalidPolicyError) Error() string {
+	return fmt.Sprintf("invalid policy in environment %s: %s", e.Environment, e.Reason)
+}
+
+type UnknownExtendsError struct {
+	Name    string
+	Extends string
+}
+
+func (e UnknownExtendsError) Error() string {
+	return fmt.Sprintf("%s extends %s, which is not a template or an environment", e.Name, e.Extends)
+}
+
+type ExtendsCycleError struct {
+	Name  string
+	Chain []string
+}
+
+func (e ExtendsCycleError) Error() string {
+	return fmt.Sprintf("%s extends itself: %s", e.Name, strings.Join(e.Chain, " -> "))
+}
+
+type InvalidServerError struct {
+	Reason string
+}
+
+func (e InvalidServerError) Error() string {
+	return fmt.Sprintf("invalid server: %s", e.Reason)
+}
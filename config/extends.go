@@ -0,0 +1,204 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/candiedyaml"
+)
+
+const (
+	environmentsKey = "environments"
+	templatesKey    = "templates"
+	extendsKey      = "extends"
+)
+
+// base is a template or an environment that can be extended.
+type base struct {
+	kind     string
+	name     string
+	settings map[string]interface{}
+}
+
+func (b base) String() string {
+	return fmt.Sprintf("%s %s", b.kind, b.name)
+}
+
+// resolveExtends returns the config with the settings that every environment inherits through extends.
+// An environment or template that extends another one gets every setting of it, apart from its name,
+// and overrides the settings it has itself. Maps such as handlers and policy are merged key by key, and
+// any other setting replaces the inherited one. extends names a template, or an environment when
+// there is no template with the name. The config is returned as it is when nothing is extended.
+func resolveExtends(data []byte) ([]byte, error) {
+	var document interface{}
+	if err := candiedyaml.Unmarshal(data, &document); err != nil {
+		return nil, ParseYamlError{err}
+	}
+
+	root, ok := toStringMap(document)
+	if !ok {
+		return data, nil
+	}
+
+	templates, _ := root[templatesKey].([]interface{})
+	environments, _ := root[environmentsKey].([]interface{})
+
+	if len(templates) == 0 && !extendsAny(environments) {
+		return data, nil
+	}
+
+	r := resolver{
+		templates:    map[string]base{},
+		environments: map[string]base{},
+		resolved:     map[string]map[string]interface{}{},
+	}
+
+	for i, template := range templates {
+		settings, ok := toStringMap(template)
+		if !ok || settings["name"] == nil {
+			return nil, MissingParameterError{fmt.Sprintf("%s[%d]", templatesKey, i), "name"}
+		}
+
+		name := fmt.Sprint(settings["name"])
+		key := strings.ToLower(name)
+		if _, ok := r.templates[key]; !ok {
+			r.templates[key] = base{"template", name, settings}
+		}
+	}
+
+	for _, environment := range environments {
+		if settings, ok := toStringMap(environment); ok && settings["name"] != nil {
+			name := fmt.Sprint(settings["name"])
+			key := strings.ToLower(name)
+			if _, ok := r.environments[key]; !ok {
+				r.environments[key] = base{"environment", name, settings}
+			}
+		}
+	}
+
+	resolvedEnvironments := make([]interface{}, len(environments))
+	for i, environment := range environments {
+		settings, ok := toStringMap(environment)
+		if !ok || settings[extendsKey] == nil {
+			resolvedEnvironments[i] = environment
+			continue
+		}
+
+		name := fmt.Sprintf("environments[%d]", i)
+		if settings["name"] != nil {
+			name = fmt.Sprint(settings["name"])
+		}
+
+		resolved, err := r.resolve(base{"environment", name, settings}, nil)
+		if err != nil {
+			return nil, err
+		}
+		resolvedEnvironments[i] = resolved
+	}
+
+	root[environmentsKey] = resolvedEnvironments
+	delete(root, templatesKey)
+
+	resolvedData, err := candiedyaml.Marshal(root)
+	if err != nil {
+		return nil, ParseYamlError{err}
+	}
+	return resolvedData, nil
+}
+
+type resolver struct {
+	templates    map[string]base
+	environments map[string]base
+	resolved     map[string]map[string]interface{}
+}
+
+// resolve returns the settings of b with the settings it inherits. chain holds the templates and
+// environments that are being resolved, so a cycle is found when b is already in it.
+func (r resolver) resolve(b base, chain []base) (map[string]interface{}, error) {
+	for i, previous := range chain {
+		if strings.EqualFold(previous.String(), b.String()) {
+			var names []string
+			for _, link := range append(chain[i:], b) {
+				names = append(names, link.name)
+			}
+			return nil, ExtendsCycleError{chain[i].String(), names}
+		}
+	}
+
+	if settings, ok := r.resolved[strings.ToLower(b.String())]; ok {
+		return settings, nil
+	}
+
+	if b.settings[extendsKey] == nil {
+		return b.settings, nil
+	}
+
+	extends := fmt.Sprint(b.settings[extendsKey])
+
+	parent, ok := r.templates[strings.ToLower(extends)]
+	if !ok {
+		parent, ok = r.environments[strings.ToLower(extends)]
+	}
+	if !ok {
+		return nil, UnknownExtendsError{b.String(), extends}
+	}
+
+	inherited, err := r.resolve(parent, append(chain, b))
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]interface{}{}
+	for key, value := range inherited {
+		if key != "name" && key != extendsKey {
+			settings[key] = value
+		}
+	}
+	settings = merge(settings, b.settings)
+
+	r.resolved[strings.ToLower(b.String())] = settings
+	return settings, nil
+}
+
+// merge returns the settings of base with the settings of override. Maps that are in both are merged.
+func merge(base, override map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range override {
+		baseMap, baseIsMap := toStringMap(merged[key])
+		overrideMap, overrideIsMap := toStringMap(value)
+		if baseIsMap && overrideIsMap {
+			merged[key] = merge(baseMap, overrideMap)
+			continue
+		}
+		merged[key] = value
+	}
+
+	return merged
+}
+
+func extendsAny(environments []interface{}) bool {
+	for _, environment := range environments {
+		if settings, ok := toStringMap(environment); ok && settings[extendsKey] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		converted := map[string]interface{}{}
+		for key, value := range m {
+			converted[fmt.Sprint(key)] = value
+		}
+		return converted, true
+	}
+	return nil, false
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/candiedyaml"
+	"github.com/compozed/deployadactyl/geterrors"
+)
+
+const (
+	filePrefix = "file:"
+	redacted   = "[REDACTED]"
+)
+
+var (
+	interpolationPattern = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+	variableNamePattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// readConfigFile reads a config file and interpolates it. It also returns the values that were
+// interpolated, so they can be removed from errors.
+func readConfigFile(getenv func(string) string, filename string) ([]byte, []string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return interpolate(getenv, data)
+}
+
+// interpolate replaces ${VAR} with the value of the environment variable VAR and ${file:/path} with the
+// contents of the file at /path, without the whitespace around them. $${ is replaced with ${ so it can be
+// written in the config. Every missing environment variable is reported in the same error.
+//
+// The values in the parsed config are interpolated rather than its text, so comments are left out and an
+// interpolated value is always a single value, whatever characters it has. A value that is only true, false
+// or a whole number after it is interpolated can be used for a setting of that type.
+func interpolate(getenv func(string) string, data []byte) ([]byte, []string, error) {
+	var document interface{}
+	if err := candiedyaml.Unmarshal(data, &document); err != nil {
+		return nil, nil, ParseYamlError{err}
+	}
+
+	i := &interpolator{getter: geterrors.WrapFunc(getenv)}
+	document = i.value(document)
+
+	if i.err != nil {
+		return nil, nil, i.err
+	}
+
+	if err := i.getter.Err("missing environment variables in config"); err != nil {
+		return nil, nil, err
+	}
+
+	if !i.changed {
+		return data, nil, nil
+	}
+
+	interpolated, err := candiedyaml.Marshal(document)
+	if err != nil {
+		return nil, nil, ParseYamlError{err}
+	}
+
+	return interpolated, i.secrets, nil
+}
+
+type interpolator struct {
+	getter  geterrors.ErrGetter
+	secrets []string
+	changed bool
+	err     error
+}
+
+// value returns value with every string in it interpolated. Maps are interpolated in the order of
+// their keys, so missing environment variables are always reported in the same order.
+func (i *interpolator) value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return i.scalar(v)
+	case []interface{}:
+		for index, item := range v {
+			v[index] = i.value(item)
+		}
+	case map[interface{}]interface{}:
+		keys := map[string]interface{}{}
+		var names []string
+		for key := range v {
+			name := fmt.Sprint(key)
+			keys[name] = key
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			v[keys[name]] = i.value(v[keys[name]])
+		}
+	}
+
+	return value
+}
+
+func (i *interpolator) scalar(s string) interface{} {
+	if !interpolationPattern.MatchString(s) {
+		return s
+	}
+	i.changed = true
+
+	interpolated := interpolationPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		expression := match[2 : len(match)-1]
+
+		if strings.HasPrefix(expression, filePrefix) {
+			file := strings.TrimPrefix(expression, filePrefix)
+
+			contents, err := ioutil.ReadFile(file)
+			if err != nil {
+				if i.err == nil {
+					i.err = InterpolateFileError{file, err}
+				}
+				return ""
+			}
+
+			secret := strings.TrimSpace(string(contents))
+			i.secrets = append(i.secrets, secret)
+			return secret
+		}
+
+		if !variableNamePattern.MatchString(expression) {
+			if i.err == nil {
+				i.err = InvalidInterpolationError{match}
+			}
+			return ""
+		}
+
+		value := i.getter.Get(expression)
+		if _, ok := typedScalar(value); !ok {
+			i.secrets = append(i.secrets, value)
+		}
+		return value
+	})
+
+	if typed, ok := typedScalar(interpolated); ok {
+		return typed
+	}
+	return interpolated
+}
+
+// typedScalar returns s as a bool or an int when it is written the way YAML writes them.
+func typedScalar(s string) (interface{}, bool) {
+	switch s {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	}
+
+	if n, err := strconv.Atoi(s); err == nil && strconv.Itoa(n) == s {
+		return n, true
+	}
+
+	return nil, false
+}
+
+// redact replaces the secrets in the message of an error so they are not logged.
+// The error is returned as it is when it does not contain a secret.
+func redact(err error, secrets []string) error {
+	message := err.Error()
+	for _, secret := range secrets {
+		if secret != "" {
+			message = strings.Replace(message, secret, redacted, -1)
+		}
+	}
+
+	if message == err.Error() {
+		return err
+	}
+	return RedactedError{message}
+}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	freezeDateFormat     = "2006-01-02"
+	freezeDateTimeFormat = "2006-01-02 15:04"
+	windowTimeFormat     = "15:04"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Policy limits the deployments to an environment. Orgs and spaces must match one of AllowedOrgs and
+// AllowedSpaces, which are globs such as team-*, when they are given. Deployments can have at most
+// MaxInstances instances, and are only allowed during DeployWindows and outside Freezes when they are given.
+// DeployWindows and Freezes are in Timezone, which is UTC by default.
+type Policy struct {
+	AllowedOrgs   []string       `yaml:"allowed_orgs"`
+	AllowedSpaces []string       `yaml:"allowed_spaces"`
+	MaxInstances  uint16         `yaml:"max_instances"`
+	Timezone      string         `yaml:"timezone"`
+	DeployWindows []DeployWindow `yaml:"deploy_windows"`
+	Freezes       []Freeze       `yaml:"freezes"`
+
+	// Location is the location of Timezone. It is set when the config is read and is nil for UTC.
+	Location *time.Location `yaml:"-"`
+}
+
+// In returns t in the timezone of the policy.
+func (p Policy) In(t time.Time) time.Time {
+	if p.Location == nil {
+		return t.UTC()
+	}
+	return t.In(p.Location)
+}
+
+// DeployWindow is a time of day on days of the week when deployments are allowed, such as 09:00 to 17:00
+// on mon, tue, wed, thu and fri. Every day is used when Days is empty, and a window that ends before
+// it starts goes past midnight. Weekdays, Opens and Closes are set when the config is read.
+type DeployWindow struct {
+	Days  []string
+	Start string
+	End   string
+
+	Weekdays []time.Weekday `yaml:"-"`
+	Opens    time.Duration  `yaml:"-"`
+	Closes   time.Duration  `yaml:"-"`
+}
+
+// Freeze is a period when deployments are not allowed. Start and End are dates such as 2026-12-20 or
+// dates and times such as 2026-12-20 18:00, and a date for End includes the whole day.
+// Begins and Ends are set when the config is read.
+type Freeze struct {
+	Start  string
+	End    string
+	Reason string
+
+	Begins time.Time `yaml:"-"`
+	Ends   time.Time `yaml:"-"`
+}
+
+// Active reports whether a deploy window is open at t.
+func (w DeployWindow) Active(t time.Time) bool {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	sinceMidnight := t.Sub(midnight)
+
+	if w.Opens <= w.Closes {
+		return w.onDay(t.Weekday()) && sinceMidnight >= w.Opens && sinceMidnight < w.Closes
+	}
+
+	// The window goes past midnight, so the early hours belong to the window that opened the day before.
+	if sinceMidnight >= w.Opens {
+		return w.onDay(t.Weekday())
+	}
+	return sinceMidnight < w.Closes && w.onDay((t.Weekday()+6)%7)
+}
+
+func (w DeployWindow) onDay(day time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+
+	for _, weekday := range w.Weekdays {
+		if weekday == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether a freeze is in place at t.
+func (f Freeze) Active(t time.Time) bool {
+	return !t.Before(f.Begins) && t.Before(f.Ends)
+}
+
+// getPolicy checks the policy of an environment and sets the parsed values of its timezone,
+// deploy windows and freezes.
+func getPolicy(environment *Environment) []error {
+	var (
+		errs   []error
+		policy = &environment.Policy
+	)
+
+	invalid := func(reason string, args ...interface{}) {
+		errs = append(errs, InvalidPolicyError{environment.Name, fmt.Sprintf(reason, args...)})
+	}
+
+	for _, glob := range append(append([]string{}, policy.AllowedOrgs...), policy.AllowedSpaces...) {
+		if _, err := path.Match(glob, ""); err != nil {
+			invalid("invalid glob %s: %s", glob, err)
+		}
+	}
+
+	if policy.MaxInstances > 0 && environment.Instances > policy.MaxInstances {
+		invalid("instances %d is more than max_instances %d", environment.Instances, policy.MaxInstances)
+	}
+
+	location := time.UTC
+	if policy.Timezone != "" {
+		loaded, err := time.LoadLocation(policy.Timezone)
+		if err != nil {
+			invalid("unknown timezone %s", policy.Timezone)
+		} else {
+			location = loaded
+			policy.Location = loaded
+		}
+	}
+
+	for i, window := range policy.DeployWindows {
+		opens, err := parseTimeOfDay(window.Start)
+		if err != nil {
+			invalid("invalid start %s of deploy_windows[%d]: use a time such as 09:00", window.Start, i)
+		}
+
+		closes, err := parseTimeOfDay(window.End)
+		if err != nil {
+			invalid("invalid end %s of deploy_windows[%d]: use a time such as 17:00", window.End, i)
+		}
+
+		window.Weekdays = nil
+		for _, day := range window.Days {
+			weekday, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				invalid("invalid day %s of deploy_windows[%d]: use sun, mon, tue, wed, thu, fri or sat", day, i)
+				continue
+			}
+			window.Weekdays = append(window.Weekdays, weekday)
+		}
+
+		window.Opens = opens
+		window.Closes = closes
+		policy.DeployWindows[i] = window
+	}
+
+	for i, freeze := range policy.Freezes {
+		begins, _, err := parseFreezeTime(freeze.Start, location)
+		if err != nil {
+			invalid("invalid start %s of freezes[%d]: use a date such as 2026-12-20 or a date and time such as 2026-12-20 18:00", freeze.Start, i)
+		}
+
+		ends, isDate, err := parseFreezeTime(freeze.End, location)
+		if err != nil {
+			invalid("invalid end %s of freezes[%d]: use a date such as 2027-01-02 or a date and time such as 2027-01-02 08:00", freeze.End, i)
+		}
+		if isDate {
+			ends = ends.AddDate(0, 0, 1)
+		}
+
+		if err == nil && !ends.After(begins) {
+			invalid("freezes[%d] ends before it starts", i)
+		}
+
+		freeze.Begins = begins
+		freeze.Ends = ends
+		policy.Freezes[i] = freeze
+	}
+
+	return errs
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse(windowTimeFormat, value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// parseFreezeTime parses a date or a date and time, and reports whether it was only a date.
+func parseFreezeTime(value string, location *time.Location) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(freezeDateFormat, value, location); err == nil {
+		return t, true, nil
+	}
+
+	t, err := time.ParseInLocation(freezeDateTimeFormat, value, location)
+	return t, false, err
+}
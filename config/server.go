@@ -0,0 +1,112 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+const (
+	defaultServerAddress      = "0.0.0.0"
+	defaultServerReadTimeout  = 10 * time.Minute
+	defaultServerWriteTimeout = time.Hour
+	defaultServerIdleTimeout  = 2 * time.Minute
+)
+
+// Server is how Deployadactyl listens for requests. It binds Address on PORT, and each request
+// can take ReadTimeout to be read, including its artifact, and WriteTimeout to be answered,
+// including the output of the deploy. A timeout of 0 does not time out. A keep-alive connection is
+// closed after IdleTimeout without a request, or after ReadTimeout when IdleTimeout is 0.
+type Server struct {
+	Address      string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	TLS          ServerTLS
+}
+
+// ServerTLS serves requests over TLS with the certificate and key in CertFile and KeyFile when
+// they are set. Clients must present a certificate signed by one of the PEM certificates in
+// ClientCAFile when it is set.
+type ServerTLS struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// Enabled reports whether requests are served over TLS.
+func (t ServerTLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+type serverYaml struct {
+	Address      string
+	ReadTimeout  string `yaml:"read_timeout"`
+	WriteTimeout string `yaml:"write_timeout"`
+	IdleTimeout  string `yaml:"idle_timeout"`
+	TLS          ServerTLS
+}
+
+// getServer returns the server settings with their defaults and every problem with them.
+// The certificate, key and client CAs are read so a server that cannot start is found with the config.
+func getServer(serverConfig serverYaml) (Server, []error) {
+	var errs []error
+
+	address := serverConfig.Address
+	if address == "" {
+		address = defaultServerAddress
+	}
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		errs = append(errs, InvalidServerError{fmt.Sprintf("address %s must not have a port: the port is read from PORT", address)})
+	}
+
+	readTimeout, err := getDuration("server.read_timeout", serverConfig.ReadTimeout, defaultServerReadTimeout)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	writeTimeout, err := getDuration("server.write_timeout", serverConfig.WriteTimeout, defaultServerWriteTimeout)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	idleTimeout, err := getDuration("server.idle_timeout", serverConfig.IdleTimeout, defaultServerIdleTimeout)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	tlsConfig := serverConfig.TLS
+
+	switch {
+	case tlsConfig.CertFile == "" && tlsConfig.KeyFile == "":
+		if tlsConfig.ClientCAFile != "" {
+			errs = append(errs, InvalidServerError{"tls.client_ca_file needs tls.cert_file and tls.key_file"})
+		}
+	case tlsConfig.CertFile == "" || tlsConfig.KeyFile == "":
+		errs = append(errs, InvalidServerError{"tls.cert_file and tls.key_file must be set together"})
+	default:
+		if _, err := tls.LoadX509KeyPair(tlsConfig.CertFile, tlsConfig.KeyFile); err != nil {
+			errs = append(errs, InvalidServerError{fmt.Sprintf("cannot load tls.cert_file and tls.key_file: %s", err)})
+		}
+
+		if tlsConfig.ClientCAFile != "" {
+			pem, err := ioutil.ReadFile(tlsConfig.ClientCAFile)
+			if err != nil {
+				errs = append(errs, InvalidServerError{fmt.Sprintf("cannot read tls.client_ca_file: %s", err)})
+			} else if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+				errs = append(errs, InvalidServerError{fmt.Sprintf("no certificates found in tls.client_ca_file %s", tlsConfig.ClientCAFile)})
+			}
+		}
+	}
+
+	return Server{
+		Address:      address,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+		IdleTimeout:  idleTimeout,
+		TLS:          tlsConfig,
+	}, errs
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/candiedyaml"
+)
+
+// Validate reads and interpolates a config file and returns every problem with it, including keys that are not used.
+// The credentials are only read when checkCredentials is true, so a config can be validated where
+// CF_USERNAME, CF_PASSWORD and the credentials of the environments are not available.
+func Validate(getenv func(string) string, configPath string, checkCredentials bool) []error {
+	data, secrets, err := readConfigFile(getenv, configPath)
+	if err != nil {
+		return []error{err}
+	}
+
+	foundationConfig, err := parseYamlFromBody(data)
+	if err != nil {
+		return []error{redact(err, secrets)}
+	}
+
+	var document interface{}
+	if err := candiedyaml.Unmarshal(data, &document); err != nil {
+		return []error{redact(ParseYamlError{err}, secrets)}
+	}
+
+	errs := unknownKeys("", document, reflect.TypeOf(configYaml{}))
+
+	_, configErrs := buildConfig(getenv, foundationConfig, checkCredentials)
+
+	errs = append(errs, configErrs...)
+	for i, err := range errs {
+		errs[i] = redact(err, secrets)
+	}
+	return errs
+}
+
+// unknownKeys returns an UnknownKeyError for every key in value that is not a key of t.
+// Maps in value are checked against structs in t, and lists against the type of their elements.
+func unknownKeys(location string, value interface{}, t reflect.Type) []error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var errs []error
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := yamlKeys(t)
+
+		for _, key := range sortedKeys(value) {
+			fieldType, ok := fields[key]
+			if !ok {
+				errs = append(errs, UnknownKeyError{join(location, key)})
+				continue
+			}
+
+			errs = append(errs, unknownKeys(join(location, key), mapValue(value, key), fieldType)...)
+		}
+
+	case reflect.Map:
+		for _, key := range sortedKeys(value) {
+			errs = append(errs, unknownKeys(join(location, key), mapValue(value, key), t.Elem())...)
+		}
+
+	case reflect.Slice:
+		if list, ok := value.([]interface{}); ok {
+			for i, element := range list {
+				errs = append(errs, unknownKeys(fmt.Sprintf("%s[%d]", location, i), element, t.Elem())...)
+			}
+		}
+	}
+
+	return errs
+}
+
+// yamlKeys returns the types of the fields of a struct by their key in the config file.
+// Foundations are parsed separately from environments, so their key is added to Environment.
+// Templates are only read to resolve extends, so their key is added to the config.
+func yamlKeys(t reflect.Type) map[string]reflect.Type {
+	keys := map[string]reflect.Type{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(field.Name)
+		}
+
+		keys[name] = field.Type
+	}
+
+	if t == reflect.TypeOf(Environment{}) {
+		keys["foundations"] = reflect.TypeOf([]foundationSettingsYaml{})
+	}
+
+	if t == reflect.TypeOf(configYaml{}) {
+		keys[templatesKey] = reflect.TypeOf([]Environment{})
+	}
+
+	return keys
+}
+
+func sortedKeys(value interface{}) []string {
+	var keys []string
+
+	switch m := value.(type) {
+	case map[interface{}]interface{}:
+		for key := range m {
+			keys = append(keys, fmt.Sprint(key))
+		}
+	case map[string]interface{}:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func mapValue(value interface{}, key string) interface{} {
+	switch m := value.(type) {
+	case map[interface{}]interface{}:
+		for k, v := range m {
+			if fmt.Sprint(k) == key {
+				return v
+			}
+		}
+	case map[string]interface{}:
+		return m[key]
+	}
+
+	return nil
+}
+
+func join(location, key string) string {
+	if location == "" {
+		return key
+	}
+	return location + "." + key
+}
@@ -0,0 +1,163 @@
+// Package configprovider keeps the current configuration and reloads it from the config file.
+package configprovider
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/config"
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// Provider returns the current Config. The config file is read again by Reload, and the new
+// Config replaces the current one only once it has been parsed and passed Validate.
+// A Config is never changed after it has been returned, so a deploy that is in progress
+// keeps using the Config it started with.
+type Provider struct {
+	Path     string
+	Getenv   func(string) string
+	Validate func(config.Config) error
+	Log      I.Logger
+	mutex    sync.RWMutex
+	current  config.Config
+}
+
+// New reads and validates the config file at path and returns a Provider for it.
+func New(path string, getenv func(string) string, validate func(config.Config) error, log I.Logger) (*Provider, error) {
+	p := &Provider{
+		Path:     path,
+		Getenv:   getenv,
+		Validate: validate,
+		Log:      log,
+	}
+
+	cfg, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	p.current = cfg
+
+	return p, nil
+}
+
+// Config returns the current Config.
+func (p *Provider) Config() config.Config {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.current
+}
+
+// Reload reads the config file and replaces the current Config with it.
+// The current Config is kept when the file cannot be read or is not valid.
+func (p *Provider) Reload() error {
+	cfg, err := p.load()
+	if err != nil {
+		p.Log.Errorf("keeping the current config: cannot reload %s: %s", p.Path, err)
+		return ReloadError{p.Path, err}
+	}
+
+	p.mutex.Lock()
+	previous := p.current
+	p.current = cfg
+	p.mutex.Unlock()
+
+	p.Log.Infof("reloaded config from %s with %d environments", p.Path, len(cfg.Environments))
+
+	if changed := startupSettingsChanged(previous, cfg); len(changed) > 0 {
+		p.Log.Errorf("%s changed in %s but are only read at startup: restart Deployadactyl to use them", strings.Join(changed, ", "), p.Path)
+	}
+	return nil
+}
+
+// startupSettingsChanged returns the settings that are only read when Deployadactyl starts
+// and are different in the reloaded config.
+func startupSettingsChanged(previous, reloaded config.Config) []string {
+	var changed []string
+
+	previousSource, reloadedSource := previous.ArtifactSource, reloaded.ArtifactSource
+	previousSource.Credentials, reloadedSource.Credentials = nil, nil
+
+	settings := []struct {
+		name              string
+		previous, current interface{}
+	}{
+		{"PORT", previous.Port, reloaded.Port},
+		{"server", previous.Server, reloaded.Server},
+		{"domain_cache_ttl", previous.DomainCacheTTL, reloaded.DomainCacheTTL},
+		{"session_ttl", previous.SessionTTL, reloaded.SessionTTL},
+		{"artifact_cache", previous.ArtifactCache, reloaded.ArtifactCache},
+		{"artifact_source", previousSource, reloadedSource},
+		{"working_directory", previous.WorkingDirectory, reloaded.WorkingDirectory},
+	}
+
+	for _, setting := range settings {
+		if !reflect.DeepEqual(setting.previous, setting.current) {
+			changed = append(changed, setting.name)
+		}
+	}
+
+	return changed
+}
+
+// ReloadOn reloads the config file every time a signal is received, until stop is closed.
+func (p *Provider) ReloadOn(signals <-chan os.Signal, stop <-chan struct{}) {
+	for {
+		select {
+		case sig := <-signals:
+			p.Log.Infof("received %s: reloading config from %s", sig, p.Path)
+			p.Reload()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Watch checks the config file every interval and reloads it when its modification time or size
+// has changed, until stop is closed.
+func (p *Provider) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, _ := os.Stat(p.Path)
+
+	for {
+		select {
+		case <-ticker.C:
+			info, err := os.Stat(p.Path)
+			if err != nil {
+				p.Log.Errorf("cannot watch config file %s: %s", p.Path, err)
+				continue
+			}
+
+			if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+				continue
+			}
+			last = info
+
+			p.Log.Infof("config file %s has changed", p.Path)
+			p.Reload()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (p *Provider) load() (config.Config, error) {
+	cfg, err := config.Custom(p.Getenv, p.Path)
+	if err != nil {
+		return config.Config{}, err
+	}
+
+	if p.Validate != nil {
+		err = p.Validate(cfg)
+		if err != nil {
+			return config.Config{}, err
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,13 @@
+package configprovider_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestConfigprovider(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Configprovider Suite")
+}
@@ -0,0 +1,170 @@
+package configprovider_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/configprovider"
+	"github.com/compozed/deployadactyl/logger"
+	logging "github.com/op/go-logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+const (
+	oneEnvironment = `---
+environments:
+  - name: preproduction
+    domain: preproduction.example.com
+    foundations:
+    - https://api.foundation-1.example.com
+`
+	twoEnvironments = oneEnvironment + `  - name: production
+    domain: production.example.com
+    foundations:
+    - https://api.foundation-2.example.com
+`
+)
+
+var _ = Describe("Configprovider", func() {
+	var (
+		configPath string
+		logBuffer  *Buffer
+		provider   *Provider
+		validate   func(config.Config) error
+	)
+
+	getenv := func(key string) string {
+		return map[string]string{"CF_USERNAME": "username", "CF_PASSWORD": "password"}[key]
+	}
+
+	writeConfig := func(contents string) {
+		Expect(ioutil.WriteFile(configPath, []byte(contents), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		file, err := ioutil.TempFile("", "deployadactyl-config-")
+		Expect(err).ToNot(HaveOccurred())
+		file.Close()
+		configPath = file.Name()
+
+		logBuffer = NewBuffer()
+		validate = func(config.Config) error { return nil }
+
+		writeConfig(oneEnvironment)
+
+		provider, err = New(configPath, getenv, func(cfg config.Config) error { return validate(cfg) }, logger.DefaultLogger(logBuffer, logging.DEBUG, "configprovider_test"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.Remove(configPath)
+	})
+
+	It("provides the config from the config file", func() {
+		Expect(provider.Config().Environments).To(HaveKey("preproduction"))
+		Expect(provider.Config().Username).To(Equal("username"))
+	})
+
+	It("returns an error when the config file is not valid at startup", func() {
+		writeConfig(oneEnvironment)
+		validate = func(config.Config) error { return errors.New("not valid") }
+
+		_, err := New(configPath, getenv, validate, logger.DefaultLogger(logBuffer, logging.DEBUG, "configprovider_test"))
+
+		Expect(err).To(MatchError("not valid"))
+	})
+
+	Describe("reloading", func() {
+		It("replaces the config with the config in the file", func() {
+			writeConfig(twoEnvironments)
+
+			Expect(provider.Reload()).To(Succeed())
+
+			Expect(provider.Config().Environments).To(HaveKey("production"))
+			Eventually(logBuffer).Should(Say("reloaded config from %s with 2 environments", configPath))
+		})
+
+		It("does not change a config that has already been returned", func() {
+			snapshot := provider.Config()
+			writeConfig(twoEnvironments)
+
+			Expect(provider.Reload()).To(Succeed())
+
+			Expect(snapshot.Environments).ToNot(HaveKey("production"))
+		})
+
+		It("logs the settings that changed but are only read at startup", func() {
+			writeConfig("session_ttl: 1m\nworking_directory:\n  path: /tmp\n" + oneEnvironment[len("---\n"):])
+
+			Expect(provider.Reload()).To(Succeed())
+
+			Expect(provider.Config().SessionTTL).To(Equal(time.Minute))
+			Eventually(logBuffer).Should(Say("session_ttl, working_directory changed in %s but are only read at startup: restart Deployadactyl to use them", configPath))
+		})
+
+		It("does not log startup settings when only the environments change", func() {
+			writeConfig(twoEnvironments)
+
+			Expect(provider.Reload()).To(Succeed())
+
+			Eventually(logBuffer).Should(Say("reloaded config"))
+			Consistently(logBuffer).ShouldNot(Say("only read at startup"))
+		})
+
+		It("keeps the current config when the file cannot be parsed", func() {
+			writeConfig("environments: [")
+
+			err := provider.Reload()
+
+			Expect(err).To(BeAssignableToTypeOf(ReloadError{}))
+			Expect(provider.Config().Environments).To(HaveKey("preproduction"))
+			Eventually(logBuffer).Should(Say("keeping the current config"))
+		})
+
+		It("keeps the current config when it is not valid", func() {
+			writeConfig(twoEnvironments)
+			validate = func(config.Config) error { return errors.New("not valid") }
+
+			Expect(provider.Reload()).To(MatchError(ReloadError{configPath, errors.New("not valid")}))
+
+			Expect(provider.Config().Environments).ToNot(HaveKey("production"))
+		})
+	})
+
+	Describe("reloading on a signal", func() {
+		It("reloads the config every time a signal is received", func() {
+			signals := make(chan os.Signal)
+			stop := make(chan struct{})
+			defer close(stop)
+
+			go provider.ReloadOn(signals, stop)
+
+			writeConfig(twoEnvironments)
+			signals <- syscall.SIGHUP
+
+			Eventually(func() map[string]config.Environment { return provider.Config().Environments }).Should(HaveKey("production"))
+		})
+	})
+
+	Describe("watching the config file", func() {
+		It("reloads the config when the file changes", func() {
+			stop := make(chan struct{})
+			defer close(stop)
+
+			go provider.Watch(10*time.Millisecond, stop)
+
+			time.Sleep(50 * time.Millisecond)
+			writeConfig(twoEnvironments)
+
+			Eventually(func() map[string]config.Environment { return provider.Config().Environments }).Should(HaveKey("production"))
+			Eventually(logBuffer).Should(Say("config file %s has changed", configPath))
+		})
+	})
+})
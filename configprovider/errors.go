@@ -0,0 +1,12 @@
+package configprovider
+
+import "fmt"
+
+type ReloadError struct {
+	Path string
+	Err  error
+}
+
+func (e ReloadError) Error() string {
+	return fmt.Sprintf("cannot reload config from %s: %s: the current config is still in use", e.Path, e.Err)
+}
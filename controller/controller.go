@@ -12,8 +12,10 @@ import (
 )
 
 // Controller is used to determine the type of request and process it accordingly.
+// The environments endpoints read the current Config from the Config provider.
 type Controller struct {
 	Deployer I.Deployer
+	Config   I.ConfigProvider
 	Log      I.Logger
 }
 
@@ -35,7 +37,10 @@ func (c *Controller) Deploy(g *gin.Context) {
 		response,
 	)
 	if err != nil {
-		g.Writer.WriteHeader(http.StatusInternalServerError)
+		if statusCode < http.StatusBadRequest {
+			statusCode = http.StatusInternalServerError
+		}
+		g.Writer.WriteHeader(statusCode)
 		fmt.Fprintf(response, "cannot deploy application: %s\n", err)
 		return
 	}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/compozed/deployadactyl/config"
 	. "github.com/compozed/deployadactyl/controller"
 	"github.com/compozed/deployadactyl/logger"
 	"github.com/compozed/deployadactyl/mocks"
@@ -25,6 +26,7 @@ var _ = Describe("Controller", func() {
 
 	var (
 		deployer   *mocks.Deployer
+		provider   *mocks.ConfigProvider
 		controller *Controller
 		router     *gin.Engine
 		resp       *httptest.ResponseRecorder
@@ -39,9 +41,11 @@ var _ = Describe("Controller", func() {
 
 	BeforeEach(func() {
 		deployer = &mocks.Deployer{}
+		provider = &mocks.ConfigProvider{}
 
 		controller = &Controller{
 			Deployer: deployer,
+			Config:   provider,
 			Log:      logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "api_test"),
 		}
 
@@ -55,6 +59,8 @@ var _ = Describe("Controller", func() {
 		space = "space-" + randomizer.StringRunes(10)
 
 		router.POST("/v1/apps/:environment/:org/:space/:appName", controller.Deploy)
+		router.GET("/v1/environments", controller.Environments)
+		router.GET("/v1/environments/:name", controller.Environment)
 	})
 
 	Describe("Deploy handler", func() {
@@ -96,6 +102,21 @@ var _ = Describe("Controller", func() {
 				Expect(resp.Code).To(Equal(http.StatusInternalServerError))
 				Expect(resp.Body).To(ContainSubstring("bork"))
 			})
+
+			It("gives the status code of the deployer", func() {
+				foundationURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				req, err := http.NewRequest("POST", foundationURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+
+				deployer.DeployCall.Returns.Error = errors.New("not allowed")
+				deployer.DeployCall.Returns.StatusCode = http.StatusForbidden
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusForbidden))
+				Expect(resp.Body).To(ContainSubstring("not allowed"))
+			})
 		})
 
 		Context("when parameters are added to the url", func() {
@@ -115,4 +136,117 @@ var _ = Describe("Controller", func() {
 			})
 		})
 	})
+
+	Describe("Environments handlers", func() {
+		BeforeEach(func() {
+			provider.ConfigCall.Returns.Config = config.Config{
+				Username: "username",
+				Password: "password",
+				Environments: map[string]config.Environment{
+					"production": {
+						Name:         "production",
+						Domain:       "production.example.com",
+						Foundations:  []string{"https://api.foundation-1.example.com", "https://api.foundation-2.example.com"},
+						Authenticate: true,
+						Instances:    4,
+						Credentials:  config.Credentials{UsernameEnv: "PRODUCTION_USERNAME", Username: "production-username", Password: "production-password"},
+						FoundationCredentials: map[string]config.Credentials{
+							"https://api.foundation-1.example.com": {Username: "foundation-username", Password: "foundation-password"},
+						},
+						Handlers: config.Handlers{
+							HealthCheck: config.HealthCheckHandler{Enabled: true},
+							RouteMapper: config.Handler{Enabled: true},
+						},
+						FoundationDetails: map[string]config.Foundation{
+							"https://api.foundation-1.example.com": {
+								URL:    "https://api.foundation-1.example.com",
+								Name:   "east",
+								Region: "us-east",
+								Labels: map[string]string{"tier": "gold"},
+							},
+						},
+					},
+					"preproduction": {
+						Name:        "preproduction",
+						Domain:      "preproduction.example.com",
+						Foundations: []string{"https://api.foundation-3.example.com"},
+						SkipSSL:     true,
+					},
+				},
+			}
+		})
+
+		It("returns every environment sorted by name", func() {
+			req, err := http.NewRequest("GET", "/v1/environments", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body.String()).To(MatchJSON(`[
+				{
+					"name": "preproduction",
+					"domain": "preproduction.example.com",
+					"foundations": [{"url": "https://api.foundation-3.example.com", "name": "https://api.foundation-3.example.com"}],
+					"authenticate": false,
+					"skip_ssl": true,
+					"instances": 0,
+					"handlers": []
+				},
+				{
+					"name": "production",
+					"domain": "production.example.com",
+					"foundations": [
+						{"url": "https://api.foundation-1.example.com", "name": "east", "region": "us-east", "labels": {"tier": "gold"}},
+						{"url": "https://api.foundation-2.example.com", "name": "https://api.foundation-2.example.com"}
+					],
+					"authenticate": true,
+					"skip_ssl": false,
+					"instances": 4,
+					"handlers": ["health_check", "route_mapper"]
+				}
+			]`))
+		})
+
+		It("leaves out the credentials", func() {
+			req, err := http.NewRequest("GET", "/v1/environments", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Body.String()).ToNot(ContainSubstring("username"))
+			Expect(resp.Body.String()).ToNot(ContainSubstring("password"))
+			Expect(resp.Body.String()).ToNot(ContainSubstring("PRODUCTION_USERNAME"))
+		})
+
+		It("returns one environment by name", func() {
+			req, err := http.NewRequest("GET", "/v1/environments/Preproduction", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body.String()).To(MatchJSON(`{
+				"name": "preproduction",
+				"domain": "preproduction.example.com",
+				"foundations": [{"url": "https://api.foundation-3.example.com", "name": "https://api.foundation-3.example.com"}],
+				"authenticate": false,
+				"skip_ssl": true,
+				"instances": 0,
+				"handlers": []
+			}`))
+		})
+
+		Context("when the environment does not exist", func() {
+			It("returns http.StatusNotFound", func() {
+				req, err := http.NewRequest("GET", "/v1/environments/staging", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+				Expect(resp.Body.String()).To(MatchJSON(`{"error": "environment not found: staging"}`))
+			})
+		})
+	})
 })
@@ -22,14 +22,25 @@ type BlueGreen struct {
 
 // Push will login to all the Cloud Foundry instances provided in the Config and then push the application to all the instances concurrently.
 // If the application fails to start in any of the instances it handles rolling back the application in every instance, unless it is the first deploy.
+// Foundations that are configured with their own settings are pushed to with their own domain, apps domain, SSL setting and CA bundle.
 func (bg BlueGreen) Push(environment config.Environment, appPath string, deploymentInfo S.DeploymentInfo, response io.ReadWriter) error {
 	bg.actors = make([]actor, len(environment.Foundations))
 	bg.buffers = make([]*bytes.Buffer, len(environment.Foundations))
+	names := make([]string, len(environment.Foundations))
 
 	for i, foundationURL := range environment.Foundations {
 		bg.buffers[i] = &bytes.Buffer{}
 
-		pusher, err := bg.PusherCreator.CreatePusher(deploymentInfo, bg.buffers[i])
+		foundationDeploymentInfo := deploymentInfo
+		if foundation, ok := environment.FoundationDetails[foundationURL]; ok {
+			names[i] = foundation.Name
+			foundationDeploymentInfo.Domain = foundation.Domain
+			foundationDeploymentInfo.AppsDomain = foundation.AppsDomain
+			foundationDeploymentInfo.SkipSSL = foundation.SkipSSL
+			foundationDeploymentInfo.CACert = foundation.CACert
+		}
+
+		pusher, err := bg.PusherCreator.CreatePusher(foundationDeploymentInfo, environment, bg.buffers[i])
 		if err != nil {
 			return err
 		}
@@ -40,8 +51,11 @@ func (bg BlueGreen) Push(environment config.Environment, appPath string, deploym
 	}
 
 	defer func() {
-		for _, buffer := range bg.buffers {
+		for i, buffer := range bg.buffers {
 			fmt.Fprintf(response, "\n%s Cloud Foundry Output %s\n", strings.Repeat("-", 19), strings.Repeat("-", 19))
+			if names[i] != "" {
+				fmt.Fprintf(response, "foundation: %s\n", names[i])
+			}
 
 			buffer.WriteTo(response)
 		}
@@ -162,6 +162,38 @@ var _ = Describe("Bluegreen", func() {
 			Eventually(response).Should(Say(pushOutput))
 		})
 
+		It("pushes to each foundation with its own settings", func() {
+			deploymentInfo.Domain = "example.com"
+			deploymentInfo.SkipSSL = true
+
+			environment.FoundationDetails = map[string]config.Foundation{
+				environment.Foundations[1]: {
+					URL:        environment.Foundations[1],
+					Name:       "east",
+					Domain:     "east.example.com",
+					AppsDomain: "apps.east.example.com",
+					SkipSSL:    false,
+					CACert:     "/etc/ssl/east.pem",
+				},
+			}
+
+			Expect(blueGreen.Push(environment, appPath, deploymentInfo, response)).To(Succeed())
+
+			received := pusherFactory.CreatePusherCall.Received.DeploymentInfos
+			Expect(received).To(HaveLen(2))
+
+			Expect(received[0]).To(Equal(deploymentInfo))
+			Expect(pusherFactory.CreatePusherCall.Received.Environments).To(Equal([]config.Environment{environment, environment}))
+
+			Expect(received[1].Domain).To(Equal("east.example.com"))
+			Expect(received[1].AppsDomain).To(Equal("apps.east.example.com"))
+			Expect(received[1].SkipSSL).To(BeFalse())
+			Expect(received[1].CACert).To(Equal("/etc/ssl/east.pem"))
+			Expect(received[1].AppName).To(Equal(appName))
+
+			Eventually(response).Should(Say("foundation: east"))
+		})
+
 		Context("when deleting the venerable fails", func() {
 			It("logs an error", func() {
 				var (
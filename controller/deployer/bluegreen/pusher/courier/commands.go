@@ -0,0 +1,140 @@
+package courier
+
+import (
+	"fmt"
+
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher/courier/executor"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// NewCommandBuilder returns the CommandBuilder for the major version of the installed Cloud Foundry CLI.
+//
+// Returns an error if the version of the CLI is not supported.
+func NewCommandBuilder(version executor.CLIVersion) (I.CommandBuilder, error) {
+	switch version.Major {
+	case 6:
+		return V6Commands{}, nil
+	case 7, 8:
+		return V7Commands{}, nil
+	default:
+		return nil, UnsupportedCLIVersionError{version.Raw}
+	}
+}
+
+// V6Commands builds commands for version 6 of the Cloud Foundry CLI.
+// The hostname of an application is routed when it is pushed.
+type V6Commands struct{}
+
+// Push returns the arguments of the push command.
+func (V6Commands) Push(appName, hostname string, instances uint16) []string {
+	return []string{"push", appName, "-i", fmt.Sprint(instances), "-n", hostname}
+}
+
+// MapRoute returns the arguments of the map-route command.
+func (V6Commands) MapRoute(appName, domain, hostname string) []string {
+	return []string{"map-route", appName, domain, "-n", hostname}
+}
+
+// UnmapRoute returns the arguments of the unmap-route command.
+func (V6Commands) UnmapRoute(appName, domain, hostname string) []string {
+	return []string{"unmap-route", appName, domain, "-n", hostname}
+}
+
+// MapsRouteAfterPush is false because the push command routes the hostname.
+func (V6Commands) MapsRouteAfterPush() bool {
+	return false
+}
+
+// SupportsRollingDeployments is false because version 6 of the CLI cannot push with a strategy.
+func (V6Commands) SupportsRollingDeployments() bool {
+	return false
+}
+
+// ParseDomains reads the "name status type details" table of the domains command.
+// The status of a private domain is owned, the type of a TCP domain is tcp and the
+// details of an internal domain are internal.
+func (V6Commands) ParseDomains(output []byte) ([]S.Domain, error) {
+	rows, err := readTable(output, "name", "status")
+	if err != nil {
+		return nil, err
+	}
+
+	domains := []S.Domain{}
+	for _, row := range rows {
+		domain := S.Domain{Name: row["name"], Type: S.SharedDomain, Protocol: "http"}
+
+		if row["status"] == "owned" || row["status"] == "private" {
+			domain.Type = S.PrivateDomain
+		}
+		if row["type"] == "tcp" {
+			domain.Protocol = "tcp"
+		}
+		if row["details"] == "internal" {
+			domain.Internal = true
+		}
+
+		domains = append(domains, domain)
+	}
+
+	return domains, nil
+}
+
+// V7Commands builds commands for versions 7 and 8 of the Cloud Foundry CLI.
+// The push command no longer accepts a hostname, so the application is pushed
+// without a route and the hostname is mapped to the default domain afterwards.
+type V7Commands struct{}
+
+// Push returns the arguments of the push command.
+func (V7Commands) Push(appName, hostname string, instances uint16) []string {
+	return []string{"push", appName, "-i", fmt.Sprint(instances), "--no-route"}
+}
+
+// MapRoute returns the arguments of the map-route command.
+func (V7Commands) MapRoute(appName, domain, hostname string) []string {
+	return []string{"map-route", appName, domain, "--hostname", hostname}
+}
+
+// UnmapRoute returns the arguments of the unmap-route command.
+func (V7Commands) UnmapRoute(appName, domain, hostname string) []string {
+	return []string{"unmap-route", appName, domain, "--hostname", hostname}
+}
+
+// MapsRouteAfterPush is true because the push command cannot route a hostname.
+func (V7Commands) MapsRouteAfterPush() bool {
+	return true
+}
+
+// SupportsRollingDeployments is true because the push command accepts the rolling strategy.
+func (V7Commands) SupportsRollingDeployments() bool {
+	return true
+}
+
+// ParseDomains reads the "name availability internal protocols" table of the domains command.
+// The availability of a private domain is private, the internal column of an internal domain
+// is true and protocols is http or tcp.
+func (V7Commands) ParseDomains(output []byte) ([]S.Domain, error) {
+	rows, err := readTable(output, "name", "availability", "internal", "protocols")
+	if err != nil {
+		return nil, err
+	}
+
+	domains := []S.Domain{}
+	for _, row := range rows {
+		domain := S.Domain{Name: row["name"], Type: S.SharedDomain, Protocol: "http"}
+
+		if row["availability"] == "private" {
+			domain.Type = S.PrivateDomain
+		}
+		if row["internal"] == "true" {
+			domain.Internal = true
+		}
+		if row["protocols"] != "" {
+			domain.Protocol = row["protocols"]
+		}
+
+		domains = append(domains, domain)
+	}
+
+	return domains, nil
+}
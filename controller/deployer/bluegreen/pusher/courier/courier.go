@@ -3,68 +3,117 @@ package courier
 
 import (
 	"fmt"
+	"io"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
 )
 
+var deployedRevisionPattern = regexp.MustCompile(`(?m)^\s*(\d+)\(deployed\)`)
+
 // Courier has an Executor to execute Cloud Foundry commands.
+// Commands are built by the CommandBuilder for the installed version of the CLI,
+// which defaults to version 6.
+//
+// Idempotent commands are retried according to the Retry policy. Each retry is logged
+// and written to Output, which is the output of the foundation the Courier is used for.
 type Courier struct {
 	Executor I.Executor
+	Commands I.CommandBuilder
+	Retry    RetryPolicy
+	Log      I.Logger
+	Output   io.Writer
 }
 
 // Login runs the Cloud Foundry login command.
+// If the Executor can resume a session that is already logged in, the org and space
+// are targeted instead. Logging in is the fallback when targeting fails.
 //
 // Returns the combined standard output and standard error.
 func (c Courier) Login(foundationURL, username, password, org, space string, skipSSL bool) ([]byte, error) {
+	sessions, pooled := c.Executor.(I.SessionExecutor)
+
+	if pooled && sessions.Resume(foundationURL, username, password, skipSSL) {
+		output, err := c.executeWithRetry("target", "-o", org, "-s", space)
+		if err == nil {
+			return output, nil
+		}
+
+		if c.Log != nil {
+			c.Log.Infof("could not resume cf session for %s: logging in", foundationURL)
+		}
+	}
+
 	var s string
 	if skipSSL {
 		s = "--skip-ssl-validation"
 	}
 
-	return c.Executor.Execute("login", "-a", foundationURL, "-u", username, "-p", password, "-o", org, "-s", space, s)
+	output, err := c.executeWithRetry("login", "-a", foundationURL, "-u", username, "-p", password, "-o", org, "-s", space, s)
+	if pooled {
+		sessions.LoggedIn(err == nil)
+	}
+
+	return output, err
 }
 
 // Delete runs the Cloud Foundry delete command.
 //
 // Returns the combined standard output and standard error.
 func (c Courier) Delete(appName string) ([]byte, error) {
-	return c.Executor.Execute("delete", appName, "-f")
+	return c.executeWithRetry("delete", appName, "-f")
 }
 
 // Push runs the Cloud Foundry push command.
+// If the CLI cannot route the hostname when pushing, the hostname is mapped to
+// the default domain of the foundation after the push.
 //
 // Returns the combined standard output and standard error.
 func (c Courier) Push(appName, appLocation, hostname string, instances uint16) ([]byte, error) {
-	return c.Executor.ExecuteInDirectory(appLocation, "push", appName, "-i", fmt.Sprint(instances), "-n", hostname)
+	output, err := c.Executor.ExecuteInDirectory(appLocation, c.commands().Push(appName, hostname, instances)...)
+	if err != nil || !c.commands().MapsRouteAfterPush() {
+		return output, err
+	}
+
+	domain, err := c.defaultDomain()
+	if err != nil {
+		return output, err
+	}
+
+	mapRouteOutput, err := c.MapRoute(appName, domain, hostname)
+	return append(output, mapRouteOutput...), err
 }
 
 // Rename runs the Cloud Foundry rename command.
 //
 // Returns the combined standard output and standard error.
 func (c Courier) Rename(appName, newAppName string) ([]byte, error) {
-	return c.Executor.Execute("rename", appName, newAppName)
+	return c.executeWithRetry("rename", appName, newAppName)
 }
 
 // MapRoute runs the Cloud Foundry map-route command.
 //
 // Returns the combined standard output and standard error.
 func (c Courier) MapRoute(appName, domain, hostname string) ([]byte, error) {
-	return c.Executor.Execute("map-route", appName, domain, "-n", hostname)
+	return c.executeWithRetry(c.commands().MapRoute(appName, domain, hostname)...)
 }
 
 // UnmapRoute runs the Cloud Foundry unmap-route command.
 //
 // Returns the combined standard output and standard error.
 func (c Courier) UnmapRoute(appName, domain, hostname string) ([]byte, error) {
-	return c.Executor.Execute("unmap-route", appName, domain, "-n", hostname)
+	return c.executeWithRetry(c.commands().UnmapRoute(appName, domain, hostname)...)
 }
 
 // Logs runs the Cloud Foundry logs command.
 //
 // Returns the combined standard output and standard error.
 func (c Courier) Logs(appName string) ([]byte, error) {
-	logs, err := c.Executor.Execute("logs", appName, "--recent")
+	logs, err := c.executeWithRetry("logs", appName, "--recent")
 	return logs, err
 }
 
@@ -85,25 +134,216 @@ func (c Courier) Uups(appName string, body string) ([]byte, error) {
 //
 // Returns true if the application exists.
 func (c Courier) Exists(appName string) bool {
-	_, err := c.Executor.Execute("app", appName)
+	_, err := c.executeWithRetry("app", appName)
 	return err == nil
 }
 
-// Domains returns a list of domain in a foundation.
+// Domains returns the domains available to the targeted org in a foundation.
+//
+// Returns an error if the output of the Cloud Foundry domains command cannot be parsed.
+func (c Courier) Domains() ([]S.Domain, error) {
+	output, err := c.executeWithRetry("domains")
+	if err != nil {
+		return nil, DomainsError{output}
+	}
+
+	return c.commands().ParseDomains(output)
+}
+
+// PushRolling runs the Cloud Foundry push command with the rolling strategy, which replaces the
+// instances of an existing application without changing its GUID, bindings or routes.
 //
 // Returns the combined standard output and standard error.
-func (c Courier) Domains() ([]string, error) {
-	output, err := c.Executor.Execute("domains")
+func (c Courier) PushRolling(appName, appLocation string, instances uint16) ([]byte, error) {
+	if !c.commands().SupportsRollingDeployments() {
+		return nil, RollingDeploymentsNotSupportedError{}
+	}
 
-	domains := strings.Split(string(output), "\n")[2:]
-	for i, domain := range domains {
-		domains[i] = strings.Split(domain, " ")[0]
+	return c.Executor.ExecuteInDirectory(appLocation, "push", appName, "-i", fmt.Sprint(instances), "--strategy", "rolling")
+}
+
+// CancelDeployment runs the Cloud Foundry cancel-deployment command, which returns an application
+// to its previous droplet while a rolling deployment is in progress.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) CancelDeployment(appName string) ([]byte, error) {
+	return c.executeWithRetry("cancel-deployment", appName)
+}
+
+// DeployedRevision runs the Cloud Foundry revisions command.
+//
+// Returns the number of the revision that is deployed.
+func (c Courier) DeployedRevision(appName string) (int, error) {
+	output, err := c.executeWithRetry("revisions", appName)
+	if err != nil {
+		return 0, RevisionsError{appName, output}
+	}
+
+	match := deployedRevisionPattern.FindSubmatch(output)
+	if match == nil {
+		return 0, RevisionsError{appName, output}
 	}
 
-	return domains, err
+	return strconv.Atoi(string(match[1]))
+}
+
+// Rollback runs the Cloud Foundry rollback command to deploy an earlier revision of an application.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) Rollback(appName string, revision int) ([]byte, error) {
+	return c.executeWithRetry("rollback", appName, "--version", strconv.Itoa(revision), "-f")
 }
 
 // CleanUp removes the temporary directory created by the Executor.
 func (c Courier) CleanUp() error {
 	return c.Executor.CleanUp()
 }
+
+func (c Courier) commands() I.CommandBuilder {
+	if c.Commands == nil {
+		return V6Commands{}
+	}
+	return c.Commands
+}
+
+// defaultDomain returns the first shared http domain of the foundation that is not internal.
+func (c Courier) defaultDomain() (string, error) {
+	domains, err := c.Domains()
+	if err != nil {
+		return "", err
+	}
+
+	for _, domain := range domains {
+		if domain.Type == S.SharedDomain && domain.Protocol == "http" && !domain.Internal {
+			return domain.Name, nil
+		}
+	}
+
+	return "", NoDefaultDomainError{}
+}
+
+// executeWithRetry runs a command that is safe to run more than once. The command is
+// run again after a backoff while it fails with a transient error and attempts remain.
+// Only the name of the command is logged so credentials are never written to the output.
+func (c Courier) executeWithRetry(args ...string) ([]byte, error) {
+	backoff := c.Retry.Backoff
+
+	for attempt := 1; ; attempt++ {
+		output, err := c.Executor.Execute(args...)
+		if err == nil || !c.Retry.isTransient(output, err) {
+			return output, err
+		}
+
+		if attempt >= c.Retry.Attempts {
+			if c.Log != nil && attempt > 1 {
+				c.Log.Errorf("cf %s failed with a transient error after %d attempts: %s", args[0], attempt, output)
+			}
+			return output, err
+		}
+
+		message := fmt.Sprintf("cf %s failed with a transient error: retrying in %s (attempt %d of %d)", args[0], backoff, attempt+1, c.Retry.Attempts)
+		if c.Log != nil {
+			c.Log.Infof("%s: %s", message, output)
+		}
+		if c.Output != nil {
+			fmt.Fprintln(c.Output, message)
+		}
+
+		time.Sleep(backoff)
+		backoff = c.Retry.nextBackoff(backoff)
+	}
+}
+
+// readTable reads the table printed by a Cloud Foundry command, such as domains, into a map of
+// the cells of each row by column name. The header is the first line that starts with the first
+// column. The CLI pads every cell to the width of its column and leaves empty cells blank, so
+// each value belongs to the column it starts in.
+//
+// Returns an error if the output does not have a header with every column.
+func readTable(output []byte, columns ...string) ([]map[string]string, error) {
+	lines := strings.Split(string(output), "\n")
+
+	header := -1
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == columns[0] {
+			header = i
+			break
+		}
+	}
+	if header == -1 {
+		return nil, DomainsOutputError{output}
+	}
+
+	names, starts := fieldsWithOffsets(lines[header])
+	for _, column := range columns {
+		if !contains(names, column) {
+			return nil, DomainsOutputError{output}
+		}
+	}
+
+	rows := []map[string]string{}
+	for _, line := range lines[header+1:] {
+		values, offsets := fieldsWithOffsets(line)
+		if len(values) == 0 {
+			continue
+		}
+
+		row := map[string]string{names[0]: values[0]}
+		for i, value := range values[1:] {
+			column := 0
+			for j, start := range starts {
+				if start <= offsets[i+1] {
+					column = j
+				}
+			}
+			if column == 0 {
+				column = 1
+			}
+
+			name := names[column]
+			if row[name] != "" {
+				value = row[name] + " " + value
+			}
+			row[name] = value
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// fieldsWithOffsets returns the fields of a line separated by whitespace and the offset each one starts at.
+func fieldsWithOffsets(line string) ([]string, []int) {
+	var (
+		fields  []string
+		offsets []int
+		start   = -1
+	)
+
+	for i, r := range line + " " {
+		if r == ' ' || r == '\t' || r == '\r' {
+			if start != -1 {
+				fields = append(fields, line[start:i])
+				offsets = append(offsets, start)
+				start = -1
+			}
+			continue
+		}
+		if start == -1 {
+			start = i
+		}
+	}
+
+	return fields, offsets
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
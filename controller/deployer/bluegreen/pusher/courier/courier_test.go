@@ -1,14 +1,23 @@
 package courier_test
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"math/rand"
+	"regexp"
+	"time"
 
 	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher/courier"
+	cfexecutor "github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher/courier/executor"
+	"github.com/compozed/deployadactyl/logger"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
 )
 
 var _ = Describe("Courier", func() {
@@ -74,6 +83,58 @@ var _ = Describe("Courier", func() {
 		})
 	})
 
+	Describe("logging in with a session executor", func() {
+		var (
+			sessionExecutor *mocks.SessionExecutor
+			foundationURL   string
+		)
+
+		BeforeEach(func() {
+			foundationURL = "foundationURL-" + randomizer.StringRunes(10)
+			sessionExecutor = &mocks.SessionExecutor{}
+			courier.Executor = sessionExecutor
+		})
+
+		Context("when a session is resumed", func() {
+			It("targets the org and space instead of logging in", func() {
+				sessionExecutor.ResumeCall.Returns.Bool = true
+				sessionExecutor.ExecuteCall.Returns.Output = []byte(output)
+
+				out, err := courier.Login(foundationURL, "user", "password", "org", "space", true)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(sessionExecutor.ResumeCall.Received.FoundationURL).To(Equal(foundationURL))
+				Expect(sessionExecutor.ResumeCall.Received.SkipSSL).To(BeTrue())
+				Expect(sessionExecutor.ExecuteCall.Received.Args).To(Equal([]string{"target", "-o", "org", "-s", "space"}))
+				Expect(sessionExecutor.LoggedInCall.TimesCalled).To(Equal(0))
+				Expect(string(out)).To(Equal(output))
+			})
+
+			It("logs in when targeting fails", func() {
+				sessionExecutor.ResumeCall.Returns.Bool = true
+				sessionExecutor.ExecuteCall.Returns.Error = errors.New("token expired")
+
+				_, err := courier.Login(foundationURL, "user", "password", "org", "space", false)
+				Expect(err).To(HaveOccurred())
+
+				Expect(sessionExecutor.ExecuteCall.TimesCalled).To(Equal(2))
+				Expect(sessionExecutor.ExecuteCall.Received.Args[0]).To(Equal("login"))
+				Expect(sessionExecutor.LoggedInCall.Received.Success).To(BeFalse())
+			})
+		})
+
+		Context("when there is no session to resume", func() {
+			It("logs in and records the login", func() {
+				_, err := courier.Login(foundationURL, "user", "password", "org", "space", false)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(sessionExecutor.ExecuteCall.Received.Args[0]).To(Equal("login"))
+				Expect(sessionExecutor.LoggedInCall.TimesCalled).To(Equal(1))
+				Expect(sessionExecutor.LoggedInCall.Received.Success).To(BeTrue())
+			})
+		})
+	})
+
 	Describe("deleting an app", func() {
 		It("should get a valid Cloud Foundry delete command", func() {
 			expectedArgs := []string{"delete", appName, "-f"}
@@ -106,6 +167,52 @@ var _ = Describe("Courier", func() {
 			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal(expectedArgs))
 			Expect(string(out)).To(Equal(output))
 		})
+
+		Context("when the cf cli is version 7 or later", func() {
+			var appLocation string
+
+			BeforeEach(func() {
+				appLocation = "appLocation-" + randomizer.StringRunes(10)
+				courier.Commands = V7Commands{}
+			})
+
+			It("pushes without a route and maps the hostname to the default domain", func() {
+				executor.ExecuteInDirectoryCall.Returns.Output = []byte(output)
+				executor.ExecuteCall.Returns.Output = []byte(`Getting domains as user...
+
+name                 availability   internal   protocols
+apps.internal        shared         true       http
+tcp.example.com      shared                    tcp
+private.example.com  private                   http
+apps.example.com     shared                    http
+`)
+
+				out, err := courier.Push(appName, appLocation, hostname, 2)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal([]string{"push", appName, "-i", "2", "--no-route"}))
+				Expect(executor.ExecuteCall.TimesCalled).To(Equal(2))
+				Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"map-route", appName, "apps.example.com", "--hostname", hostname}))
+				Expect(string(out)).To(HavePrefix(output))
+			})
+
+			It("returns an error when there is no default domain", func() {
+				executor.ExecuteInDirectoryCall.Returns.Output = []byte(output)
+				executor.ExecuteCall.Returns.Output = []byte("name   availability   internal   protocols\n")
+
+				_, err := courier.Push(appName, appLocation, hostname, 2)
+				Expect(err).To(MatchError(NoDefaultDomainError{}))
+			})
+
+			It("does not map a route when the push fails", func() {
+				executor.ExecuteInDirectoryCall.Returns.Error = errors.New("push error")
+
+				_, err := courier.Push(appName, appLocation, hostname, 2)
+				Expect(err).To(MatchError("push error"))
+
+				Expect(executor.ExecuteCall.TimesCalled).To(Equal(0))
+			})
+		})
 	})
 
 	Describe("renaming an app", func() {
@@ -142,6 +249,15 @@ var _ = Describe("Courier", func() {
 			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
 			Expect(string(out)).To(Equal(output))
 		})
+
+		It("uses the hostname flag when the cf cli is version 7 or later", func() {
+			courier.Commands = V7Commands{}
+
+			_, err := courier.MapRoute(appName, "domain", hostname)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"map-route", appName, "domain", "--hostname", hostname}))
+		})
 	})
 
 	Describe("unmapping a route", func() {
@@ -239,9 +355,259 @@ var _ = Describe("Courier", func() {
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
-			Expect(domains[0]).To(Equal("example0.com"))
-			Expect(domains[1]).To(Equal("example1.com"))
-			Expect(domains[2]).To(Equal("example2.com"))
+			Expect(domains[0].Name).To(Equal("example0.com"))
+			Expect(domains[1].Name).To(Equal("example1.com"))
+			Expect(domains[2].Name).To(Equal("example2.com"))
+		})
+
+		It("reads the type of each domain and whether it is internal", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`Getting domains in org my-org as admin...
+name                  status   type   details
+apps.example.com      shared
+tcp.example.com       shared   tcp
+apps.internal         shared          internal
+private.example.com   owned
+tcp.internal          owned    tcp    internal
+`)
+
+			domains, err := courier.Domains()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(domains).To(Equal([]S.Domain{
+				{Name: "apps.example.com", Type: S.SharedDomain, Protocol: "http"},
+				{Name: "tcp.example.com", Type: S.SharedDomain, Protocol: "tcp"},
+				{Name: "apps.internal", Type: S.SharedDomain, Protocol: "http", Internal: true},
+				{Name: "private.example.com", Type: S.PrivateDomain, Protocol: "http"},
+				{Name: "tcp.internal", Type: S.PrivateDomain, Protocol: "tcp", Internal: true},
+			}))
+		})
+
+		Context("when the cf cli is version 7 or later", func() {
+			BeforeEach(func() {
+				courier.Commands = V7Commands{}
+			})
+
+			It("reads the availability, internal and protocols columns", func() {
+				executor.ExecuteCall.Returns.Output = []byte(`Getting domains in org my-org as admin...
+
+name                         availability   internal   protocols
+apps.example.com             shared                    http
+apps.internal                shared         true       http
+tcp.example.com              shared                    tcp
+private.example.com          private                   http
+true.example.com             private                   http
+`)
+
+				domains, err := courier.Domains()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(domains).To(Equal([]S.Domain{
+					{Name: "apps.example.com", Type: S.SharedDomain, Protocol: "http"},
+					{Name: "apps.internal", Type: S.SharedDomain, Protocol: "http", Internal: true},
+					{Name: "tcp.example.com", Type: S.SharedDomain, Protocol: "tcp"},
+					{Name: "private.example.com", Type: S.PrivateDomain, Protocol: "http"},
+					{Name: "true.example.com", Type: S.PrivateDomain, Protocol: "http"},
+				}))
+			})
+
+			It("returns an error when the output has the columns of version 6", func() {
+				output := "name                  status   type   details\napps.example.com      shared\n"
+				executor.ExecuteCall.Returns.Output = []byte(output)
+
+				_, err := courier.Domains()
+				Expect(err).To(MatchError(DomainsOutputError{[]byte(output)}))
+			})
+		})
+
+		Context("when the output does not contain a domains table", func() {
+			It("returns an error", func() {
+				executor.ExecuteCall.Returns.Output = []byte("FAILED")
+
+				_, err := courier.Domains()
+				Expect(err).To(MatchError(DomainsOutputError{[]byte("FAILED")}))
+			})
+		})
+
+		Context("when the domains command fails", func() {
+			It("returns an error", func() {
+				executor.ExecuteCall.Returns.Output = []byte(output)
+				executor.ExecuteCall.Returns.Error = errors.New("domains error")
+
+				_, err := courier.Domains()
+				Expect(err).To(MatchError(DomainsError{[]byte(output)}))
+			})
+		})
+	})
+
+	Describe("rolling deployments", func() {
+		It("pushes with the rolling strategy", func() {
+			courier.Commands = V7Commands{}
+			executor.ExecuteInDirectoryCall.Returns.Output = []byte(output)
+
+			out, err := courier.PushRolling(appName, "appLocation", 3)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteInDirectoryCall.Received.AppLocation).To(Equal("appLocation"))
+			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal([]string{"push", appName, "-i", "3", "--strategy", "rolling"}))
+			Expect(string(out)).To(Equal(output))
+		})
+
+		It("returns an error when the cf cli is version 6", func() {
+			_, err := courier.PushRolling(appName, "appLocation", 3)
+
+			Expect(err).To(MatchError(RollingDeploymentsNotSupportedError{}))
+		})
+
+		It("cancels a deployment", func() {
+			_, err := courier.CancelDeployment(appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"cancel-deployment", appName}))
+		})
+
+		It("rolls back to a revision", func() {
+			_, err := courier.Rollback(appName, 3)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"rollback", appName, "--version", "3", "-f"}))
+		})
+
+		It("reads the deployed revision", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`Getting revisions for app myapp in org org / space space as admin...
+
+revision      description             deployable   revision guid                          created at
+4             New droplet deployed.   true         0f2b2fb4-5d2c-4a1b-8d0e-4f3c2d1b0a9e   Tue 06 Oct 12:00:00 UTC 2020
+3(deployed)   New droplet deployed.   true         1c3d5e7f-9a1b-4c3d-8e5f-7a9b1c3d5e7f   Mon 05 Oct 12:00:00 UTC 2020
+1             Initial revision.       true         2d4f6a8b-0c2e-4f6a-9b0d-2e4f6a8b0c2e   Sun 04 Oct 12:00:00 UTC 2020
+`)
+
+			revision, err := courier.DeployedRevision(appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"revisions", appName}))
+			Expect(revision).To(Equal(3))
+		})
+
+		It("returns an error when no revision is deployed", func() {
+			executor.ExecuteCall.Returns.Output = []byte("FAILED")
+
+			_, err := courier.DeployedRevision(appName)
+
+			Expect(err).To(MatchError(RevisionsError{appName, []byte("FAILED")}))
+		})
+	})
+
+	Describe("choosing a command builder", func() {
+		It("builds v6 commands for version 6 of the cf cli", func() {
+			commands, err := NewCommandBuilder(cfexecutor.CLIVersion{Major: 6, Raw: "6.53.0"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(commands).To(Equal(V6Commands{}))
+		})
+
+		It("builds v7 commands for versions 7 and 8 of the cf cli", func() {
+			commands, err := NewCommandBuilder(cfexecutor.CLIVersion{Major: 7, Raw: "7.2.0"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(commands).To(Equal(V7Commands{}))
+
+			commands, err = NewCommandBuilder(cfexecutor.CLIVersion{Major: 8, Raw: "8.5.0"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(commands).To(Equal(V7Commands{}))
+		})
+
+		It("returns an error for other versions of the cf cli", func() {
+			_, err := NewCommandBuilder(cfexecutor.CLIVersion{Major: 5, Raw: "5.0.0"})
+
+			Expect(err).To(MatchError(UnsupportedCLIVersionError{"5.0.0"}))
+		})
+	})
+
+	Describe("retrying transient failures", func() {
+		var (
+			response  *bytes.Buffer
+			logBuffer *gbytes.Buffer
+		)
+
+		BeforeEach(func() {
+			response = &bytes.Buffer{}
+			courier.Retry = RetryPolicy{
+				Attempts:        3,
+				Backoff:         time.Millisecond,
+				MaxBackoff:      2 * time.Millisecond,
+				TransientErrors: []*regexp.Regexp{regexp.MustCompile("502 Bad Gateway")},
+			}
+			logBuffer = gbytes.NewBuffer()
+			courier.Log = logger.DefaultLogger(logBuffer, logging.DEBUG, "courier_test")
+			courier.Output = response
+		})
+
+		Context("when the output matches a transient error", func() {
+			It("runs the command until it runs out of attempts", func() {
+				executor.ExecuteCall.Returns.Output = []byte("Server error, status code: 502 Bad Gateway")
+				executor.ExecuteCall.Returns.Error = errors.New("exit status 1")
+
+				_, err := courier.MapRoute(appName, "domain", hostname)
+				Expect(err).To(MatchError("exit status 1"))
+
+				Expect(executor.ExecuteCall.TimesCalled).To(Equal(3))
+				Expect(response.String()).To(ContainSubstring("cf map-route failed with a transient error: retrying in 1ms (attempt 2 of 3)"))
+				Expect(response.String()).To(ContainSubstring("cf map-route failed with a transient error: retrying in 2ms (attempt 3 of 3)"))
+			})
+
+			It("logs retries as info and only the final failure as an error", func() {
+				executor.ExecuteCall.Returns.Output = []byte("502 Bad Gateway")
+				executor.ExecuteCall.Returns.Error = errors.New("exit status 1")
+
+				courier.MapRoute(appName, "domain", hostname)
+
+				Expect(logBuffer).To(gbytes.Say(`INFO .* cf map-route failed with a transient error: retrying in 1ms \(attempt 2 of 3\)`))
+				Expect(logBuffer).To(gbytes.Say(`INFO .* cf map-route failed with a transient error: retrying in 2ms \(attempt 3 of 3\)`))
+				Expect(logBuffer).To(gbytes.Say(`ERRO .* cf map-route failed with a transient error after 3 attempts: 502 Bad Gateway`))
+			})
+
+			It("does not write the arguments of the command to the output", func() {
+				executor.ExecuteCall.Returns.Output = []byte("502 Bad Gateway")
+				executor.ExecuteCall.Returns.Error = errors.New("exit status 1")
+
+				courier.Login("foundationURL", "user", "secret-password", "org", "space", false)
+
+				Expect(executor.ExecuteCall.TimesCalled).To(Equal(3))
+				Expect(response.String()).ToNot(ContainSubstring("secret-password"))
+			})
+		})
+
+		Context("when the output does not match a transient error", func() {
+			It("does not retry the command", func() {
+				executor.ExecuteCall.Returns.Output = []byte("App not found")
+				executor.ExecuteCall.Returns.Error = errors.New("exit status 1")
+
+				_, err := courier.Delete(appName)
+				Expect(err).To(HaveOccurred())
+
+				Expect(executor.ExecuteCall.TimesCalled).To(Equal(1))
+				Expect(response.String()).To(BeEmpty())
+			})
+		})
+
+		Context("when the command succeeds", func() {
+			It("does not retry the command", func() {
+				executor.ExecuteCall.Returns.Output = []byte("502 Bad Gateway")
+
+				_, err := courier.Rename(appName, appName+"-venerable")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(executor.ExecuteCall.TimesCalled).To(Equal(1))
+			})
+		})
+
+		It("does not retry pushes", func() {
+			executor.ExecuteInDirectoryCall.Returns.Output = []byte("502 Bad Gateway")
+			executor.ExecuteInDirectoryCall.Returns.Error = errors.New("exit status 1")
+
+			courier.Push(appName, "appLocation", hostname, 1)
+
+			Expect(executor.ExecuteCall.TimesCalled).To(Equal(0))
+			Expect(response.String()).To(BeEmpty())
 		})
 	})
 
@@ -0,0 +1,48 @@
+package courier
+
+import "fmt"
+
+type DomainsError struct {
+	Out []byte
+}
+
+func (e DomainsError) Error() string {
+	return fmt.Sprintf("cannot get domains: %s", string(e.Out))
+}
+
+type DomainsOutputError struct {
+	Out []byte
+}
+
+func (e DomainsOutputError) Error() string {
+	return fmt.Sprintf("cannot read domains from Cloud Foundry output: %s", string(e.Out))
+}
+
+type UnsupportedCLIVersionError struct {
+	Version string
+}
+
+func (e UnsupportedCLIVersionError) Error() string {
+	return fmt.Sprintf("unsupported cf cli version: %s: install version 6, 7 or 8 of the cf cli", e.Version)
+}
+
+type NoDefaultDomainError struct{}
+
+func (e NoDefaultDomainError) Error() string {
+	return "cannot find a shared http domain to route the pushed application to"
+}
+
+type RollingDeploymentsNotSupportedError struct{}
+
+func (e RollingDeploymentsNotSupportedError) Error() string {
+	return "rolling deployments need version 7 or later of the cf cli"
+}
+
+type RevisionsError struct {
+	ApplicationName string
+	Out             []byte
+}
+
+func (e RevisionsError) Error() string {
+	return fmt.Sprintf("cannot get the deployed revision of %s: %s", e.ApplicationName, string(e.Out))
+}
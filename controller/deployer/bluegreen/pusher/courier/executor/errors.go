@@ -0,0 +1,20 @@
+package executor
+
+import "fmt"
+
+type VersionError struct {
+	Err error
+	Out []byte
+}
+
+func (e VersionError) Error() string {
+	return fmt.Sprintf("cannot get the version of the cf cli: %s: %s", e.Err, string(e.Out))
+}
+
+type ParseVersionError struct {
+	Out []byte
+}
+
+func (e ParseVersionError) Error() string {
+	return fmt.Sprintf("cannot read the version of the cf cli from: %s", string(e.Out))
+}
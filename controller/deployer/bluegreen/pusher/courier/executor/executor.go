@@ -9,9 +9,10 @@ import (
 	"github.com/spf13/afero"
 )
 
-// New returns a new Executor struct.
-func New(fileSystem *afero.Afero) (Executor, error) {
-	tempDir, err := fileSystem.TempDir("", "deployadactyl-executor-")
+// New returns a new Executor struct that keeps the settings of the cf cli in a temp directory
+// inside of the working directory, or the OS temp dir when the working directory is empty.
+func New(fileSystem *afero.Afero, workingDirectory string) (Executor, error) {
+	tempDir, err := fileSystem.TempDir(workingDirectory, "deployadactyl-executor-")
 	if err != nil {
 		return Executor{}, err
 	}
@@ -25,15 +26,24 @@ func New(fileSystem *afero.Afero) (Executor, error) {
 // Executor has a file system that is used to execute the Cloud Foundry CLI.
 type Executor struct {
 	tempDir    string
+	caCertFile string
 	fileSystem *afero.Afero
 }
 
+// WithCACert returns an Executor that runs the cf cli with SSL_CERT_FILE set to the PEM bundle
+// in caCertFile, so the certificate of the foundation is verified with it instead of the CAs of
+// the host. The CAs of the host are used when caCertFile is empty.
+func (e Executor) WithCACert(caCertFile string) Executor {
+	e.caCertFile = caCertFile
+	return e
+}
+
 // Execute takes a slice of string args and runs them together against the cf command on the Cloud Foundry binary.
 //
 // Returns the combined standard output and standard error.
 func (e Executor) Execute(args ...string) ([]byte, error) {
 	command := exec.Command("cf", args...)
-	command.Env = setEnv(os.Environ(), "CF_HOME", e.tempDir)
+	command.Env = e.env()
 	return command.CombinedOutput()
 }
 
@@ -42,7 +52,7 @@ func (e Executor) Execute(args ...string) ([]byte, error) {
 // Returns the combined standard output and standard error.
 func (e Executor) ExecuteInDirectory(directory string, args ...string) ([]byte, error) {
 	command := exec.Command("cf", args...)
-	command.Env = setEnv(os.Environ(), "CF_HOME", e.tempDir)
+	command.Env = e.env()
 	command.Dir = directory
 	return command.CombinedOutput()
 }
@@ -52,6 +62,14 @@ func (e Executor) CleanUp() error {
 	return e.fileSystem.RemoveAll(e.tempDir)
 }
 
+func (e Executor) env() []string {
+	env := setEnv(os.Environ(), "CF_HOME", e.tempDir)
+	if e.caCertFile != "" {
+		env = setEnv(env, "SSL_CERT_FILE", e.caCertFile)
+	}
+	return env
+}
+
 func setEnv(env []string, key, value string) []string {
 	keyValuePair := key + "=" + value
 
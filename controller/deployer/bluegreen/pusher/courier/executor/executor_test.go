@@ -0,0 +1,78 @@
+package executor_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher/courier/executor"
+	"github.com/spf13/afero"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Executor", func() {
+	var (
+		directory  string
+		hostPath   string
+		certFile   string
+		fileSystem *afero.Afero
+	)
+
+	BeforeEach(func() {
+		var err error
+		directory, err = ioutil.TempDir("", "executor-test-")
+		Expect(err).ToNot(HaveOccurred())
+
+		cf := "#!/bin/sh\necho \"CF_HOME=$CF_HOME\"\necho \"SSL_CERT_FILE=$SSL_CERT_FILE\"\n"
+		Expect(ioutil.WriteFile(path.Join(directory, "cf"), []byte(cf), 0755)).To(Succeed())
+
+		hostPath = os.Getenv("PATH")
+		os.Setenv("PATH", directory+string(os.PathListSeparator)+hostPath)
+
+		certFile = path.Join(directory, "foundation.pem")
+		fileSystem = &afero.Afero{Fs: afero.NewOsFs()}
+	})
+
+	AfterEach(func() {
+		os.Setenv("PATH", hostPath)
+		os.RemoveAll(directory)
+	})
+
+	It("runs the cf cli with its own CF_HOME", func() {
+		executor, err := New(fileSystem, directory)
+		Expect(err).ToNot(HaveOccurred())
+		defer executor.CleanUp()
+
+		output, err := executor.Execute("version")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(string(output)).To(ContainSubstring("CF_HOME=" + path.Join(directory, "deployadactyl-executor-")))
+	})
+
+	It("runs the cf cli with SSL_CERT_FILE set to the CA bundle of the foundation", func() {
+		executor, err := New(fileSystem, directory)
+		Expect(err).ToNot(HaveOccurred())
+		defer executor.CleanUp()
+
+		output, err := executor.WithCACert(certFile).Execute("login")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(output)).To(ContainSubstring("SSL_CERT_FILE=" + certFile + "\n"))
+
+		output, err = executor.WithCACert(certFile).ExecuteInDirectory(directory, "push")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(output)).To(ContainSubstring("SSL_CERT_FILE=" + certFile + "\n"))
+	})
+
+	It("uses the SSL_CERT_FILE of the host without a CA bundle", func() {
+		executor, err := New(fileSystem, directory)
+		Expect(err).ToNot(HaveOccurred())
+		defer executor.CleanUp()
+
+		output, err := executor.WithCACert("").Execute("login")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(string(output)).To(ContainSubstring("SSL_CERT_FILE=" + os.Getenv("SSL_CERT_FILE") + "\n"))
+	})
+})
@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+var versionPattern = regexp.MustCompile(`version (\d+)\.(\d+)\.(\d+)`)
+
+// CLIVersion is the version of the installed Cloud Foundry CLI.
+type CLIVersion struct {
+	Major int
+	Minor int
+	Patch int
+	Raw   string
+}
+
+// DetectVersion runs the Cloud Foundry version command to find the version of the installed CLI.
+func DetectVersion() (CLIVersion, error) {
+	output, err := exec.Command("cf", "version").CombinedOutput()
+	if err != nil {
+		return CLIVersion{}, VersionError{err, output}
+	}
+
+	return ParseVersion(output)
+}
+
+// ParseVersion reads the output of the Cloud Foundry version command, such as
+// "cf version 6.53.0+8e2b70a4a.2020-10-01" or "cf7 version 7.2.0+be4a5ce2b.2020-12-10".
+func ParseVersion(output []byte) (CLIVersion, error) {
+	match := versionPattern.FindSubmatch(output)
+	if match == nil {
+		return CLIVersion{}, ParseVersionError{output}
+	}
+
+	major, _ := strconv.Atoi(string(match[1]))
+	minor, _ := strconv.Atoi(string(match[2]))
+	patch, _ := strconv.Atoi(string(match[3]))
+
+	return CLIVersion{
+		Major: major,
+		Minor: minor,
+		Patch: patch,
+		Raw:   string(match[1]) + "." + string(match[2]) + "." + string(match[3]),
+	}, nil
+}
@@ -0,0 +1,38 @@
+package executor_test
+
+import (
+	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher/courier/executor"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Version", func() {
+	Describe("parsing the output of cf version", func() {
+		It("reads a v6 version", func() {
+			version, err := ParseVersion([]byte("cf version 6.53.0+8e2b70a4a.2020-10-01\n"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(version).To(Equal(CLIVersion{Major: 6, Minor: 53, Patch: 0, Raw: "6.53.0"}))
+		})
+
+		It("reads a v7 version", func() {
+			version, err := ParseVersion([]byte("cf7 version 7.2.0+be4a5ce2b.2020-12-10\n"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(version).To(Equal(CLIVersion{Major: 7, Minor: 2, Patch: 0, Raw: "7.2.0"}))
+		})
+
+		It("reads a v8 version", func() {
+			version, err := ParseVersion([]byte("cf version 8.5.0+73aa161.2022-09-12\n"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(version.Major).To(Equal(8))
+		})
+
+		It("returns an error when there is no version in the output", func() {
+			_, err := ParseVersion([]byte("command not found"))
+
+			Expect(err).To(MatchError(ParseVersionError{[]byte("command not found")}))
+		})
+	})
+})
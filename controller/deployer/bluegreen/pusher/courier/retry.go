@@ -0,0 +1,37 @@
+package courier
+
+import (
+	"regexp"
+	"time"
+)
+
+// RetryPolicy describes how idempotent Cloud Foundry commands are retried when they fail
+// with a transient error, such as a 502 from the router or a failed token refresh.
+//
+// Attempts is the total number of times a command is run. A command is only retried when
+// its output matches one of the TransientErrors regular expressions. The wait before each
+// retry starts at Backoff and doubles after every retry, up to MaxBackoff.
+type RetryPolicy struct {
+	Attempts        int
+	Backoff         time.Duration
+	MaxBackoff      time.Duration
+	TransientErrors []*regexp.Regexp
+}
+
+func (r RetryPolicy) isTransient(output []byte, err error) bool {
+	for _, pattern := range r.TransientErrors {
+		if pattern.Match(output) || pattern.MatchString(err.Error()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r RetryPolicy) nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if r.MaxBackoff > 0 && backoff > r.MaxBackoff {
+		return r.MaxBackoff
+	}
+	return backoff
+}
@@ -60,3 +60,22 @@ type UnmapRouteError struct {
 func (e UnmapRouteError) Error() string {
 	return fmt.Sprintf("failed to unmap route for %s: %s", e.ApplicationName, string(e.Out))
 }
+
+type CancelDeploymentError struct {
+	ApplicationName string
+	Out             []byte
+}
+
+func (e CancelDeploymentError) Error() string {
+	return fmt.Sprintf("cannot cancel the deployment of %s: %s", e.ApplicationName, string(e.Out))
+}
+
+type RollbackDeploymentError struct {
+	ApplicationName string
+	Revision        int
+	Out             []byte
+}
+
+func (e RollbackDeploymentError) Error() string {
+	return fmt.Sprintf("cannot roll back %s to revision %d: %s", e.ApplicationName, e.Revision, string(e.Out))
+}
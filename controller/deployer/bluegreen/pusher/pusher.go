@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/compozed/deployadactyl/config"
 	C "github.com/compozed/deployadactyl/constants"
 	I "github.com/compozed/deployadactyl/interfaces"
 	S "github.com/compozed/deployadactyl/structs"
@@ -16,9 +17,11 @@ const TemporaryNameSuffix = "-new-build-"
 
 // Pusher has a courier used to push applications to Cloud Foundry.
 // It represents logging into a single foundation to perform operations.
+// Environment is the config of the environment the deployment started with.
 type Pusher struct {
 	Courier        I.Courier
 	DeploymentInfo S.DeploymentInfo
+	Environment    config.Environment
 	EventManager   I.EventManager
 	Response       io.ReadWriter
 	Log            I.Logger
@@ -26,20 +29,26 @@ type Pusher struct {
 }
 
 // Login will login to a Cloud Foundry instance.
+// The credentials for the foundation are used when the deployment has them.
 func (p Pusher) Login(foundationURL string) error {
+	credentials, ok := p.DeploymentInfo.FoundationCredentials[foundationURL]
+	if !ok {
+		credentials = S.Credentials{Username: p.DeploymentInfo.Username, Password: p.DeploymentInfo.Password}
+	}
+
 	p.Log.Debugf(
 		`logging into cloud foundry with parameters:
 		foundation URL: %+v
 		username: %+v
 		org: %+v
 		space: %+v`,
-		foundationURL, p.DeploymentInfo.Username, p.DeploymentInfo.Org, p.DeploymentInfo.Space,
+		foundationURL, credentials.Username, p.DeploymentInfo.Org, p.DeploymentInfo.Space,
 	)
 
 	output, err := p.Courier.Login(
 		foundationURL,
-		p.DeploymentInfo.Username,
-		p.DeploymentInfo.Password,
+		credentials.Username,
+		credentials.Password,
 		p.DeploymentInfo.Org,
 		p.DeploymentInfo.Space,
 		p.DeploymentInfo.SkipSSL,
@@ -90,6 +99,7 @@ func (p Pusher) Push(appPath, foundationURL string) error {
 		FoundationURL:   foundationURL,
 		TempAppWithUUID: tempAppWithUUID,
 		DeploymentInfo:  &p.DeploymentInfo,
+		Environment:     p.Environment,
 		Courier:         p.Courier,
 		Response:        p.Response,
 	}
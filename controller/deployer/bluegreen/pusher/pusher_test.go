@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 
+	"github.com/compozed/deployadactyl/config"
 	C "github.com/compozed/deployadactyl/constants"
 	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher"
 	"github.com/compozed/deployadactyl/logger"
@@ -101,6 +102,17 @@ var _ = Describe("Pusher", func() {
 				Expect(courier.LoginCall.Received.SkipSSL).To(Equal(skipSSL))
 			})
 
+			It("uses the credentials for the foundation when the deployment has them", func() {
+				pusher.DeploymentInfo.FoundationCredentials = map[string]S.Credentials{
+					randomFoundationURL: {Username: "foundation-username", Password: "foundation-password"},
+				}
+
+				Expect(pusher.Login(randomFoundationURL)).To(Succeed())
+
+				Expect(courier.LoginCall.Received.Username).To(Equal("foundation-username"))
+				Expect(courier.LoginCall.Received.Password).To(Equal("foundation-password"))
+			})
+
 			It("writes the output of the courier to the response", func() {
 				courier.LoginCall.Returns.Output = []byte("login succeeded")
 
@@ -469,6 +481,14 @@ var _ = Describe("Pusher", func() {
 
 				Expect(eventManager.EmitCall.Received.Events[0].Data.(S.PushEventData).TempAppWithUUID).To(Equal(randomAppName + TemporaryNameSuffix + randomUUID))
 			})
+
+			It("has the config of the environment the deployment started with on the event", func() {
+				pusher.Environment = config.Environment{Name: "production", Handlers: config.Handlers{RouteMapper: config.Handler{Enabled: true}}}
+
+				Expect(pusher.Push(randomAppPath, randomFoundationURL)).To(Succeed())
+
+				Expect(eventManager.EmitCall.Received.Events[0].Data.(S.PushEventData).Environment).To(Equal(pusher.Environment))
+			})
 		})
 
 		Context("when an event fails", func() {
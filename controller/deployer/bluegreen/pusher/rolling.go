@@ -0,0 +1,108 @@
+package pusher
+
+import (
+	"fmt"
+)
+
+// RollingPusher pushes an application to a Cloud Foundry instance with a native rolling deployment.
+// The instances of the existing application are replaced in place, so the application keeps its GUID,
+// service bindings, network policies and routes. There is no temporary application to rename,
+// so the push.finished event is not emitted.
+type RollingPusher struct {
+	Pusher
+	previousRevision int
+}
+
+// Push starts a rolling deployment of the application. The deployed revision is
+// remembered first so the deployment can be rolled back if another foundation fails.
+//
+// Returns Cloud Foundry logs if there is an error.
+func (p *RollingPusher) Push(appPath, foundationURL string) error {
+	appName := p.DeploymentInfo.AppName
+
+	if p.appExists {
+		revision, err := p.Courier.DeployedRevision(appName)
+		if err != nil {
+			p.Log.Errorf("could not get the deployed revision of %s: %s", appName, err)
+		}
+		p.previousRevision = revision
+	} else {
+		p.Log.Infof("new app detected")
+	}
+
+	p.Log.Debugf("starting a rolling deployment of %s to %s", appName, foundationURL)
+
+	var (
+		pushOutput       []byte
+		cloudFoundryLogs []byte
+		err              error
+		logsErr          error
+	)
+
+	defer func() { p.Response.Write(cloudFoundryLogs) }()
+	defer func() { p.Response.Write(pushOutput) }()
+
+	pushOutput, err = p.Courier.PushRolling(appName, appPath, p.DeploymentInfo.Instances)
+	p.Log.Infof("output from Cloud Foundry: \n%s", pushOutput)
+	if err != nil {
+		defer p.Log.Errorf("logs from %s: \n%s", appName, cloudFoundryLogs)
+
+		cloudFoundryLogs, logsErr = p.Courier.Logs(appName)
+		if logsErr != nil {
+			return CloudFoundryGetLogsError{err, logsErr}
+		}
+
+		return PushError{}
+	}
+
+	p.Log.Infof("successfully deployed %s with a rolling deployment", appName)
+
+	if p.DeploymentInfo.Domain != "" {
+		return p.mapTempAppToLoadBalancedDomain(appName)
+	}
+
+	return nil
+}
+
+// FinishPush does nothing because a rolling deployment is finished when its push succeeds.
+func (p *RollingPusher) FinishPush() error {
+	return nil
+}
+
+// UndoPush is only called when a Push fails. A rolling deployment that is still in progress
+// is cancelled. A rolling deployment that already finished is rolled back to the revision that
+// was deployed before it. Nothing is rolled back if it is the first deployment.
+func (p *RollingPusher) UndoPush() error {
+	appName := p.DeploymentInfo.AppName
+
+	if !p.appExists {
+		p.Log.Errorf("app %s did not previously exist: not rolling back", appName)
+		return nil
+	}
+
+	p.Log.Errorf("rolling back deploy of %s", appName)
+
+	out, err := p.Courier.CancelDeployment(appName)
+	if err == nil {
+		p.Response.Write(out)
+		p.Log.Infof("cancelled the deployment of %s", appName)
+		return nil
+	}
+
+	if p.previousRevision == 0 {
+		p.Log.Errorf("could not cancel the deployment of %s", appName)
+		return CancelDeploymentError{appName, out}
+	}
+
+	out, err = p.Courier.Rollback(appName, p.previousRevision)
+	p.Response.Write(out)
+	if err != nil {
+		p.Log.Errorf("could not roll back %s to revision %d", appName, p.previousRevision)
+		return RollbackDeploymentError{appName, p.previousRevision, out}
+	}
+
+	p.Log.Infof("rolled back %s to revision %d", appName, p.previousRevision)
+	fmt.Fprintf(p.Response, "rolled back %s to revision %d\n", appName, p.previousRevision)
+
+	return nil
+}
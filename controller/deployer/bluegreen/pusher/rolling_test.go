@@ -0,0 +1,170 @@
+package pusher_test
+
+import (
+	"errors"
+
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher"
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/op/go-logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("RollingPusher", func() {
+	var (
+		pusher       *RollingPusher
+		courier      *mocks.Courier
+		eventManager *mocks.EventManager
+
+		randomAppName       string
+		randomAppPath       string
+		randomDomain        string
+		randomFoundationURL string
+		response            *Buffer
+		logBuffer           *Buffer
+	)
+
+	BeforeEach(func() {
+		courier = &mocks.Courier{}
+		eventManager = &mocks.EventManager{}
+
+		randomAppName = "randomAppName-" + randomizer.StringRunes(10)
+		randomAppPath = "randomAppPath-" + randomizer.StringRunes(10)
+		randomDomain = "randomDomain-" + randomizer.StringRunes(10)
+		randomFoundationURL = "randomFoundationURL-" + randomizer.StringRunes(10)
+
+		response = NewBuffer()
+		logBuffer = NewBuffer()
+
+		pusher = &RollingPusher{
+			Pusher: Pusher{
+				Courier: courier,
+				DeploymentInfo: S.DeploymentInfo{
+					AppName:   randomAppName,
+					Instances: 2,
+					UUID:      randomizer.StringRunes(10),
+					Strategy:  config.RollingStrategy,
+				},
+				EventManager: eventManager,
+				Response:     response,
+				Log:          logger.DefaultLogger(logBuffer, logging.DEBUG, "rolling_test"),
+			},
+		}
+	})
+
+	Describe("pushing", func() {
+		It("pushes the application with a rolling deployment", func() {
+			courier.PushRollingCall.Returns.Output = []byte("push succeeded")
+
+			Expect(pusher.Push(randomAppPath, randomFoundationURL)).To(Succeed())
+
+			Expect(courier.PushRollingCall.Received.AppName).To(Equal(randomAppName))
+			Expect(courier.PushRollingCall.Received.AppPath).To(Equal(randomAppPath))
+			Expect(courier.PushRollingCall.Received.Instances).To(Equal(uint16(2)))
+			Eventually(response).Should(Say("push succeeded"))
+		})
+
+		It("does not emit a push.finished event", func() {
+			Expect(pusher.Push(randomAppPath, randomFoundationURL)).To(Succeed())
+
+			Expect(eventManager.EmitCall.TimesCalled).To(Equal(0))
+		})
+
+		It("remembers the deployed revision when the app exists", func() {
+			courier.ExistsCall.Returns.Bool = true
+			pusher.Exists(randomAppName)
+
+			Expect(pusher.Push(randomAppPath, randomFoundationURL)).To(Succeed())
+
+			Expect(courier.DeployedRevisionCall.Received.AppName).To(Equal(randomAppName))
+		})
+
+		It("maps the load balanced domain when there is one", func() {
+			pusher.DeploymentInfo.Domain = randomDomain
+
+			Expect(pusher.Push(randomAppPath, randomFoundationURL)).To(Succeed())
+
+			Expect(courier.MapRouteCall.Received.AppName).To(ConsistOf(randomAppName))
+			Expect(courier.MapRouteCall.Received.Domain).To(ConsistOf(randomDomain))
+		})
+
+		Context("when the push fails", func() {
+			It("writes the logs to the response and returns an error", func() {
+				courier.PushRollingCall.Returns.Error = errors.New("push error")
+				courier.LogsCall.Returns.Output = []byte("cf logs")
+
+				Expect(pusher.Push(randomAppPath, randomFoundationURL)).To(MatchError(PushError{}))
+
+				Eventually(response).Should(Say("cf logs"))
+			})
+		})
+	})
+
+	Describe("finishing a push", func() {
+		It("does not rename or delete anything", func() {
+			Expect(pusher.FinishPush()).To(Succeed())
+
+			Expect(courier.RenameCall.Received.AppName).To(BeEmpty())
+			Expect(courier.DeleteCall.Received.AppName).To(BeEmpty())
+		})
+	})
+
+	Describe("undoing a push", func() {
+		BeforeEach(func() {
+			courier.ExistsCall.Returns.Bool = true
+			courier.DeployedRevisionCall.Returns.Revision = 4
+			pusher.Exists(randomAppName)
+			pusher.Push(randomAppPath, randomFoundationURL)
+		})
+
+		It("cancels a deployment that is in progress", func() {
+			Expect(pusher.UndoPush()).To(Succeed())
+
+			Expect(courier.CancelDeploymentCall.Received.AppName).To(Equal(randomAppName))
+			Expect(courier.RollbackCall.TimesCalled).To(Equal(0))
+		})
+
+		It("rolls back to the previous revision when the deployment has finished", func() {
+			courier.CancelDeploymentCall.Returns.Error = errors.New("no active deployment")
+
+			Expect(pusher.UndoPush()).To(Succeed())
+
+			Expect(courier.RollbackCall.Received.AppName).To(Equal(randomAppName))
+			Expect(courier.RollbackCall.Received.Revision).To(Equal(4))
+			Eventually(response).Should(Say("rolled back %s to revision 4", randomAppName))
+		})
+
+		It("returns an error when the rollback fails", func() {
+			courier.CancelDeploymentCall.Returns.Error = errors.New("no active deployment")
+			courier.RollbackCall.Returns.Output = []byte("rollback output")
+			courier.RollbackCall.Returns.Error = errors.New("rollback error")
+
+			Expect(pusher.UndoPush()).To(MatchError(RollbackDeploymentError{randomAppName, 4, []byte("rollback output")}))
+		})
+
+		It("returns an error when the deployment cannot be cancelled and there is no previous revision", func() {
+			courier.CancelDeploymentCall.Returns.Output = []byte("cancel output")
+			courier.CancelDeploymentCall.Returns.Error = errors.New("no active deployment")
+			courier.DeployedRevisionCall.Returns.Revision = 0
+			courier.DeployedRevisionCall.Returns.Error = errors.New("revisions error")
+			pusher.Push(randomAppPath, randomFoundationURL)
+
+			Expect(pusher.UndoPush()).To(MatchError(CancelDeploymentError{randomAppName, []byte("cancel output")}))
+		})
+	})
+
+	Describe("undoing the first push", func() {
+		It("does not roll anything back", func() {
+			Expect(pusher.UndoPush()).To(Succeed())
+
+			Expect(courier.CancelDeploymentCall.TimesCalled).To(Equal(0))
+			Expect(courier.RollbackCall.TimesCalled).To(Equal(0))
+		})
+	})
+})
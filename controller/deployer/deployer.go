@@ -6,12 +6,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"regexp"
+	"time"
 
+	"github.com/compozed/deployadactyl/artifetcher"
 	"github.com/compozed/deployadactyl/config"
 	C "github.com/compozed/deployadactyl/constants"
 	"github.com/compozed/deployadactyl/controller/deployer/manifestro"
+	"github.com/compozed/deployadactyl/controller/deployer/policy"
+	"github.com/compozed/deployadactyl/diskspace"
 	"github.com/compozed/deployadactyl/geterrors"
 	I "github.com/compozed/deployadactyl/interfaces"
 	S "github.com/compozed/deployadactyl/structs"
@@ -33,11 +38,24 @@ Environment:  %s,
 Org:          %s,
 Space:        %s,
 AppName:      %s`
+
+	deploymentInfoPart = "deployment_info"
+	artifactPart       = "artifact"
+
+	// statusInsufficientStorage is not named by net/http in every supported version of Go.
+	statusInsufficientStorage = 507
+
+	checksumOutput = `
+Artifact SHA256: %s,
+Artifact SHA1:   %s,
+Artifact MD5:    %s`
 )
 
 // Deployer contains the bluegreener for deployments, environment variables, a fetcher for artifacts, a prechecker and event manager.
+// The Config is read from the ConfigProvider once at the start of each deploy, so a deploy is not
+// affected by the config being reloaded while it is in progress.
 type Deployer struct {
-	Config       config.Config
+	Config       I.ConfigProvider
 	BlueGreener  I.BlueGreener
 	Fetcher      I.Fetcher
 	Prechecker   I.Prechecker
@@ -50,12 +68,14 @@ type Deployer struct {
 // Deploy takes the deployment information, checks the foundations, fetches the artifact and deploys the application.
 func (d Deployer) Deploy(req *http.Request, environment, org, space, appName, contentType string, response io.ReadWriter) (statusCode int, err error) {
 	var (
+		cfg                    = d.Config.Config()
 		deploymentInfo         = S.DeploymentInfo{}
-		environments           = d.Config.Environments
+		environments           = cfg.Environments
 		authenticationRequired = environments[environment].Authenticate
 		deployEventData        = S.DeployEventData{}
 		manifest               []byte
 		appPath                string
+		foundationCredentials  map[string]S.Credentials
 	)
 	defer func() { d.FileSystem.RemoveAll(appPath) }()
 
@@ -65,21 +85,29 @@ func (d Deployer) Deploy(req *http.Request, environment, org, space, appName, co
 		return http.StatusInternalServerError, EnvironmentNotFoundError{environment}
 	}
 
-	d.Log.Debug("prechecking the foundations")
-	err = d.Prechecker.AssertAllFoundationsUp(environments[environment])
-	if err != nil {
-		d.Log.Error(err)
-		return http.StatusInternalServerError, err
-	}
-
 	d.Log.Debug("checking for basic auth")
 	username, password, ok := req.BasicAuth()
 	if !ok {
 		if authenticationRequired {
 			return http.StatusUnauthorized, BasicAuthError{}
 		}
-		username = d.Config.Username
-		password = d.Config.Password
+		username, password = credentialsFor(cfg, e)
+		foundationCredentials = foundationCredentialsFor(e)
+	}
+
+	d.Log.Debug("checking the policy of the environment")
+	err = policy.Check(e, org, space, time.Now())
+	if err != nil {
+		d.Log.Error(err)
+		fmt.Fprintln(response, err)
+		return http.StatusForbidden, err
+	}
+
+	d.Log.Debug("prechecking the foundations")
+	err = d.Prechecker.AssertAllFoundationsUp(environments[environment])
+	if err != nil {
+		d.Log.Error(err)
+		return http.StatusInternalServerError, err
 	}
 
 	if isJSON(contentType) {
@@ -99,17 +127,48 @@ func (d Deployer) Deploy(req *http.Request, environment, org, space, appName, co
 			}
 		}
 
-		appPath, err = d.Fetcher.Fetch(deploymentInfo.ArtifactURL, string(manifest))
+		expectedChecksums := S.Checksums{
+			SHA256: deploymentInfo.ArtifactSHA256,
+			SHA1:   deploymentInfo.ArtifactSHA1,
+			MD5:    deploymentInfo.ArtifactMD5,
+		}
+
+		signature := S.ArtifactSignature{
+			Signature: deploymentInfo.ArtifactSignature,
+			URL:       deploymentInfo.ArtifactSignatureURL,
+		}
+
+		var checksums S.Checksums
+		appPath, checksums, err = d.Fetcher.Fetch(deploymentInfo.ArtifactURL, string(manifest), expectedChecksums, signature, e, response)
 		if err != nil {
 			d.Log.Error(err)
-			return http.StatusInternalServerError, err
+			return fetchStatus(err), err
+		}
+
+		deploymentInfo.ArtifactSHA256 = checksums.SHA256
+		deploymentInfo.ArtifactSHA1 = checksums.SHA1
+		deploymentInfo.ArtifactMD5 = checksums.MD5
+
+	} else if isArchive(contentType) {
+		d.Log.Debugf("deploying from %s request", contentType)
+		if e.RequireSignedArtifacts {
+			return http.StatusBadRequest, SignedArtifactRequiredError{environment}
 		}
 
-	} else if isZip(contentType) {
-		d.Log.Debug("deploying from zip request")
 		appPath, err = d.Fetcher.FetchZipFromRequest(req)
 		if err != nil {
-			return http.StatusInternalServerError, err
+			return fetchStatus(err), err
+		}
+
+		manifest, _ = d.FileSystem.ReadFile(appPath + "/manifest.yml")
+
+		deploymentInfo.ArtifactURL = appPath
+	} else if isMultipart(contentType) {
+		d.Log.Debug("deploying from multipart request")
+		deploymentInfo, appPath, statusCode, err = d.fetchMultipart(req, e, response)
+		if err != nil {
+			d.Log.Error(err)
+			return statusCode, err
 		}
 
 		manifest, _ = d.FileSystem.ReadFile(appPath + "/manifest.yml")
@@ -121,6 +180,7 @@ func (d Deployer) Deploy(req *http.Request, environment, org, space, appName, co
 
 	deploymentInfo.Username = username
 	deploymentInfo.Password = password
+	deploymentInfo.FoundationCredentials = foundationCredentials
 	deploymentInfo.Environment = environment
 	deploymentInfo.Org = org
 	deploymentInfo.Space = space
@@ -130,6 +190,7 @@ func (d Deployer) Deploy(req *http.Request, environment, org, space, appName, co
 	deploymentInfo.Manifest = string(manifest)
 	deploymentInfo.Domain = environments[environment].Domain
 	deploymentInfo.AppPath = appPath
+	deploymentInfo.Strategy = environments[environment].Strategy
 
 	instances := manifestro.GetInstances(deploymentInfo.Manifest)
 	if instances != nil {
@@ -138,6 +199,13 @@ func (d Deployer) Deploy(req *http.Request, environment, org, space, appName, co
 		deploymentInfo.Instances = environments[environment].Instances
 	}
 
+	err = policy.CheckInstances(e, deploymentInfo.Instances)
+	if err != nil {
+		d.Log.Error(err)
+		fmt.Fprintln(response, err)
+		return http.StatusForbidden, err
+	}
+
 	e, found := environments[deploymentInfo.Environment]
 	if !found {
 		err = d.EventManager.Emit(S.Event{Type: C.DeployErrorEvent, Data: deployEventData})
@@ -151,10 +219,13 @@ func (d Deployer) Deploy(req *http.Request, environment, org, space, appName, co
 	}
 
 	deploymentMessage := fmt.Sprintf(deploymentOutput, deploymentInfo.ArtifactURL, deploymentInfo.Username, deploymentInfo.Environment, deploymentInfo.Org, deploymentInfo.Space, deploymentInfo.AppName)
+	if deploymentInfo.ArtifactSHA256 != "" {
+		deploymentMessage += fmt.Sprintf(checksumOutput, deploymentInfo.ArtifactSHA256, deploymentInfo.ArtifactSHA1, deploymentInfo.ArtifactMD5)
+	}
 	d.Log.Info(deploymentMessage)
 	fmt.Fprintln(response, deploymentMessage)
 
-	deployEventData = S.DeployEventData{Response: response, DeploymentInfo: &deploymentInfo, RequestBody: req.Body}
+	deployEventData = S.DeployEventData{Response: response, DeploymentInfo: &deploymentInfo, Environment: e, RequestBody: req.Body}
 
 	defer emitDeployFinish(d, deployEventData, response, &err, &statusCode)
 
@@ -180,6 +251,76 @@ func (d Deployer) Deploy(req *http.Request, environment, org, space, appName, co
 	return http.StatusOK, err
 }
 
+// fetchMultipart reads a multipart/form-data request with a deployment_info part, which is the
+// same JSON as a JSON request without the artifact_url, followed by an artifact part with an archive.
+// The manifest, checksums and signature in the deployment_info are used for the archive.
+//
+// Returns the deployment info, the path of the extracted archive, a status code and an error.
+func (d Deployer) fetchMultipart(req *http.Request, environment config.Environment, response io.Writer) (S.DeploymentInfo, string, int, error) {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return S.DeploymentInfo{}, "", http.StatusBadRequest, MultipartError{err}
+	}
+
+	var (
+		deploymentInfo S.DeploymentInfo
+		manifest       []byte
+		found          bool
+	)
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return S.DeploymentInfo{}, "", http.StatusBadRequest, MissingPartError{artifactPart}
+		}
+		if err != nil {
+			return S.DeploymentInfo{}, "", http.StatusBadRequest, MultipartError{err}
+		}
+
+		switch part.FormName() {
+		case deploymentInfoPart:
+			err = json.NewDecoder(part).Decode(&deploymentInfo)
+			if err != nil {
+				return S.DeploymentInfo{}, "", http.StatusBadRequest, MultipartError{err}
+			}
+
+			if deploymentInfo.Manifest != "" {
+				manifest, err = base64.StdEncoding.DecodeString(deploymentInfo.Manifest)
+				if err != nil {
+					return S.DeploymentInfo{}, "", http.StatusBadRequest, ManifestError{err}
+				}
+			}
+			found = true
+
+		case artifactPart:
+			if !found {
+				return S.DeploymentInfo{}, "", http.StatusBadRequest, MissingPartError{deploymentInfoPart}
+			}
+
+			expectedChecksums := S.Checksums{
+				SHA256: deploymentInfo.ArtifactSHA256,
+				SHA1:   deploymentInfo.ArtifactSHA1,
+				MD5:    deploymentInfo.ArtifactMD5,
+			}
+			signature := S.ArtifactSignature{
+				Signature: deploymentInfo.ArtifactSignature,
+				URL:       deploymentInfo.ArtifactSignatureURL,
+			}
+
+			appPath, checksums, err := d.Fetcher.FetchArchive(part, string(manifest), expectedChecksums, signature, environment, response)
+			if err != nil {
+				return S.DeploymentInfo{}, "", fetchStatus(err), err
+			}
+
+			deploymentInfo.ArtifactSHA256 = checksums.SHA256
+			deploymentInfo.ArtifactSHA1 = checksums.SHA1
+			deploymentInfo.ArtifactMD5 = checksums.MD5
+
+			return deploymentInfo, appPath, http.StatusOK, nil
+		}
+	}
+}
+
 func getDeploymentInfo(reader io.Reader) (S.DeploymentInfo, error) {
 	deploymentInfo := S.DeploymentInfo{}
 	err := json.NewDecoder(reader).Decode(&deploymentInfo)
@@ -203,14 +344,64 @@ func getDeploymentInfo(reader io.Reader) (S.DeploymentInfo, error) {
 	return deploymentInfo, nil
 }
 
-func isZip(contentType string) bool {
-	return contentType == "application/zip"
+// archiveContentTypes are the content types of archives the extractor can extract.
+var archiveContentTypes = map[string]bool{
+	"application/zip":                   true,
+	"application/x-zip-compressed":      true,
+	"application/java-archive":          true,
+	"application/x-tar":                 true,
+	"application/gzip":                  true,
+	"application/x-gzip":                true,
+	"application/x-compressed-tar":      true,
+	"application/x-bzip2":               true,
+	"application/x-bzip-compressed-tar": true,
+}
+
+func isArchive(contentType string) bool {
+	return archiveContentTypes[contentType]
+}
+
+func isMultipart(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "multipart/form-data"
 }
 
 func isJSON(contentType string) bool {
 	return contentType == "application/json"
 }
 
+// credentialsFor returns the credentials of the environment, or CF_USERNAME and CF_PASSWORD when it has none.
+func credentialsFor(cfg config.Config, environment config.Environment) (string, string) {
+	if environment.Credentials.Username != "" {
+		return environment.Credentials.Username, environment.Credentials.Password
+	}
+	return cfg.Username, cfg.Password
+}
+
+func foundationCredentialsFor(environment config.Environment) map[string]S.Credentials {
+	if len(environment.FoundationCredentials) == 0 {
+		return nil
+	}
+
+	foundationCredentials := map[string]S.Credentials{}
+	for foundationURL, credentials := range environment.FoundationCredentials {
+		foundationCredentials[foundationURL] = S.Credentials{Username: credentials.Username, Password: credentials.Password}
+	}
+	return foundationCredentials
+}
+
+// fetchStatus returns the status for an error from fetching an artifact. Artifacts that are too
+// large and deploys without enough free space have their own status so clients can tell them apart.
+func fetchStatus(err error) int {
+	switch err.(type) {
+	case artifetcher.ArtifactTooLargeError:
+		return http.StatusRequestEntityTooLarge
+	case diskspace.InsufficientDiskSpaceError:
+		return statusInsufficientStorage
+	}
+	return http.StatusInternalServerError
+}
+
 func emitDeployFinish(d Deployer, deployEventData S.DeployEventData, response io.ReadWriter, err *error, statusCode *int) {
 	d.Log.Debugf("emitting a %s event", C.DeployFinishEvent)
 
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"mime/multipart"
 	"net/http"
 
 	. "github.com/onsi/ginkgo"
@@ -14,9 +15,12 @@ import (
 	"github.com/op/go-logging"
 	"github.com/spf13/afero"
 
+	"github.com/compozed/deployadactyl/artifetcher"
 	"github.com/compozed/deployadactyl/config"
 	C "github.com/compozed/deployadactyl/constants"
 	. "github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/policy"
+	"github.com/compozed/deployadactyl/diskspace"
 	"github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/logger"
 	"github.com/compozed/deployadactyl/mocks"
@@ -39,6 +43,7 @@ var _ = Describe("Deployer", func() {
 		deployer Deployer
 
 		c              config.Config
+		configProvider *mocks.ConfigProvider
 		blueGreener    *mocks.BlueGreener
 		fetcher        *mocks.Fetcher
 		prechecker     *mocks.Prechecker
@@ -146,8 +151,11 @@ var _ = Describe("Deployer", func() {
 		logBuffer = NewBuffer()
 		log = logger.DefaultLogger(logBuffer, logging.DEBUG, "deployer tests")
 
+		configProvider = &mocks.ConfigProvider{}
+		configProvider.ConfigCall.Returns.Config = c
+
 		deployer = Deployer{
-			c,
+			configProvider,
 			blueGreener,
 			fetcher,
 			prechecker,
@@ -172,12 +180,90 @@ var _ = Describe("Deployer", func() {
 		})
 	})
 
+	Describe("checking the policy of the environment", func() {
+		Context("when the org is not allowed", func() {
+			It("rejects the request with a http.StatusForbidden before prechecking", func() {
+				environments[environment] = config.Environment{
+					Name:   environment,
+					Policy: config.Policy{AllowedOrgs: []string{"team-*"}},
+				}
+
+				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(policy.ViolationError{Environment: environment, Policy: "allowed_orgs", Reason: fmt.Sprintf("org %s is not allowed", org)}))
+
+				Expect(statusCode).To(Equal(http.StatusForbidden))
+				Expect(response.String()).To(ContainSubstring("allowed_orgs"))
+				Expect(prechecker.AssertAllFoundationsUpCall.Received.Environment).To(Equal(config.Environment{}))
+				Expect(eventManager.EmitCall.TimesCalled).To(Equal(0), eventManagerNotEnoughCalls)
+			})
+		})
+
+		Context("when the deployment has more instances than allowed", func() {
+			It("rejects the request with a http.StatusForbidden before pushing", func() {
+				environments[environment] = config.Environment{
+					Name:      environment,
+					Instances: 4,
+					Policy:    config.Policy{MaxInstances: 3},
+				}
+
+				deploymentInfo.Manifest = `---
+applications:
+- name: deployadactyl
+  instances: 5
+`
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{
+					"artifact_url": "%s",
+					"manifest": "%s"
+				}`,
+					artifactURL,
+					base64.StdEncoding.EncodeToString([]byte(deploymentInfo.Manifest)),
+				))
+
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(policy.ViolationError{Environment: environment, Policy: "max_instances", Reason: "5 instances is more than 3"}))
+
+				Expect(statusCode).To(Equal(http.StatusForbidden))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo).To(Equal(S.DeploymentInfo{}))
+			})
+		})
+
+		Context("when the org and space are allowed", func() {
+			It("accepts the request", func() {
+				environments[environment] = config.Environment{
+					Name:   environment,
+					Policy: config.Policy{AllowedOrgs: []string{org}, AllowedSpaces: []string{"*"}},
+				}
+
+				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(statusCode).To(Equal(http.StatusOK))
+			})
+		})
+	})
+
+	Describe("emitting events", func() {
+		It("emits the deploy events with the config of the environment the deployment started with", func() {
+			started := environments[environment]
+
+			statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statusCode).To(Equal(http.StatusOK))
+
+			Expect(eventManager.EmitCall.Received.Events).ToNot(BeEmpty())
+			for _, event := range eventManager.EmitCall.Received.Events {
+				Expect(event.Data.(S.DeployEventData).Environment).To(Equal(started))
+			}
+		})
+	})
+
 	Describe("authentication", func() {
 		Context("a username and password are not provided", func() {
 			Context("when authenticate in the config is not true", func() {
 				It("uses the config username and password and accepts the request with a http.StatusOK", func() {
 					By("setting authenticate to false")
-					deployer.Config.Environments[environment] = config.Environment{Authenticate: false}
+					environments[environment] = config.Environment{Authenticate: false}
 
 					By("not setting basic auth")
 
@@ -193,7 +279,7 @@ var _ = Describe("Deployer", func() {
 
 			Context("when authenticate in the config is true", func() {
 				It("rejects the request with a http.StatusUnauthorized", func() {
-					deployer.Config.Environments[environment] = config.Environment{Authenticate: true}
+					environments[environment] = config.Environment{Authenticate: true}
 
 					By("not setting basic auth")
 
@@ -203,6 +289,54 @@ var _ = Describe("Deployer", func() {
 					Expect(statusCode).To(Equal(http.StatusUnauthorized))
 					Expect(eventManager.EmitCall.TimesCalled).To(Equal(0), eventManagerNotEnoughCalls)
 				})
+
+				It("rejects the request before checking the policy or the foundations", func() {
+					environments[environment] = config.Environment{
+						Name:         environment,
+						Authenticate: true,
+						Policy:       config.Policy{AllowedOrgs: []string{"another-org"}},
+					}
+
+					statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+					Expect(err).To(MatchError(BasicAuthError{}))
+
+					Expect(statusCode).To(Equal(http.StatusUnauthorized))
+					Expect(response.String()).ToNot(ContainSubstring("policy"))
+					Expect(response.String()).ToNot(ContainSubstring("another-org"))
+					Expect(prechecker.AssertAllFoundationsUpCall.Received.Environment).To(Equal(config.Environment{}))
+				})
+			})
+
+			Context("when the environment has credentials", func() {
+				BeforeEach(func() {
+					environments[environment] = config.Environment{
+						Credentials: config.Credentials{Username: "environment-username", Password: "environment-password"},
+						FoundationCredentials: map[string]config.Credentials{
+							"https://api.example.com": {Username: "foundation-username", Password: "foundation-password"},
+						},
+					}
+				})
+
+				It("uses the credentials of the environment and its foundations", func() {
+					statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(statusCode).To(Equal(http.StatusOK))
+
+					Expect(blueGreener.PushCall.Received.DeploymentInfo.Username).To(Equal("environment-username"))
+					Expect(blueGreener.PushCall.Received.DeploymentInfo.Password).To(Equal("environment-password"))
+					Expect(blueGreener.PushCall.Received.DeploymentInfo.FoundationCredentials).To(Equal(map[string]S.Credentials{
+						"https://api.example.com": {Username: "foundation-username", Password: "foundation-password"},
+					}))
+				})
+
+				It("uses basic auth for every foundation when it is given", func() {
+					req.SetBasicAuth("basic-username", "basic-password")
+
+					deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+
+					Expect(blueGreener.PushCall.Received.DeploymentInfo.Username).To(Equal("basic-username"))
+					Expect(blueGreener.PushCall.Received.DeploymentInfo.FoundationCredentials).To(BeEmpty())
+				})
 			})
 		})
 	})
@@ -282,6 +416,37 @@ var _ = Describe("Deployer", func() {
 					Expect(fetcher.FetchCall.Received.Manifest).To(Equal(manifest))
 				})
 			})
+
+			Context("when the artifact is too large", func() {
+				It("returns an error and http.StatusRequestEntityTooLarge", func() {
+					fetcher.FetchCall.Returns.Error = artifetcher.ArtifactTooLargeError{Url: artifactURL, Limit: 1024}
+
+					statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+					Expect(err).To(MatchError(artifetcher.ArtifactTooLargeError{Url: artifactURL, Limit: 1024}))
+
+					Expect(statusCode).To(Equal(http.StatusRequestEntityTooLarge))
+				})
+			})
+
+			Context("when there is not enough free space in the working directory", func() {
+				It("returns an error and a 507 status", func() {
+					fetcher.FetchCall.Returns.Error = diskspace.InsufficientDiskSpaceError{Directory: "/tmp", Required: 2048, Available: 1024}
+
+					statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+					Expect(err).To(MatchError(diskspace.InsufficientDiskSpaceError{Directory: "/tmp", Required: 2048, Available: 1024}))
+
+					Expect(statusCode).To(Equal(507))
+				})
+			})
+
+			It("uses the artifact credentials of the environment", func() {
+				credentials := []config.ArtifactCredentials{{Host: "artifactory.example.com", Token: "token"}}
+				environments[environment] = config.Environment{ArtifactCredentials: credentials}
+
+				deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+
+				Expect(fetcher.FetchCall.Received.Environment.ArtifactCredentials).To(Equal(credentials))
+			})
 		})
 	})
 
@@ -308,6 +473,161 @@ var _ = Describe("Deployer", func() {
 					Expect(statusCode).To(Equal(http.StatusInternalServerError))
 				})
 			})
+
+			Context("when the archive is too large", func() {
+				It("returns an error and http.StatusRequestEntityTooLarge", func() {
+					fetcher.FetchFromZipCall.Returns.Error = artifetcher.ArtifactTooLargeError{Url: "uploaded archive", Limit: 1024}
+
+					statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/zip", response)
+					Expect(err).To(HaveOccurred())
+
+					Expect(statusCode).To(Equal(http.StatusRequestEntityTooLarge))
+				})
+			})
+		})
+	})
+
+	Describe("deploying with other archives in the request body", func() {
+		for _, contentType := range []string{"application/x-tar", "application/gzip", "application/x-bzip2", "application/java-archive"} {
+			contentType := contentType
+
+			It("accepts "+contentType, func() {
+				statusCode, err := deployer.Deploy(req, environment, org, space, appName, contentType, response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(fetcher.FetchFromZipCall.Received.Request).To(Equal(req))
+			})
+		}
+	})
+
+	Describe("deploying with a multipart request", func() {
+		var (
+			multipartBody   *bytes.Buffer
+			multipartWriter *multipart.Writer
+		)
+
+		BeforeEach(func() {
+			multipartBody = &bytes.Buffer{}
+			multipartWriter = multipart.NewWriter(multipartBody)
+		})
+
+		send := func() (int, error) {
+			Expect(multipartWriter.Close()).To(Succeed())
+
+			req, _ = http.NewRequest("POST", "", multipartBody)
+			req.Header.Set("Content-Type", multipartWriter.FormDataContentType())
+
+			return deployer.Deploy(req, environment, org, space, appName, multipartWriter.FormDataContentType(), response)
+		}
+
+		writePart := func(name, contents string) {
+			part, err := multipartWriter.CreateFormField(name)
+			Expect(err).ToNot(HaveOccurred())
+			fmt.Fprint(part, contents)
+		}
+
+		It("deploys the archive with the deployment info", func() {
+			fetcher.FetchArchiveCall.Returns.AppPath = "/tmp/uploaded-app"
+			fetcher.FetchArchiveCall.Returns.Checksums = S.Checksums{SHA256: "digest"}
+
+			writePart("deployment_info", fmt.Sprintf(`{
+				"manifest": "%s",
+				"artifact_sha256": "digest",
+				"environment_variables": {"FOO": "bar"},
+				"health_check_endpoint": "/health",
+				"data": {"team": "dinosaurs"}
+			}`, base64.StdEncoding.EncodeToString([]byte(manifest))))
+			writePart("artifact", "archive contents")
+
+			statusCode, err := send()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statusCode).To(Equal(http.StatusOK))
+
+			Expect(fetcher.FetchArchiveCall.Received.Archive).To(BeEquivalentTo("archive contents"))
+			Expect(fetcher.FetchArchiveCall.Received.Manifest).To(Equal(manifest))
+			Expect(fetcher.FetchArchiveCall.Received.Checksums).To(Equal(S.Checksums{SHA256: "digest"}))
+
+			pushed := blueGreener.PushCall.Received.DeploymentInfo
+			Expect(pushed.ArtifactURL).To(Equal("/tmp/uploaded-app"))
+			Expect(pushed.AppPath).To(Equal("/tmp/uploaded-app"))
+			Expect(pushed.ArtifactSHA256).To(Equal("digest"))
+			Expect(pushed.EnvironmentVariables).To(Equal(map[string]string{"FOO": "bar"}))
+			Expect(pushed.HealthCheckEndpoint).To(Equal("/health"))
+			Expect(pushed.Data).To(Equal(map[string]interface{}{"team": "dinosaurs"}))
+		})
+
+		It("returns an error when the artifact part is missing", func() {
+			writePart("deployment_info", "{}")
+
+			statusCode, err := send()
+
+			Expect(err).To(MatchError(MissingPartError{"artifact"}))
+			Expect(statusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("returns an error when the artifact part comes before the deployment info", func() {
+			writePart("artifact", "archive contents")
+			writePart("deployment_info", "{}")
+
+			statusCode, err := send()
+
+			Expect(err).To(MatchError(MissingPartError{"deployment_info"}))
+			Expect(statusCode).To(Equal(http.StatusBadRequest))
+			Expect(fetcher.FetchArchiveCall.Received.Archive).To(BeNil())
+		})
+
+		It("returns an error when the deployment info is not JSON", func() {
+			writePart("deployment_info", "not json")
+			writePart("artifact", "archive contents")
+
+			statusCode, err := send()
+
+			Expect(err).To(BeAssignableToTypeOf(MultipartError{}))
+			Expect(statusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("returns an error when the archive cannot be fetched", func() {
+			fetcher.FetchArchiveCall.Returns.Error = errors.New("fetcher error")
+
+			writePart("deployment_info", "{}")
+			writePart("artifact", "archive contents")
+
+			statusCode, err := send()
+
+			Expect(err).To(MatchError("fetcher error"))
+			Expect(statusCode).To(Equal(http.StatusInternalServerError))
+		})
+	})
+
+	Describe("deploying to an environment that requires signed artifacts", func() {
+		BeforeEach(func() {
+			environments[environment] = config.Environment{RequireSignedArtifacts: true}
+		})
+
+		It("passes the signature to the fetcher", func() {
+			requestBody = bytes.NewBufferString(fmt.Sprintf(`{
+					"artifact_url": "%s",
+					"artifact_signature": "signature",
+					"artifact_signature_url": "https://example.com/artifact.jar.sig"
+				}`,
+				artifactURL,
+			))
+
+			req, _ = http.NewRequest("POST", "", requestBody)
+
+			deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+
+			Expect(fetcher.FetchCall.Received.Signature).To(Equal(S.ArtifactSignature{Signature: "signature", URL: "https://example.com/artifact.jar.sig"}))
+			Expect(fetcher.FetchCall.Received.Environment.RequireSignedArtifacts).To(BeTrue())
+		})
+
+		It("rejects archives in the request body", func() {
+			statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/zip", response)
+
+			Expect(err).To(MatchError(SignedArtifactRequiredError{environment}))
+			Expect(statusCode).To(Equal(http.StatusBadRequest))
+			Expect(fetcher.FetchFromZipCall.Received.Request).To(BeNil())
 		})
 	})
 
@@ -350,7 +670,7 @@ applications:
 
 		Context("when a manifest is not provided", func() {
 			It("uses the instances declared in the deployadactyl config", func() {
-				deployer.Config.Environments[environment] = config.Environment{Instances: 303}
+				environments[environment] = config.Environment{Instances: 303}
 
 				deployer.Deploy(req, environment, org, space, appName, "application/json", response)
 
@@ -359,6 +679,52 @@ applications:
 		})
 	})
 
+	Describe("deployment strategy", func() {
+		It("uses the strategy declared in the deployadactyl config", func() {
+			environments[environment] = config.Environment{Strategy: "rolling"}
+
+			deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.Strategy).To(Equal("rolling"))
+		})
+	})
+
+	Describe("artifact checksums", func() {
+		It("gives the expected checksums to the fetcher", func() {
+			requestBody = bytes.NewBufferString(fmt.Sprintf(`{
+					"artifact_url": "%s",
+					"artifact_sha256": "expected-sha256",
+					"artifact_sha1": "expected-sha1",
+					"artifact_md5": "expected-md5"
+				}`,
+				artifactURL,
+			))
+			req, _ = http.NewRequest("POST", "", requestBody)
+
+			deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+
+			Expect(fetcher.FetchCall.Received.Checksums).To(Equal(S.Checksums{
+				SHA256: "expected-sha256",
+				SHA1:   "expected-sha1",
+				MD5:    "expected-md5",
+			}))
+		})
+
+		It("reports the checksums of the artifact in the response and the deployment info", func() {
+			fetcher.FetchCall.Returns.AppPath = appPath
+			fetcher.FetchCall.Returns.Checksums = S.Checksums{SHA256: "actual-sha256", SHA1: "actual-sha1", MD5: "actual-md5"}
+
+			statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statusCode).To(Equal(http.StatusOK))
+
+			Expect(response.String()).To(ContainSubstring("Artifact SHA256: actual-sha256"))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.ArtifactSHA256).To(Equal("actual-sha256"))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.ArtifactSHA1).To(Equal("actual-sha1"))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.ArtifactMD5).To(Equal("actual-md5"))
+		})
+	})
+
 	Describe("not finding an environment in the config", func() {
 		It("returns an error and an http.StatusInternalServerError", func() {
 			statusCode, err := deployer.Deploy(req, "doesnt_exist", org, space, appName, "application/json", response)
@@ -369,6 +735,28 @@ applications:
 		})
 	})
 
+	Describe("reading the config", func() {
+		It("reads the config from the provider once for each deploy", func() {
+			deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+
+			Expect(configProvider.ConfigCall.TimesCalled).To(Equal(1))
+		})
+
+		It("deploys to environments that are added when the config is reloaded", func() {
+			configProvider.ConfigCall.Returns.Config = config.Config{
+				Environments: map[string]config.Environment{
+					"reloaded": {Name: "reloaded", Foundations: foundations},
+				},
+			}
+
+			statusCode, err := deployer.Deploy(req, "reloaded", org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(prechecker.AssertAllFoundationsUpCall.Received.Environment.Name).To(Equal("reloaded"))
+		})
+	})
+
 	Describe("deployment output", func() {
 		It("shows the user deployment info properties", func() {
 			statusCode, _ := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
@@ -516,7 +904,7 @@ applications:
 		It("deletes the unzipped folder from the fetcher", func() {
 			af = &afero.Afero{Fs: afero.NewMemMapFs()}
 			deployer = Deployer{
-				c,
+				configProvider,
 				blueGreener,
 				fetcher,
 				prechecker,
@@ -553,8 +941,9 @@ applications:
 				Expect(response.String()).To(ContainSubstring("Deployment Parameters"))
 				Expect(response.String()).To(ContainSubstring("deploy was successful"))
 
-				Eventually(logBuffer).Should(Say("prechecking the foundations"))
 				Eventually(logBuffer).Should(Say("checking for basic auth"))
+				Eventually(logBuffer).Should(Say("checking the policy of the environment"))
+				Eventually(logBuffer).Should(Say("prechecking the foundations"))
 				Eventually(logBuffer).Should(Say("deploying from json request"))
 				Eventually(logBuffer).Should(Say("building deploymentInfo"))
 				Eventually(logBuffer).Should(Say("Deployment Parameters"))
@@ -590,9 +979,10 @@ applications:
 				Expect(response.String()).To(ContainSubstring("Deployment Parameters"))
 				Expect(response.String()).To(ContainSubstring("deploy was successful"))
 
-				Eventually(logBuffer).Should(Say("prechecking the foundations"))
 				Eventually(logBuffer).Should(Say("checking for basic auth"))
-				Eventually(logBuffer).Should(Say("deploying from zip request"))
+				Eventually(logBuffer).Should(Say("checking the policy of the environment"))
+				Eventually(logBuffer).Should(Say("prechecking the foundations"))
+				Eventually(logBuffer).Should(Say("deploying from application/zip request"))
 				Eventually(logBuffer).Should(Say("Deployment Parameters"))
 				Eventually(logBuffer).Should(Say("emitting a " + C.DeployStartEvent + " event"))
 				Eventually(logBuffer).Should(Say("emitting a " + C.DeploySuccessEvent + " event"))
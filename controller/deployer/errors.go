@@ -19,7 +19,7 @@ func (e ManifestError) Error() string {
 type InvalidContentTypeError struct{}
 
 func (e InvalidContentTypeError) Error() string {
-	return "must be application/json or application/zip"
+	return "must be application/json, multipart/form-data or an archive such as application/zip, application/x-tar, application/gzip or application/x-bzip2"
 }
 
 type EventError struct {
@@ -38,3 +38,27 @@ type EnvironmentNotFoundError struct {
 func (e EnvironmentNotFoundError) Error() string {
 	return fmt.Sprintf("environment not found: %s", e.Environment)
 }
+
+type SignedArtifactRequiredError struct {
+	Environment string
+}
+
+func (e SignedArtifactRequiredError) Error() string {
+	return fmt.Sprintf("environment %s requires signed artifacts: deploy with an artifact_signature or artifact_signature_url", e.Environment)
+}
+
+type MultipartError struct {
+	Err error
+}
+
+func (e MultipartError) Error() string {
+	return fmt.Sprintf("cannot read multipart request: %s", e.Err)
+}
+
+type MissingPartError struct {
+	Name string
+}
+
+func (e MissingPartError) Error() string {
+	return fmt.Sprintf("multipart request is missing the %s part: send a deployment_info part followed by an artifact part", e.Name)
+}
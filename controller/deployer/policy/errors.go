@@ -0,0 +1,16 @@
+package policy
+
+import "fmt"
+
+// ViolationError is returned when the policy of an environment does not allow a deployment.
+// Policy is the key of the policy in the config, such as allowed_orgs or max_instances.
+// It is written to the response, so it is only returned to requests that are authenticated.
+type ViolationError struct {
+	Environment string
+	Policy      string
+	Reason      string
+}
+
+func (e ViolationError) Error() string {
+	return fmt.Sprintf("deployment to %s is not allowed by the %s policy: %s", e.Environment, e.Policy, e.Reason)
+}
@@ -0,0 +1,76 @@
+// Package policy checks that a deployment is allowed by the policy of its environment.
+package policy
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/compozed/deployadactyl/config"
+)
+
+// Check returns a ViolationError when the policy of the environment does not allow deploying
+// to the org and space at now.
+func Check(environment config.Environment, org, space string, now time.Time) error {
+	policy := environment.Policy
+
+	if !matchesAny(policy.AllowedOrgs, org) {
+		return ViolationError{environment.Name, "allowed_orgs", fmt.Sprintf("org %s is not allowed", org)}
+	}
+
+	if !matchesAny(policy.AllowedSpaces, space) {
+		return ViolationError{environment.Name, "allowed_spaces", fmt.Sprintf("space %s is not allowed", space)}
+	}
+
+	now = policy.In(now)
+
+	for _, freeze := range policy.Freezes {
+		if freeze.Active(now) {
+			reason := fmt.Sprintf("deployments are frozen from %s until %s", freeze.Start, freeze.End)
+			if freeze.Reason != "" {
+				reason += ": " + freeze.Reason
+			}
+			return ViolationError{environment.Name, "freezes", reason}
+		}
+	}
+
+	if len(policy.DeployWindows) > 0 && !inDeployWindow(policy.DeployWindows, now) {
+		return ViolationError{environment.Name, "deploy_windows", fmt.Sprintf("%s is outside of the deploy windows", now.Format("Mon 15:04 MST"))}
+	}
+
+	return nil
+}
+
+// CheckInstances returns a ViolationError when a deployment has more instances than the policy
+// of the environment allows.
+func CheckInstances(environment config.Environment, instances uint16) error {
+	maxInstances := environment.Policy.MaxInstances
+	if maxInstances > 0 && instances > maxInstances {
+		return ViolationError{environment.Name, "max_instances", fmt.Sprintf("%d instances is more than %d", instances, maxInstances)}
+	}
+
+	return nil
+}
+
+// matchesAny reports whether value matches one of the globs. Every value matches when there are no globs.
+func matchesAny(globs []string, value string) bool {
+	if len(globs) == 0 {
+		return true
+	}
+
+	for _, glob := range globs {
+		if matched, _ := path.Match(glob, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func inDeployWindow(windows []config.DeployWindow, now time.Time) bool {
+	for _, window := range windows {
+		if window.Active(now) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package policy_test
+
+import (
+	"time"
+
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/controller/deployer/policy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Policy", func() {
+	var (
+		environment config.Environment
+		monday      time.Time
+	)
+
+	BeforeEach(func() {
+		environment = config.Environment{Name: "production"}
+		monday = time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	})
+
+	Describe("Check", func() {
+		It("allows every deployment when there is no policy", func() {
+			Expect(Check(environment, "any-org", "any-space", monday)).To(Succeed())
+		})
+
+		Context("when orgs and spaces are allowed", func() {
+			BeforeEach(func() {
+				environment.Policy.AllowedOrgs = []string{"team-*", "platform"}
+				environment.Policy.AllowedSpaces = []string{"prod"}
+			})
+
+			It("allows orgs and spaces that match", func() {
+				Expect(Check(environment, "team-a", "prod", monday)).To(Succeed())
+				Expect(Check(environment, "platform", "prod", monday)).To(Succeed())
+			})
+
+			It("rejects an org that does not match", func() {
+				err := Check(environment, "other", "prod", monday)
+
+				Expect(err).To(MatchError(ViolationError{"production", "allowed_orgs", "org other is not allowed"}))
+			})
+
+			It("rejects a space that does not match", func() {
+				err := Check(environment, "team-a", "dev", monday)
+
+				Expect(err).To(MatchError(ViolationError{"production", "allowed_spaces", "space dev is not allowed"}))
+			})
+		})
+
+		Context("when there are deploy windows", func() {
+			BeforeEach(func() {
+				environment.Policy.DeployWindows = []config.DeployWindow{
+					{Days: []string{"mon"}, Start: "09:00", End: "17:00", Weekdays: []time.Weekday{time.Monday}, Opens: 9 * time.Hour, Closes: 17 * time.Hour},
+					{Start: "22:00", End: "02:00", Opens: 22 * time.Hour, Closes: 2 * time.Hour},
+				}
+			})
+
+			It("allows deployments in a window", func() {
+				Expect(Check(environment, "org", "space", monday)).To(Succeed())
+				Expect(Check(environment, "org", "space", monday.Add(11*time.Hour))).To(Succeed())
+				Expect(Check(environment, "org", "space", monday.Add(13*time.Hour))).To(Succeed())
+			})
+
+			It("rejects deployments outside of the windows", func() {
+				err := Check(environment, "org", "space", monday.Add(6*time.Hour))
+
+				Expect(err).To(MatchError(ViolationError{"production", "deploy_windows", "Mon 18:00 UTC is outside of the deploy windows"}))
+				Expect(Check(environment, "org", "space", monday.AddDate(0, 0, 1))).ToNot(Succeed())
+			})
+
+			It("uses the timezone of the policy", func() {
+				location := time.FixedZone("CDT", -5*60*60)
+				environment.Policy.Location = location
+
+				Expect(Check(environment, "org", "space", time.Date(2026, time.October, 19, 12, 0, 0, 0, location))).To(Succeed())
+				Expect(Check(environment, "org", "space", time.Date(2026, time.October, 19, 23, 0, 0, 0, time.UTC))).ToNot(Succeed())
+			})
+		})
+
+		Context("when there is a freeze", func() {
+			It("rejects deployments during the freeze", func() {
+				environment.Policy.Freezes = []config.Freeze{{
+					Start:  "2026-10-19",
+					End:    "2026-10-20",
+					Reason: "quarter end",
+					Begins: time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+					Ends:   time.Date(2026, time.October, 21, 0, 0, 0, 0, time.UTC),
+				}}
+
+				err := Check(environment, "org", "space", monday)
+				Expect(err).To(MatchError(ViolationError{"production", "freezes", "deployments are frozen from 2026-10-19 until 2026-10-20: quarter end"}))
+
+				Expect(Check(environment, "org", "space", monday.AddDate(0, 0, 2))).To(Succeed())
+			})
+		})
+	})
+
+	Describe("CheckInstances", func() {
+		It("allows any number of instances when there is no maximum", func() {
+			Expect(CheckInstances(environment, 100)).To(Succeed())
+		})
+
+		It("rejects more instances than the maximum", func() {
+			environment.Policy.MaxInstances = 3
+
+			Expect(CheckInstances(environment, 3)).To(Succeed())
+			Expect(CheckInstances(environment, 4)).To(MatchError(ViolationError{"production", "max_instances", "4 instances is more than 3"}))
+		})
+	})
+})
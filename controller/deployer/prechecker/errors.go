@@ -18,10 +18,20 @@ func (e InvalidGetRequestError) Error() string {
 }
 
 type FoundationUnavailableError struct {
-	FoundationURL string
-	Status        string
+	Foundation string
+	Status     string
 }
 
 func (e FoundationUnavailableError) Error() string {
-	return fmt.Sprintf("deploy aborted: one or more CF foundations unavailable: %s: %s", e.FoundationURL, e.Status)
+	return fmt.Sprintf("deploy aborted: one or more CF foundations unavailable: %s: %s", e.Foundation, e.Status)
+}
+
+type CACertError struct {
+	Foundation string
+	File       string
+	Err        error
+}
+
+func (e CACertError) Error() string {
+	return fmt.Sprintf("cannot read the CA bundle of foundation %s from %s: %s", e.Foundation, e.File, e.Err)
 }
@@ -3,7 +3,10 @@ package prechecker
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"time"
 
@@ -18,6 +21,7 @@ type Prechecker struct {
 }
 
 // AssertAllFoundationsUp will send a request to each Cloud Foundry instance and check that the response status code is 200 OK.
+// The certificate of an instance is only verified when it is configured with a CA bundle.
 func (p Prechecker) AssertAllFoundationsUp(environment config.Environment) error {
 	precheckerEventData := S.PrecheckerEventData{Environment: environment}
 
@@ -29,22 +33,22 @@ func (p Prechecker) AssertAllFoundationsUp(environment config.Environment) error
 		return NoFoundationsConfiguredError{}
 	}
 
-	insecureClient := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
-			ResponseHeaderTimeout: 15 * time.Second,
-		},
-	}
-
 	for _, foundationURL := range environment.Foundations {
-		resp, err := insecureClient.Get(fmt.Sprintf("%s/v2/info", foundationURL))
+		foundation := environment.Foundation(foundationURL)
+
+		client, err := newClient(foundation)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Get(fmt.Sprintf("%s/v2/info", foundationURL))
 		if err != nil {
 			return InvalidGetRequestError{foundationURL, err}
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			err := FoundationUnavailableError{foundationURL, resp.Status}
+			err := FoundationUnavailableError{foundation.Name, resp.Status}
 
 			precheckerEventData.Description = err.Error()
 
@@ -56,3 +60,28 @@ func (p Prechecker) AssertAllFoundationsUp(environment config.Environment) error
 
 	return nil
 }
+
+func newClient(foundation config.Foundation) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+
+	if foundation.CACert != "" {
+		pem, err := ioutil.ReadFile(foundation.CACert)
+		if err != nil {
+			return nil, CACertError{foundation.Name, foundation.CACert, err}
+		}
+
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, CACertError{foundation.Name, foundation.CACert, errors.New("no certificates found")}
+		}
+
+		tlsConfig = &tls.Config{RootCAs: rootCAs}
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:       tlsConfig,
+			ResponseHeaderTimeout: 15 * time.Second,
+		},
+	}, nil
+}
@@ -1,9 +1,12 @@
 package prechecker_test
 
 import (
+	"encoding/pem"
 	"errors"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 
 	"github.com/compozed/deployadactyl/config"
 	. "github.com/compozed/deployadactyl/controller/deployer/prechecker"
@@ -95,6 +98,60 @@ var _ = Describe("Prechecker", func() {
 			})
 		})
 
+		Context("when a foundation has a CA bundle", func() {
+			var (
+				tlsServer *httptest.Server
+				caCert    string
+			)
+
+			BeforeEach(func() {
+				httpStatus = http.StatusOK
+
+				tlsServer = httptest.NewTLSServer(testServer.Config.Handler)
+
+				file, err := ioutil.TempFile("", "deployadactyl-ca-")
+				Expect(err).ToNot(HaveOccurred())
+				defer file.Close()
+
+				caCert = file.Name()
+				Expect(pem.Encode(file, &pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.TLS.Certificates[0].Certificate[0]})).To(Succeed())
+
+				environment.Foundations = []string{tlsServer.URL}
+				environment.FoundationDetails = map[string]config.Foundation{
+					tlsServer.URL: {URL: tlsServer.URL, Name: "east", CACert: caCert},
+				}
+			})
+
+			AfterEach(func() {
+				tlsServer.Close()
+				Expect(os.Remove(caCert)).To(Succeed())
+			})
+
+			It("verifies the certificate of the foundation", func() {
+				Expect(prechecker.AssertAllFoundationsUp(environment)).To(Succeed())
+
+				Expect(foundationURls).To(ConsistOf("/v2/info"))
+			})
+
+			It("returns an error when the CA bundle cannot be read", func() {
+				environment.FoundationDetails[tlsServer.URL] = config.Foundation{URL: tlsServer.URL, Name: "east", CACert: caCert + "-missing"}
+
+				err := prechecker.AssertAllFoundationsUp(environment)
+
+				Expect(err).To(BeAssignableToTypeOf(CACertError{}))
+				Expect(foundationURls).To(BeEmpty())
+			})
+
+			It("names the foundation when it is unavailable", func() {
+				httpStatus = http.StatusServiceUnavailable
+				eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
+
+				err := prechecker.AssertAllFoundationsUp(environment)
+
+				Expect(err).To(MatchError(FoundationUnavailableError{"east", "503 Service Unavailable"}))
+			})
+		})
+
 		Context("when a foundation returns a 500 internal server error", func() {
 			It("returns an error and emits an event", func() {
 				event = S.Event{
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/gin-gonic/gin"
+)
+
+// Environment is an environment in the config as it is returned by the environments endpoints.
+// The credentials of the environment are left out.
+type Environment struct {
+	Name         string       `json:"name"`
+	Domain       string       `json:"domain"`
+	Foundations  []Foundation `json:"foundations"`
+	Authenticate bool         `json:"authenticate"`
+	SkipSSL      bool         `json:"skip_ssl"`
+	Instances    uint16       `json:"instances"`
+	Handlers     []string     `json:"handlers"`
+}
+
+// Foundation is a foundation of an Environment.
+type Foundation struct {
+	URL    string            `json:"url"`
+	Name   string            `json:"name"`
+	Region string            `json:"region,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Environments writes every environment in the config, sorted by name.
+func (c *Controller) Environments(g *gin.Context) {
+	environments := []Environment{}
+	for _, environment := range c.Config.Config().Environments {
+		environments = append(environments, newEnvironment(environment))
+	}
+
+	sort.Sort(byName(environments))
+
+	g.JSON(http.StatusOK, environments)
+}
+
+// Environment writes the environment with the name in the path, or responds 404 when there is none.
+func (c *Controller) Environment(g *gin.Context) {
+	name := g.Param("name")
+
+	environment, ok := c.Config.Config().Environments[strings.ToLower(name)]
+	if !ok {
+		g.JSON(http.StatusNotFound, gin.H{"error": EnvironmentNotFoundError{name}.Error()})
+		return
+	}
+
+	g.JSON(http.StatusOK, newEnvironment(environment))
+}
+
+func newEnvironment(environment config.Environment) Environment {
+	e := Environment{
+		Name:         environment.Name,
+		Domain:       environment.Domain,
+		Foundations:  []Foundation{},
+		Authenticate: environment.Authenticate,
+		SkipSSL:      environment.SkipSSL,
+		Instances:    environment.Instances,
+		Handlers:     []string{},
+	}
+
+	for _, url := range environment.Foundations {
+		foundation := environment.Foundation(url)
+
+		e.Foundations = append(e.Foundations, Foundation{
+			URL:    foundation.URL,
+			Name:   foundation.Name,
+			Region: foundation.Region,
+			Labels: foundation.Labels,
+		})
+	}
+
+	handlers := environment.Handlers
+	if handlers.EnvironmentVariables.Enabled {
+		e.Handlers = append(e.Handlers, "environment_variables")
+	}
+	if handlers.HealthCheck.Enabled {
+		e.Handlers = append(e.Handlers, "health_check")
+	}
+	if handlers.RouteMapper.Enabled {
+		e.Handlers = append(e.Handlers, "route_mapper")
+	}
+
+	return e
+}
+
+type byName []Environment
+
+func (e byName) Len() int           { return len(e) }
+func (e byName) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byName) Less(i, j int) bool { return e[i].Name < e[j].Name }
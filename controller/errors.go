@@ -0,0 +1,11 @@
+package controller
+
+import "fmt"
+
+type EnvironmentNotFoundError struct {
+	Environment string
+}
+
+func (e EnvironmentNotFoundError) Error() string {
+	return fmt.Sprintf("environment not found: %s", e.Environment)
+}
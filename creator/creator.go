@@ -3,17 +3,24 @@ package creator
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"strconv"
 
+	"github.com/compozed/deployadactyl/artifactcache"
 	"github.com/compozed/deployadactyl/artifetcher"
 	"github.com/compozed/deployadactyl/artifetcher/extractor"
+	"github.com/compozed/deployadactyl/artifetcher/source"
+	"github.com/compozed/deployadactyl/certprovider"
 	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/configprovider"
 	"github.com/compozed/deployadactyl/controller"
 	"github.com/compozed/deployadactyl/controller/deployer"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
@@ -21,10 +28,13 @@ import (
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher/courier"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher/courier/executor"
 	"github.com/compozed/deployadactyl/controller/deployer/prechecker"
+	"github.com/compozed/deployadactyl/diskspace"
+	"github.com/compozed/deployadactyl/domaincache"
 	"github.com/compozed/deployadactyl/eventmanager"
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/logger"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/sessionpool"
 	S "github.com/compozed/deployadactyl/structs"
 	"github.com/gin-gonic/gin"
 	"github.com/op/go-logging"
@@ -34,22 +44,33 @@ import (
 // ENDPOINT is used by the handler to define the deployment endpoint.
 const ENDPOINT = "/v1/apps/:environment/:org/:space/:appName"
 
+// ENVIRONMENTS_ENDPOINT and ENVIRONMENT_ENDPOINT are used by the handler to define the endpoints that return the environments in the config.
+const (
+	ENVIRONMENTS_ENDPOINT = "/v1/environments"
+	ENVIRONMENT_ENDPOINT  = "/v1/environments/:name"
+)
+
 // Creator has a config, eventManager, logger and writer for creating dependencies.
+// config is the Config the server started with, and settings that are only read at startup
+// come from it. Everything else reads the current Config from the configProvider.
 type Creator struct {
-	config       config.Config
-	eventManager I.EventManager
-	logger       I.Logger
-	writer       io.Writer
-	fileSystem   *afero.Afero
+	config         config.Config
+	eventManager   I.EventManager
+	logger         I.Logger
+	writer         io.Writer
+	fileSystem     *afero.Afero
+	domainCache    I.DomainCache
+	commandBuilder I.CommandBuilder
+	sessionPool    *sessionpool.SessionPool
+	artifactCache  I.ArtifactCache
+	diskChecker    I.DiskChecker
+	configProvider *configprovider.Provider
+	certProvider   *certprovider.Provider
 }
 
 // Default returns a default Creator and an Error.
 func Default() (Creator, error) {
-	cfg, err := config.Default(os.Getenv)
-	if err != nil {
-		return Creator{}, err
-	}
-	return createCreator(logging.DEBUG, cfg)
+	return createCreator(logging.DEBUG, config.DefaultPath)
 }
 
 // Custom returns a custom Creator with an Error.
@@ -59,11 +80,7 @@ func Custom(level string, configFilename string) (Creator, error) {
 		return Creator{}, err
 	}
 
-	cfg, err := config.Custom(os.Getenv, configFilename)
-	if err != nil {
-		return Creator{}, err
-	}
-	return createCreator(l, cfg)
+	return createCreator(l, configFilename)
 }
 
 // CreateControllerHandler returns a gin.Engine that implements http.Handler.
@@ -77,63 +94,156 @@ func (c Creator) CreateControllerHandler() *gin.Engine {
 	r.Use(gin.ErrorLogger())
 
 	r.POST(ENDPOINT, controller.Deploy)
+	r.GET(ENVIRONMENTS_ENDPOINT, controller.Environments)
+	r.GET(ENVIRONMENT_ENDPOINT, controller.Environment)
 
 	return r
 }
 
-// CreateListener creates a listener TCP and listens for all incoming requests.
-func (c Creator) CreateListener() net.Listener {
-	ls, err := net.ListenTCP("tcp", &net.TCPAddr{
-		IP:   net.IPv4(0, 0, 0, 0),
-		Port: c.config.Port,
-		Zone: "",
-	})
+// CreateListener listens for requests on the address of the server and PORT.
+// Requests are served over TLS when the server has a certificate, and clients must present
+// a certificate signed by one of the client CAs when they are configured.
+func (c Creator) CreateListener() (net.Listener, error) {
+	address := net.JoinHostPort(c.config.Server.Address, strconv.Itoa(c.config.Port))
+
+	ls, err := net.Listen("tcp", address)
 	if err != nil {
-		log.Fatal(err)
+		return nil, ListenError{address, err}
+	}
+
+	if c.certProvider == nil {
+		return ls, nil
+	}
+
+	tlsConfig := &tls.Config{GetCertificate: c.certProvider.GetCertificate}
+
+	if c.config.Server.TLS.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(c.config.Server.TLS.ClientCAFile)
+		if err != nil {
+			ls.Close()
+			return nil, ClientCAError{c.config.Server.TLS.ClientCAFile, err}
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			ls.Close()
+			return nil, ClientCAError{c.config.Server.TLS.ClientCAFile, errors.New("no certificates found")}
+		}
+
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tls.NewListener(ls, tlsConfig), nil
+}
+
+// CreateServer returns an http.Server for the controller handler with the timeouts of the server.
+func (c Creator) CreateServer() *http.Server {
+	return &http.Server{
+		Handler:      c.CreateControllerHandler(),
+		ReadTimeout:  c.config.Server.ReadTimeout,
+		WriteTimeout: c.config.Server.WriteTimeout,
+		IdleTimeout:  c.config.Server.IdleTimeout,
 	}
-	return ls
 }
 
 // CreatePusher is used by the BlueGreener.
+// Environments with the rolling strategy get a pusher that uses rolling deployments.
 //
 // Returns a pusher and error.
-func (c Creator) CreatePusher(deploymentInfo S.DeploymentInfo, response io.ReadWriter) (I.Pusher, error) {
-	newCourier, err := c.CreateCourier()
+func (c Creator) CreatePusher(deploymentInfo S.DeploymentInfo, environment config.Environment, response io.ReadWriter) (I.Pusher, error) {
+	newCourier, err := c.CreateCourier(response, deploymentInfo.CACert)
 	if err != nil {
 		return nil, err
 	}
 
-	p := &pusher.Pusher{
+	p := pusher.Pusher{
 		Courier:        newCourier,
 		DeploymentInfo: deploymentInfo,
+		Environment:    environment,
 		EventManager:   c.CreateEventManager(),
 		Response:       response,
 		Log:            c.CreateLogger(),
 	}
 
-	return p, nil
+	if deploymentInfo.Strategy == config.RollingStrategy {
+		return &pusher.RollingPusher{Pusher: p}, nil
+	}
+
+	return &p, nil
 }
 
-// CreateCourier returns a courier with an executor.
-func (c Creator) CreateCourier() (I.Courier, error) {
-	ex, err := executor.New(c.CreateFileSystem())
+// CreateCourier returns a courier with an executor that verifies the certificate of the
+// foundation with the CA bundle in caCertFile, or the CAs of the host when it is empty.
+// Retries of transient failures are reported to the output.
+func (c Creator) CreateCourier(output io.Writer, caCertFile string) (I.Courier, error) {
+	ex, err := c.createExecutor(caCertFile)
 	if err != nil {
 		return nil, err
 	}
 
+	retry := c.CreateConfig().Retry
+
 	return courier.Courier{
 		Executor: ex,
+		Commands: c.commandBuilder,
+		Retry: courier.RetryPolicy{
+			Attempts:        retry.Attempts,
+			Backoff:         retry.Backoff,
+			MaxBackoff:      retry.MaxBackoff,
+			TransientErrors: retry.TransientErrors,
+		},
+		Log:    c.CreateLogger(),
+		Output: output,
 	}, nil
 }
 
+// createExecutor returns an executor that reuses logged in sessions from the session pool.
+// Every executor gets a new session when sessions are not kept.
+func (c Creator) createExecutor(caCertFile string) (I.Executor, error) {
+	if c.sessionPool != nil {
+		return c.sessionPool.Executor(caCertFile), nil
+	}
+
+	return newExecutor(c.CreateFileSystem(), c.config.WorkingDirectory.Path, caCertFile)
+}
+
+func newExecutor(fileSystem *afero.Afero, workingDirectory, caCertFile string) (I.Executor, error) {
+	ex, err := executor.New(fileSystem, workingDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	return ex.WithCACert(caCertFile), nil
+}
+
+// Close cleans up the Cloud Foundry CLI sessions that are kept for later deployments.
+// It is called when Deployadactyl shuts down.
+func (c Creator) Close() {
+	if c.sessionPool != nil {
+		c.sessionPool.Close()
+	}
+}
+
 // CreateLogger returns a Logger.
 func (c Creator) CreateLogger() I.Logger {
 	return c.logger
 }
 
-// CreateConfig returns a Config.
+// CreateConfig returns the current Config.
 func (c Creator) CreateConfig() config.Config {
-	return c.config
+	return c.configProvider.Config()
+}
+
+// CreateCertProvider returns the Provider that reloads the certificate of the server.
+// It is nil when the server does not use TLS.
+func (c Creator) CreateCertProvider() *certprovider.Provider {
+	return c.certProvider
+}
+
+// CreateConfigProvider returns the ConfigProvider that reloads the config file.
+func (c Creator) CreateConfigProvider() *configprovider.Provider {
+	return c.configProvider
 }
 
 // CreateEventManager returns an EventManager.
@@ -146,6 +256,11 @@ func (c Creator) CreateFileSystem() *afero.Afero {
 	return c.fileSystem
 }
 
+// CreateDomainCache returns the DomainCache shared by the event handlers.
+func (c Creator) CreateDomainCache() I.DomainCache {
+	return c.domainCache
+}
+
 // CreateHTTPClient return an http client.
 func (c Creator) CreateHTTPClient() *http.Client {
 	insecureClient := &http.Client{
@@ -160,13 +275,14 @@ func (c Creator) CreateHTTPClient() *http.Client {
 func (c Creator) createController() controller.Controller {
 	return controller.Controller{
 		Deployer: c.createDeployer(),
+		Config:   c.CreateConfigProvider(),
 		Log:      c.CreateLogger(),
 	}
 }
 
 func (c Creator) createDeployer() I.Deployer {
 	return deployer.Deployer{
-		Config:       c.CreateConfig(),
+		Config:       c.CreateConfigProvider(),
 		BlueGreener:  c.createBlueGreener(),
 		Fetcher:      c.createFetcher(),
 		Prechecker:   c.createPrechecker(),
@@ -178,13 +294,26 @@ func (c Creator) createDeployer() I.Deployer {
 }
 
 func (c Creator) createFetcher() I.Fetcher {
+	sourceConfig := c.config.ArtifactSource
+
 	return &artifetcher.Artifetcher{
 		FileSystem: c.CreateFileSystem(),
 		Extractor: &extractor.Extractor{
 			Log:        c.CreateLogger(),
 			FileSystem: c.CreateFileSystem(),
+			MaxBytes:   c.config.WorkingDirectory.MaxExtractedSize,
 		},
-		Log: c.CreateLogger(),
+		Log:     c.CreateLogger(),
+		Cache:   c.artifactCache,
+		Sources: source.Defaults(source.NewClient(sourceConfig.Timeout), c.CreateFileSystem(), sourceConfig.FileRoot),
+		Retry: artifetcher.RetryPolicy{
+			Attempts:   sourceConfig.Attempts,
+			Backoff:    sourceConfig.Backoff,
+			MaxBackoff: sourceConfig.MaxBackoff,
+		},
+		WorkingDirectory: c.config.WorkingDirectory.Path,
+		MaxArtifactSize:  c.config.WorkingDirectory.MaxArtifactSize,
+		Disk:             c.diskChecker,
 	}
 }
 
@@ -209,30 +338,107 @@ func (c Creator) createBlueGreener() I.BlueGreener {
 	}
 }
 
-func createCreator(l logging.Level, cfg config.Config) (Creator, error) {
-	err := ensureCLI()
+func createCreator(l logging.Level, configFilename string) (Creator, error) {
+	logger := logger.DefaultLogger(os.Stdout, l, "controller")
+
+	configProvider, err := configprovider.New(configFilename, os.Getenv, nil, logger)
 	if err != nil {
 		return Creator{}, err
 	}
+	cfg := configProvider.Config()
+
+	err = ensureCLI()
+	if err != nil {
+		return Creator{}, err
+	}
+
+	commandBuilder, err := createCommandBuilder()
+	if err != nil {
+		return Creator{}, err
+	}
+
+	err = validateStrategies(cfg, commandBuilder)
+	if err != nil {
+		return Creator{}, err
+	}
+	configProvider.Validate = func(cfg config.Config) error {
+		return validateStrategies(cfg, commandBuilder)
+	}
 
-	logger := logger.DefaultLogger(os.Stdout, l, "controller")
 	eventManager := eventmanager.NewEventManager(logger)
+	fileSystem := &afero.Afero{Fs: afero.NewOsFs()}
+
+	var sessionPool *sessionpool.SessionPool
+	if cfg.SessionTTL > 0 {
+		sessionPool = sessionpool.New(cfg.SessionTTL, func(caCertFile string) (I.Executor, error) {
+			return newExecutor(fileSystem, cfg.WorkingDirectory.Path, caCertFile)
+		}, logger)
+	}
+
+	var artifactCache I.ArtifactCache
+	if cfg.ArtifactCache.Directory != "" {
+		artifactCache, err = artifactcache.New(cfg.ArtifactCache.Directory, cfg.ArtifactCache.MaxSize, fileSystem, logger)
+		if err != nil {
+			return Creator{}, err
+		}
+	}
+
+	diskChecker, err := diskspace.New(cfg.WorkingDirectory.Path, cfg.WorkingDirectory.MinFreeSpace, fileSystem, logger)
+	if err != nil {
+		return Creator{}, err
+	}
+
+	var certProvider *certprovider.Provider
+	if cfg.Server.TLS.Enabled() {
+		certProvider, err = certprovider.New(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile, logger)
+		if err != nil {
+			return Creator{}, err
+		}
+	}
 
 	return Creator{
 		cfg,
 		eventManager,
 		logger,
 		os.Stdout,
-		&afero.Afero{Fs: afero.NewOsFs()},
+		fileSystem,
+		domaincache.New(cfg.DomainCacheTTL, logger),
+		commandBuilder,
+		sessionPool,
+		artifactCache,
+		diskChecker,
+		configProvider,
+		certProvider,
 	}, nil
 
 }
 
+// validateStrategies rejects configs with rolling environments when the cf cli cannot do rolling deployments.
+func validateStrategies(cfg config.Config, commandBuilder I.CommandBuilder) error {
+	for _, environment := range cfg.Environments {
+		if environment.Strategy == config.RollingStrategy && !commandBuilder.SupportsRollingDeployments() {
+			return courier.RollingDeploymentsNotSupportedError{}
+		}
+	}
+	return nil
+}
+
 func ensureCLI() error {
 	_, err := exec.LookPath("cf")
 	return err
 }
 
+// createCommandBuilder detects the version of the installed cf cli so the courier
+// builds commands the cli understands. Unsupported versions fail at startup.
+func createCommandBuilder() (I.CommandBuilder, error) {
+	version, err := executor.DetectVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	return courier.NewCommandBuilder(version)
+}
+
 func getLevel(level string) (logging.Level, error) {
 	if level != "" {
 		l, err := logging.LogLevel(level)
@@ -0,0 +1,13 @@
+package creator_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCreator(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Creator Suite")
+}
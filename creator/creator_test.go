@@ -0,0 +1,230 @@
+package creator_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/compozed/deployadactyl/artifetcher"
+	"github.com/compozed/deployadactyl/artifetcher/extractor"
+	"github.com/compozed/deployadactyl/certprovider"
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/creator"
+	"github.com/compozed/deployadactyl/logger"
+	logging "github.com/op/go-logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Creator", func() {
+	Describe("CreateListener", func() {
+		var (
+			directory string
+			certFile  string
+			keyFile   string
+			cfg       config.Config
+			listener  net.Listener
+		)
+
+		// writeCertificate writes a self-signed certificate for 127.0.0.1 and its key to
+		// certFile and keyFile, and returns the certificate.
+		writeCertificate := func(commonName, certFile, keyFile string) tls.Certificate {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			template := &x509.Certificate{
+				SerialNumber: big.NewInt(time.Now().UnixNano()),
+				Subject:      pkix.Name{CommonName: commonName},
+				IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+				NotBefore:    time.Now().Add(-time.Hour),
+				NotAfter:     time.Now().Add(time.Hour),
+			}
+
+			certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+			Expect(err).ToNot(HaveOccurred())
+
+			keyBytes, err := x509.MarshalECPrivateKey(key)
+			Expect(err).ToNot(HaveOccurred())
+
+			certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})
+			keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+			Expect(ioutil.WriteFile(certFile, certPEM, 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(keyFile, keyPEM, 0600)).To(Succeed())
+
+			pair, err := tls.X509KeyPair(certPEM, keyPEM)
+			Expect(err).ToNot(HaveOccurred())
+			return pair
+		}
+
+		// serve answers every request on the listener with the common name of the client certificate, if any.
+		serve := func(listener net.Listener) {
+			server := &http.Server{
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+						fmt.Fprint(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+					}
+				}),
+				ErrorLog: log.New(GinkgoWriter, "", 0),
+			}
+			go server.Serve(listener)
+		}
+
+		client := func(trusted *tls.Certificate, certificates ...tls.Certificate) *http.Client {
+			tlsConfig := &tls.Config{Certificates: certificates}
+			if trusted != nil {
+				leaf, err := x509.ParseCertificate(trusted.Certificate[0])
+				Expect(err).ToNot(HaveOccurred())
+
+				tlsConfig.RootCAs = x509.NewCertPool()
+				tlsConfig.RootCAs.AddCert(leaf)
+			}
+
+			return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true}}
+		}
+
+		newCertProvider := func() *certprovider.Provider {
+			certProvider, err := certprovider.New(certFile, keyFile, logger.DefaultLogger(gbytes.NewBuffer(), logging.DEBUG, "creator_test"))
+			Expect(err).ToNot(HaveOccurred())
+			return certProvider
+		}
+
+		BeforeEach(func() {
+			var err error
+			directory, err = ioutil.TempDir("", "deployadactyl-creator-")
+			Expect(err).ToNot(HaveOccurred())
+
+			certFile = path.Join(directory, "server.crt")
+			keyFile = path.Join(directory, "server.key")
+
+			cfg = config.Config{Port: 0, Server: config.Server{Address: "127.0.0.1"}}
+		})
+
+		AfterEach(func() {
+			if listener != nil {
+				listener.Close()
+				listener = nil
+			}
+			os.RemoveAll(directory)
+		})
+
+		It("listens without tls when the server has no certificate", func() {
+			var err error
+			listener, err = NewListenerCreator(cfg, nil).CreateListener()
+			Expect(err).ToNot(HaveOccurred())
+			serve(listener)
+
+			response, err := http.Get("http://" + listener.Addr().String())
+			Expect(err).ToNot(HaveOccurred())
+			response.Body.Close()
+
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("returns an error when it cannot listen on the address", func() {
+			cfg.Server.Address = "256.0.0.1"
+
+			_, err := NewListenerCreator(cfg, nil).CreateListener()
+
+			Expect(err).To(BeAssignableToTypeOf(ListenError{}))
+		})
+
+		It("serves the certificate the certificate provider has reloaded", func() {
+			first := writeCertificate("first", certFile, keyFile)
+			certProvider := newCertProvider()
+			cfg.Server.TLS = config.ServerTLS{CertFile: certFile, KeyFile: keyFile}
+
+			var err error
+			listener, err = NewListenerCreator(cfg, certProvider).CreateListener()
+			Expect(err).ToNot(HaveOccurred())
+			serve(listener)
+
+			response, err := client(&first).Get("https://" + listener.Addr().String())
+			Expect(err).ToNot(HaveOccurred())
+			response.Body.Close()
+
+			second := writeCertificate("second", certFile, keyFile)
+			Expect(certProvider.Reload()).To(Succeed())
+
+			response, err = client(&second).Get("https://" + listener.Addr().String())
+			Expect(err).ToNot(HaveOccurred())
+			response.Body.Close()
+			Expect(response.TLS.PeerCertificates[0].Subject.CommonName).To(Equal("second"))
+
+			_, err = client(&first).Get("https://" + listener.Addr().String())
+			Expect(err).To(HaveOccurred())
+		})
+
+		Context("when the server has client CAs", func() {
+			var (
+				server       tls.Certificate
+				clientCert   tls.Certificate
+				clientCAFile string
+			)
+
+			BeforeEach(func() {
+				server = writeCertificate("server", certFile, keyFile)
+
+				clientCAFile = path.Join(directory, "client.crt")
+				clientCert = writeCertificate("client", clientCAFile, path.Join(directory, "client.key"))
+
+				cfg.Server.TLS = config.ServerTLS{CertFile: certFile, KeyFile: keyFile, ClientCAFile: clientCAFile}
+
+				var err error
+				listener, err = NewListenerCreator(cfg, newCertProvider()).CreateListener()
+				Expect(err).ToNot(HaveOccurred())
+				serve(listener)
+			})
+
+			It("rejects clients without a certificate", func() {
+				_, err := client(&server).Get("https://" + listener.Addr().String())
+
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("accepts clients with a certificate signed by a client CA", func() {
+				response, err := client(&server, clientCert).Get("https://" + listener.Addr().String())
+				Expect(err).ToNot(HaveOccurred())
+				defer response.Body.Close()
+
+				body, err := ioutil.ReadAll(response.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(body)).To(Equal("client"))
+			})
+		})
+
+		It("returns an error when the client CAs have no certificates", func() {
+			writeCertificate("server", certFile, keyFile)
+			Expect(ioutil.WriteFile(path.Join(directory, "clients.pem"), []byte("not a certificate"), 0644)).To(Succeed())
+			cfg.Server.TLS = config.ServerTLS{CertFile: certFile, KeyFile: keyFile, ClientCAFile: path.Join(directory, "clients.pem")}
+
+			_, err := NewListenerCreator(cfg, newCertProvider()).CreateListener()
+
+			Expect(err).To(BeAssignableToTypeOf(ClientCAError{}))
+		})
+	})
+
+	Describe("CreateFetcher", func() {
+		It("limits the size of extracted artifacts", func() {
+			cfg := config.Config{WorkingDirectory: config.WorkingDirectory{MaxExtractedSize: 300 * 1024 * 1024}}
+
+			fetcher := NewFetcherCreator(cfg).CreateFetcher().(*artifetcher.Artifetcher)
+
+			Expect(fetcher.Extractor.(*extractor.Extractor).MaxBytes).To(Equal(int64(300 * 1024 * 1024)))
+		})
+	})
+})
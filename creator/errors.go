@@ -0,0 +1,21 @@
+package creator
+
+import "fmt"
+
+type ListenError struct {
+	Address string
+	Err     error
+}
+
+func (e ListenError) Error() string {
+	return fmt.Sprintf("cannot listen on %s: %s", e.Address, e.Err)
+}
+
+type ClientCAError struct {
+	File string
+	Err  error
+}
+
+func (e ClientCAError) Error() string {
+	return fmt.Sprintf("cannot read client CAs from %s: %s", e.File, e.Err)
+}
@@ -0,0 +1,23 @@
+package creator
+
+import (
+	"github.com/compozed/deployadactyl/certprovider"
+	"github.com/compozed/deployadactyl/config"
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// NewListenerCreator returns a Creator with only the config and certificate provider
+// that CreateListener uses.
+func NewListenerCreator(cfg config.Config, certProvider *certprovider.Provider) Creator {
+	return Creator{config: cfg, certProvider: certProvider}
+}
+
+// NewFetcherCreator returns a Creator with only the config that createFetcher uses.
+func NewFetcherCreator(cfg config.Config) Creator {
+	return Creator{config: cfg}
+}
+
+// CreateFetcher returns the Fetcher that the deployer uses.
+func (c Creator) CreateFetcher() I.Fetcher {
+	return c.createFetcher()
+}
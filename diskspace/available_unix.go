@@ -0,0 +1,17 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package diskspace
+
+import "syscall"
+
+func available(directory string) (int64, error) {
+	var stat syscall.Statfs_t
+
+	err := syscall.Statfs(directory, &stat)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
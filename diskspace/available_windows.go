@@ -0,0 +1,23 @@
+package diskspace
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+func available(directory string) (int64, error) {
+	name, err := syscall.UTF16PtrFromString(directory)
+	if err != nil {
+		return 0, err
+	}
+
+	var free int64
+	result, _, err := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&free)), 0, 0)
+	if result == 0 {
+		return 0, err
+	}
+
+	return free, nil
+}
@@ -0,0 +1,59 @@
+// Package diskspace checks that the working directory has enough free space for a deploy.
+package diskspace
+
+import (
+	"os"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/spf13/afero"
+)
+
+// Checker rejects deploys that would leave less than MinFree bytes in Directory.
+// Available returns the number of bytes that can be written to a directory.
+type Checker struct {
+	Directory string
+	MinFree   int64
+	Available func(directory string) (int64, error)
+	Log       I.Logger
+}
+
+// New returns a Checker for the directory, creating the directory if it does not exist.
+// The OS temp dir is checked when the directory is empty.
+func New(directory string, minFree int64, fileSystem *afero.Afero, log I.Logger) (*Checker, error) {
+	if directory == "" {
+		directory = os.TempDir()
+	}
+
+	err := fileSystem.MkdirAll(directory, 0700)
+	if err != nil {
+		return nil, CreateDirectoryError{directory, err}
+	}
+
+	return &Checker{
+		Directory: directory,
+		MinFree:   minFree,
+		Available: available,
+		Log:       log,
+	}, nil
+}
+
+// Check returns an InsufficientDiskSpaceError when writing required bytes would leave less
+// than MinFree bytes in the directory. Platforms that cannot report free space are not checked.
+func (c *Checker) Check(required int64) error {
+	free, err := c.Available(c.Directory)
+	if err == errUnsupported {
+		c.Log.Debugf("cannot check free space in %s on this platform", c.Directory)
+		return nil
+	}
+	if err != nil {
+		return StatDirectoryError{c.Directory, err}
+	}
+
+	c.Log.Debugf("%d bytes free in %s, %d bytes required", free, c.Directory, required+c.MinFree)
+
+	if free-required < c.MinFree {
+		return InsufficientDiskSpaceError{c.Directory, required + c.MinFree, free}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package diskspace_test
+
+import (
+	"errors"
+	"os"
+
+	. "github.com/compozed/deployadactyl/diskspace"
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/randomizer"
+	logging "github.com/op/go-logging"
+	"github.com/spf13/afero"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Diskspace", func() {
+	var (
+		directory string
+		af        *afero.Afero
+		checker   *Checker
+		free      int64
+		statErr   error
+	)
+
+	BeforeEach(func() {
+		directory = "/work-" + randomizer.StringRunes(10)
+		af = &afero.Afero{Fs: afero.NewMemMapFs()}
+		free = 100
+		statErr = nil
+
+		var err error
+		checker, err = New(directory, 10, af, logger.DefaultLogger(NewBuffer(), logging.DEBUG, "diskspace_test"))
+		Expect(err).ToNot(HaveOccurred())
+
+		checker.Available = func(dir string) (int64, error) {
+			Expect(dir).To(Equal(directory))
+			return free, statErr
+		}
+	})
+
+	It("creates the working directory", func() {
+		info, err := af.Stat(directory)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.IsDir()).To(BeTrue())
+	})
+
+	It("uses the OS temp dir when there is no working directory", func() {
+		checker, err := New("", 10, af, logger.DefaultLogger(NewBuffer(), logging.DEBUG, "diskspace_test"))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(checker.Directory).To(Equal(os.TempDir()))
+	})
+
+	It("allows writes that leave the minimum free space", func() {
+		Expect(checker.Check(90)).To(Succeed())
+	})
+
+	It("rejects writes that would leave less than the minimum free space", func() {
+		err := checker.Check(91)
+
+		Expect(err).To(MatchError(InsufficientDiskSpaceError{directory, 101, 100}))
+		Expect(err.Error()).To(ContainSubstring("not enough free space in working directory " + directory))
+	})
+
+	It("rejects deploys when the directory already has less than the minimum free space", func() {
+		free = 5
+
+		Expect(checker.Check(0)).To(MatchError(InsufficientDiskSpaceError{directory, 10, 5}))
+	})
+
+	It("returns an error when the free space cannot be read", func() {
+		statErr = errors.New("stat failed")
+
+		Expect(checker.Check(0)).To(MatchError(StatDirectoryError{directory, statErr}))
+	})
+})
@@ -0,0 +1,40 @@
+package diskspace
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errUnsupported = errors.New("free space cannot be checked on this platform")
+
+type CreateDirectoryError struct {
+	Directory string
+	Err       error
+}
+
+func (e CreateDirectoryError) Error() string {
+	return fmt.Sprintf("cannot create working directory %s: %s", e.Directory, e.Err)
+}
+
+type StatDirectoryError struct {
+	Directory string
+	Err       error
+}
+
+func (e StatDirectoryError) Error() string {
+	return fmt.Sprintf("cannot check free space in working directory %s: %s", e.Directory, e.Err)
+}
+
+type InsufficientDiskSpaceError struct {
+	Directory string
+	Required  int64
+	Available int64
+}
+
+func (e InsufficientDiskSpaceError) Error() string {
+	return fmt.Sprintf("not enough free space in working directory %s: %d MB required, %d MB available: try again once other deploys have finished", e.Directory, megabytes(e.Required), megabytes(e.Available))
+}
+
+func megabytes(bytes int64) int64 {
+	return (bytes + 1<<20 - 1) >> 20
+}
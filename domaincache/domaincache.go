@@ -0,0 +1,68 @@
+// Package domaincache caches the domains available in each Cloud Foundry foundation.
+package domaincache
+
+import (
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// DomainCache remembers the domains found in each foundation and org for the length of its TTL
+// so handlers do not have to ask Cloud Foundry for them on every push.
+type DomainCache struct {
+	TTL     time.Duration
+	Log     I.Logger
+	mutex   sync.Mutex
+	entries map[string]entry
+}
+
+type entry struct {
+	domains   []S.Domain
+	expiresAt time.Time
+}
+
+// New returns a DomainCache that keeps domains for the length of the ttl.
+func New(ttl time.Duration, log I.Logger) *DomainCache {
+	return &DomainCache{
+		TTL:     ttl,
+		Log:     log,
+		entries: make(map[string]entry),
+	}
+}
+
+// Domains returns the domains available to an org in a foundation. Domains are looked up with the
+// courier the first time they are asked for and again once the cached domains are older than the TTL.
+// Failed lookups are not cached.
+func (d *DomainCache) Domains(foundationURL, org string, courier I.Courier) ([]S.Domain, error) {
+	key := foundationURL + " " + org
+
+	d.mutex.Lock()
+	cached, found := d.entries[key]
+	d.mutex.Unlock()
+
+	if found && time.Now().Before(cached.expiresAt) {
+		d.Log.Debugf("using cached domains for %s", foundationURL)
+		return copyDomains(cached.domains), nil
+	}
+
+	d.Log.Debugf("looking up domains for %s", foundationURL)
+	domains, err := courier.Domains()
+	if err != nil {
+		d.Log.Errorf("could not look up domains for %s: %s", foundationURL, err)
+		return nil, err
+	}
+
+	d.mutex.Lock()
+	d.entries[key] = entry{domains: domains, expiresAt: time.Now().Add(d.TTL)}
+	d.mutex.Unlock()
+
+	return copyDomains(domains), nil
+}
+
+func copyDomains(domains []S.Domain) []S.Domain {
+	result := make([]S.Domain, len(domains))
+	copy(result, domains)
+	return result
+}
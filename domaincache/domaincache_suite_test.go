@@ -0,0 +1,13 @@
+package domaincache_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDomaincache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Domaincache Suite")
+}
@@ -0,0 +1,101 @@
+package domaincache_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/compozed/deployadactyl/domaincache"
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
+	logging "github.com/op/go-logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("DomainCache", func() {
+	var (
+		randomFoundationURL string
+		randomOrg           string
+		domains             []S.Domain
+
+		courier     *mocks.Courier
+		logBuffer   *Buffer
+		domainCache *DomainCache
+	)
+
+	BeforeEach(func() {
+		randomFoundationURL = "https://api.cf." + randomizer.StringRunes(10) + ".com"
+		randomOrg = "randomOrg-" + randomizer.StringRunes(10)
+		domains = []S.Domain{
+			{Name: "apps." + randomizer.StringRunes(10) + ".com", Type: S.SharedDomain, Protocol: "http"},
+		}
+
+		courier = &mocks.Courier{}
+		courier.DomainsCall.Returns.Domains = domains
+
+		logBuffer = NewBuffer()
+		domainCache = New(time.Minute, logger.DefaultLogger(logBuffer, logging.DEBUG, "domaincache_test"))
+	})
+
+	It("returns the domains from the courier", func() {
+		result, err := domainCache.Domains(randomFoundationURL, randomOrg, courier)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result).To(Equal(domains))
+	})
+
+	It("only asks the courier once for the same foundation and org", func() {
+		domainCache.Domains(randomFoundationURL, randomOrg, courier)
+		domainCache.Domains(randomFoundationURL, randomOrg, courier)
+
+		Expect(courier.DomainsCall.TimesCalled).To(Equal(1))
+		Eventually(logBuffer).Should(Say("looking up domains for %s", randomFoundationURL))
+		Eventually(logBuffer).Should(Say("using cached domains for %s", randomFoundationURL))
+	})
+
+	It("caches the domains of each foundation separately", func() {
+		domainCache.Domains(randomFoundationURL, randomOrg, courier)
+		domainCache.Domains("https://api.cf.other.com", randomOrg, courier)
+
+		Expect(courier.DomainsCall.TimesCalled).To(Equal(2))
+	})
+
+	It("caches the domains of each org separately", func() {
+		domainCache.Domains(randomFoundationURL, randomOrg, courier)
+		domainCache.Domains(randomFoundationURL, "otherOrg", courier)
+
+		Expect(courier.DomainsCall.TimesCalled).To(Equal(2))
+	})
+
+	Context("when the cached domains are older than the TTL", func() {
+		It("looks up the domains again", func() {
+			domainCache.TTL = time.Millisecond
+
+			domainCache.Domains(randomFoundationURL, randomOrg, courier)
+			time.Sleep(5 * time.Millisecond)
+			domainCache.Domains(randomFoundationURL, randomOrg, courier)
+
+			Expect(courier.DomainsCall.TimesCalled).To(Equal(2))
+		})
+	})
+
+	Context("when the courier fails to get the domains", func() {
+		It("returns an error and does not cache the result", func() {
+			courier.DomainsCall.Returns.Error = errors.New("domains error")
+
+			_, err := domainCache.Domains(randomFoundationURL, randomOrg, courier)
+			Expect(err).To(MatchError("domains error"))
+
+			courier.DomainsCall.Returns.Error = nil
+
+			result, err := domainCache.Domains(randomFoundationURL, randomOrg, courier)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(domains))
+			Expect(courier.DomainsCall.TimesCalled).To(Equal(2))
+		})
+	})
+})
@@ -2,21 +2,26 @@
 package eventmanager
 
 import (
+	"github.com/compozed/deployadactyl/config"
 	I "github.com/compozed/deployadactyl/interfaces"
 	S "github.com/compozed/deployadactyl/structs"
 )
 
-// EventManager has handlers for each registered event type.
+// EventManager has handlers for each registered event type. Environment handlers are
+// asked for the handler of the environment that a deployment started with every time one
+// of its events is emitted, so a deployment keeps its handlers when the config is reloaded.
 type EventManager struct {
-	handlers map[string][]I.Handler
-	Log      I.Logger
+	handlers            map[string][]I.Handler
+	environmentHandlers map[string][]I.EnvironmentHandler
+	Log                 I.Logger
 }
 
 // NewEventManager returns an EventManager.
 func NewEventManager(log I.Logger) *EventManager {
 	return &EventManager{
-		handlers: make(map[string][]I.Handler),
-		Log:      log,
+		handlers:            make(map[string][]I.Handler),
+		environmentHandlers: make(map[string][]I.EnvironmentHandler),
+		Log:                 log,
 	}
 }
 
@@ -30,9 +35,24 @@ func (e *EventManager) AddHandler(handler I.Handler, eventType string) error {
 	return nil
 }
 
-// Emit emits an event.
+// AddEnvironmentHandler takes an environment handler and eventType and returns an error if a handler is not provided.
+// The handler it returns for an environment only receives the events of deployments to that environment.
+func (e *EventManager) AddEnvironmentHandler(handler I.EnvironmentHandler, eventType string) error {
+	if handler == nil {
+		return InvalidArgumentError{}
+	}
+	e.environmentHandlers[eventType] = append(e.environmentHandlers[eventType], handler)
+	e.Log.Debugf("environment handler for [%s] event added successfully", eventType)
+	return nil
+}
+
+// Emit emits an event to the handlers of its type, and then to the handlers of its type
+// for the environment of the deployment it belongs to.
 func (e *EventManager) Emit(event S.Event) error {
-	for _, handler := range e.handlers[event.Type] {
+	handlers := append([]I.Handler{}, e.handlers[event.Type]...)
+	handlers = append(handlers, e.handlersOfEnvironment(event)...)
+
+	for _, handler := range handlers {
 		err := handler.OnEvent(event)
 		if err != nil {
 			return err
@@ -41,3 +61,45 @@ func (e *EventManager) Emit(event S.Event) error {
 	}
 	return nil
 }
+
+// handlersOfEnvironment returns the handlers of an event for the environment that the
+// deployment it belongs to started with.
+func (e *EventManager) handlersOfEnvironment(event S.Event) []I.Handler {
+	environmentHandlers := e.environmentHandlers[event.Type]
+	if len(environmentHandlers) == 0 {
+		return nil
+	}
+
+	environment, ok := eventEnvironment(event)
+	if !ok {
+		return nil
+	}
+
+	var handlers []I.Handler
+	for _, environmentHandler := range environmentHandlers {
+		if handler := environmentHandler(environment); handler != nil {
+			handlers = append(handlers, handler)
+		}
+	}
+	return handlers
+}
+
+// eventEnvironment returns the config of the environment that the deployment an event belongs to started with.
+func eventEnvironment(event S.Event) (config.Environment, bool) {
+	var environment config.Environment
+
+	switch data := event.Data.(type) {
+	case S.DeployEventData:
+		environment = data.Environment
+	case *S.DeployEventData:
+		environment = data.Environment
+	case S.PushEventData:
+		environment = data.Environment
+	case *S.PushEventData:
+		environment = data.Environment
+	case S.PrecheckerEventData:
+		environment = data.Environment
+	}
+
+	return environment, environment.Name != ""
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/onsi/gomega/gbytes"
 	"github.com/op/go-logging"
 
+	"github.com/compozed/deployadactyl/config"
 	. "github.com/compozed/deployadactyl/eventmanager"
 	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/logger"
@@ -116,4 +117,90 @@ var _ = Describe("Events", func() {
 			Expect(eventHandlerTwo.OnEventCall.Received.Event).ToNot(Equal(event))
 		})
 	})
+
+	Context("when handlers are registered for environments", func() {
+		var (
+			routeMapper I.EnvironmentHandler
+			production  config.Environment
+		)
+
+		BeforeEach(func() {
+			production = config.Environment{Name: "production", Handlers: config.Handlers{RouteMapper: config.Handler{Enabled: true}}}
+
+			routeMapper = func(environment config.Environment) I.Handler {
+				if !environment.Handlers.RouteMapper.Enabled {
+					return nil
+				}
+				return eventHandlerOne
+			}
+		})
+
+		It("should fail if a nil value is passed in as an argument", func() {
+			err := eventManager.AddEnvironmentHandler(nil, eventType)
+
+			Expect(err).To(MatchError(InvalidArgumentError{}))
+		})
+
+		It("only emits to the handlers of the environment of the deployment", func() {
+			productionEvent := S.Event{Type: eventType, Data: S.DeployEventData{Environment: production}}
+			preproductionEvent := S.Event{Type: eventType, Data: S.DeployEventData{Environment: config.Environment{Name: "preproduction"}}}
+
+			eventManager.AddHandler(eventHandler, eventType)
+			eventManager.AddEnvironmentHandler(routeMapper, eventType)
+
+			Expect(eventManager.Emit(preproductionEvent)).To(Succeed())
+			Expect(eventHandler.OnEventCall.Received.Event).To(Equal(preproductionEvent))
+			Expect(eventHandlerOne.OnEventCall.Received.Event).ToNot(Equal(preproductionEvent))
+
+			Expect(eventManager.Emit(productionEvent)).To(Succeed())
+			Expect(eventHandler.OnEventCall.Received.Event).To(Equal(productionEvent))
+			Expect(eventHandlerOne.OnEventCall.Received.Event).To(Equal(productionEvent))
+		})
+
+		It("emits to the handlers of the environment that the deployment started with", func() {
+			reloaded := production
+			reloaded.Handlers = config.Handlers{HealthCheck: config.HealthCheckHandler{Enabled: true}}
+			startedEvent := S.Event{Type: eventType, Data: S.PushEventData{Environment: production}}
+			reloadedEvent := S.Event{Type: eventType, Data: S.PushEventData{Environment: reloaded}}
+
+			eventManager.AddEnvironmentHandler(routeMapper, eventType)
+			eventManager.AddEnvironmentHandler(func(environment config.Environment) I.Handler {
+				if !environment.Handlers.HealthCheck.Enabled {
+					return nil
+				}
+				return eventHandlerTwo
+			}, eventType)
+
+			Expect(eventManager.Emit(reloadedEvent)).To(Succeed())
+			Expect(eventManager.Emit(startedEvent)).To(Succeed())
+
+			Expect(eventHandlerOne.OnEventCall.Received.Event).To(Equal(startedEvent))
+			Expect(eventHandlerTwo.OnEventCall.Received.Event).To(Equal(reloadedEvent))
+		})
+
+		It("finds the environment of push and prechecker events", func() {
+			pushEvent := S.Event{Type: eventType, Data: &S.PushEventData{Environment: production}}
+			precheckerEvent := S.Event{Type: eventType, Data: S.PrecheckerEventData{Environment: production}}
+
+			eventManager.AddEnvironmentHandler(routeMapper, eventType)
+
+			Expect(eventManager.Emit(pushEvent)).To(Succeed())
+			Expect(eventHandlerOne.OnEventCall.Received.Event).To(Equal(pushEvent))
+
+			Expect(eventManager.Emit(precheckerEvent)).To(Succeed())
+			Expect(eventHandlerOne.OnEventCall.Received.Event).To(Equal(precheckerEvent))
+		})
+
+		It("does not emit events without the config of an environment to them", func() {
+			event := S.Event{Type: eventType, Data: eventData}
+			unknownEvent := S.Event{Type: eventType, Data: S.DeployEventData{DeploymentInfo: &S.DeploymentInfo{Environment: "production"}}}
+
+			eventManager.AddEnvironmentHandler(func(config.Environment) I.Handler { return eventHandler }, eventType)
+
+			Expect(eventManager.Emit(event)).To(Succeed())
+			Expect(eventManager.Emit(unknownEvent)).To(Succeed())
+
+			Expect(eventHandler.OnEventCall.Received.Event).To(Equal(S.Event{}))
+		})
+	})
 })
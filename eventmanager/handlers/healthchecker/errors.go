@@ -42,3 +42,20 @@ type WrongEventTypeError struct {
 func (e WrongEventTypeError) Error() string {
 	return fmt.Sprintf("wrong event type for healthchecker: %s", e.Type)
 }
+
+type DomainsError struct {
+	FoundationURL string
+	Err           error
+}
+
+func (e DomainsError) Error() string {
+	return fmt.Sprintf("could not get the domains in %s: %s", e.FoundationURL, e.Err)
+}
+
+type NoDomainError struct {
+	FoundationURL string
+}
+
+func (e NoDomainError) Error() string {
+	return fmt.Sprintf("could not find a shared domain for the temporary health check route in %s", e.FoundationURL)
+}
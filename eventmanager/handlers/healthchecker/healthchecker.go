@@ -3,6 +3,7 @@ package healthchecker
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 
@@ -22,14 +23,15 @@ type HealthChecker struct {
 	// Eg: "cfapps"
 	NewURL string
 
-	Client  I.Client
-	Courier I.Courier
-	Log     I.Logger
+	Client      I.Client
+	Courier     I.Courier
+	DomainCache I.DomainCache
+	Log         I.Logger
 }
 
 // OnEvent is used for the EventManager to do health checking during deployments.
-// It will create the new application URL by combining the tempAppWithUUID to the
-// domain URL.
+// It will create the new application URL by combining the tempAppWithUUID with the
+// apps domain of the foundation, or a domain from the foundation when it has none.
 func (h HealthChecker) OnEvent(event S.Event) error {
 
 	if event.Type != C.PushFinishedEvent {
@@ -50,19 +52,27 @@ func (h HealthChecker) OnEvent(event S.Event) error {
 		return nil
 	}
 
-	newFoundationURL := strings.Replace(foundationURL, h.OldURL, h.NewURL, 1)
-	domain := regexp.MustCompile(fmt.Sprintf("%s.*", h.NewURL)).FindString(newFoundationURL)
+	domain := deploymentInfo.AppsDomain
+	if domain == "" {
+		var err error
+		domain, err = h.findDomain(foundationURL, deploymentInfo.Org)
+		if err != nil {
+			return err
+		}
+	}
 
 	err := h.mapTemporaryRoute(tempAppWithUUID, domain)
 	if err != nil {
 		return err
 	}
-
 	defer h.unmapTemporaryRoute(tempAppWithUUID, domain)
 
-	newFoundationURL = strings.Replace(newFoundationURL, h.NewURL, fmt.Sprintf("%s.%s", tempAppWithUUID, h.NewURL), 1)
+	scheme := "https"
+	if u, err := url.Parse(foundationURL); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
 
-	return h.Check(newFoundationURL, deploymentInfo.HealthCheckEndpoint)
+	return h.Check(fmt.Sprintf("%s://%s.%s", scheme, tempAppWithUUID, domain), deploymentInfo.HealthCheckEndpoint)
 }
 
 // Check takes a url and endpoint. It does an http.Get to get the response
@@ -87,6 +97,39 @@ func (h HealthChecker) Check(url, endpoint string) error {
 	return nil
 }
 
+// findDomain picks the domain used for the temporary health check route.
+// When OldURL and NewURL are set, the domain made by replacing OldURL with NewURL
+// in the foundation URL is used if it exists in the foundation. Otherwise the first
+// shared, external HTTP domain in the foundation is used.
+func (h HealthChecker) findDomain(foundationURL, org string) (string, error) {
+	domains, err := h.DomainCache.Domains(foundationURL, org, h.Courier)
+	if err != nil {
+		h.Log.Error(DomainsError{foundationURL, err})
+		return "", DomainsError{foundationURL, err}
+	}
+
+	if h.NewURL != "" {
+		newFoundationURL := strings.Replace(foundationURL, h.OldURL, h.NewURL, 1)
+		derivedDomain := regexp.MustCompile(fmt.Sprintf("%s.*", regexp.QuoteMeta(h.NewURL))).FindString(newFoundationURL)
+
+		for _, domain := range domains {
+			if domain.Name == derivedDomain {
+				return domain.Name, nil
+			}
+		}
+		h.Log.Debugf("domain %s not found in %s", derivedDomain, foundationURL)
+	}
+
+	for _, domain := range domains {
+		if domain.Type == S.SharedDomain && !domain.Internal && domain.Protocol == "http" {
+			return domain.Name, nil
+		}
+	}
+
+	h.Log.Error(NoDomainError{foundationURL})
+	return "", NoDomainError{foundationURL}
+}
+
 func (h HealthChecker) mapTemporaryRoute(tempAppWithUUID, domain string) error {
 	h.Log.Debugf("mapping temporary route %s.%s", tempAppWithUUID, domain)
 
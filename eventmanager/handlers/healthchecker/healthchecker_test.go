@@ -34,6 +34,7 @@ var _ = Describe("Healthchecker", func() {
 		healthchecker HealthChecker
 		client        *mocks.Client
 		courier       *mocks.Courier
+		domainCache   *mocks.DomainCache
 		logBuffer     *Buffer
 	)
 
@@ -54,6 +55,11 @@ var _ = Describe("Healthchecker", func() {
 
 		courier = &mocks.Courier{}
 		client = &mocks.Client{}
+		domainCache = &mocks.DomainCache{}
+		domainCache.DomainsCall.Returns.Domains = []S.Domain{
+			{Name: "internal." + s + ".com", Type: S.SharedDomain, Protocol: "http", Internal: true},
+			{Name: randomDomain, Type: S.SharedDomain, Protocol: "http"},
+		}
 
 		event = S.Event{
 			Type: C.PushFinishedEvent,
@@ -73,10 +79,11 @@ var _ = Describe("Healthchecker", func() {
 
 		logBuffer = NewBuffer()
 		healthchecker = HealthChecker{
-			OldURL: "api.cf",
-			NewURL: "apps",
-			Client: client,
-			Log:    logger.DefaultLogger(logBuffer, logging.DEBUG, "healthchecker_test"),
+			OldURL:      "api.cf",
+			NewURL:      "apps",
+			Client:      client,
+			DomainCache: domainCache,
+			Log:         logger.DefaultLogger(logBuffer, logging.DEBUG, "healthchecker_test"),
 		}
 	})
 
@@ -162,6 +169,77 @@ var _ = Describe("Healthchecker", func() {
 			})
 		})
 
+		Context("when finding the domain for the temporary route", func() {
+			It("looks up the domains of the foundation and org", func() {
+				healthchecker.OnEvent(event)
+
+				Expect(domainCache.DomainsCall.Received.FoundationURL).To(Equal(randomFoundationURL))
+				Expect(domainCache.DomainsCall.Received.Org).To(Equal(randomOrg))
+				Expect(domainCache.DomainsCall.Received.Courier).To(Equal(courier))
+			})
+
+			Context("when the domain made from the foundation url is not in the foundation", func() {
+				It("uses the first shared external domain", func() {
+					otherDomain := "other-" + randomizer.StringRunes(10) + ".com"
+					domainCache.DomainsCall.Returns.Domains = []S.Domain{
+						{Name: "private.example.com", Type: S.PrivateDomain, Protocol: "http"},
+						{Name: "tcp.example.com", Type: S.SharedDomain, Protocol: "tcp"},
+						{Name: "apps.internal", Type: S.SharedDomain, Protocol: "http", Internal: true},
+						{Name: otherDomain, Type: S.SharedDomain, Protocol: "http"},
+					}
+
+					healthchecker.OnEvent(event)
+
+					Expect(courier.MapRouteCall.Received.Domain[0]).To(Equal(otherDomain))
+					Expect(client.GetCall.Received.URL).To(Equal(fmt.Sprintf("https://%s.%s%s", randomAppName, otherDomain, randomEndpoint)))
+				})
+			})
+
+			Context("when OldURL and NewURL are not set", func() {
+				It("uses the first shared external domain", func() {
+					healthchecker.OldURL = ""
+					healthchecker.NewURL = ""
+
+					healthchecker.OnEvent(event)
+
+					Expect(courier.MapRouteCall.Received.Domain[0]).To(Equal(randomDomain))
+				})
+			})
+
+			Context("when the foundation has an apps domain", func() {
+				It("uses the apps domain without looking up the domains of the foundation", func() {
+					event.Data.(S.PushEventData).DeploymentInfo.AppsDomain = "apps.east.example.com"
+					client.GetCall.Returns.Response = http.Response{StatusCode: http.StatusOK}
+
+					Expect(healthchecker.OnEvent(event)).To(Succeed())
+
+					Expect(domainCache.DomainsCall.TimesCalled).To(Equal(0))
+					Expect(courier.MapRouteCall.Received.Domain[0]).To(Equal("apps.east.example.com"))
+					Expect(client.GetCall.Received.URL).To(Equal(fmt.Sprintf("https://%s.apps.east.example.com%s", randomAppName, randomEndpoint)))
+				})
+			})
+
+			Context("when there is no shared external domain", func() {
+				It("returns an error", func() {
+					domainCache.DomainsCall.Returns.Domains = []S.Domain{
+						{Name: "apps.internal", Type: S.SharedDomain, Protocol: "http", Internal: true},
+					}
+
+					err := healthchecker.OnEvent(event)
+					Expect(err).To(MatchError(NoDomainError{randomFoundationURL}))
+				})
+			})
+
+			Context("when the domains cannot be found", func() {
+				It("returns an error", func() {
+					domainCache.DomainsCall.Returns.Error = errors.New("domains error")
+
+					err := healthchecker.OnEvent(event)
+					Expect(err).To(MatchError(DomainsError{randomFoundationURL, errors.New("domains error")}))
+				})
+			})
+		})
+
 		Context("when mapping the temporary route fails", func() {
 			It("returns an error", func() {
 				courier.MapRouteCall.Returns.Output = append(courier.MapRouteCall.Returns.Output, []byte("map route output"))
@@ -26,3 +26,12 @@ type ReadFileError struct {
 func (e ReadFileError) Error() string {
 	return fmt.Sprintf("failed to read manifest file: %s", e.Err.Error())
 }
+
+type DomainsError struct {
+	FoundationURL string
+	Err           error
+}
+
+func (e DomainsError) Error() string {
+	return fmt.Sprintf("failed to get the domains in %s: %s", e.FoundationURL, e.Err)
+}
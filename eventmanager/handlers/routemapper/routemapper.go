@@ -13,9 +13,10 @@ import (
 // RouteMapper will map additional routes to an application at
 // deploy time if they are specified in the manifest.
 type RouteMapper struct {
-	Courier    I.Courier
-	FileSystem *afero.Afero
-	Log        I.Logger
+	Courier     I.Courier
+	DomainCache I.DomainCache
+	FileSystem  *afero.Afero
+	Log         I.Logger
 }
 
 type manifest struct {
@@ -38,6 +39,7 @@ func (r RouteMapper) OnEvent(event S.Event) error {
 
 	var (
 		tempAppWithUUID = event.Data.(S.PushEventData).TempAppWithUUID
+		foundationURL   = event.Data.(S.PushEventData).FoundationURL
 		deploymentInfo  = event.Data.(S.PushEventData).DeploymentInfo
 
 		manifestBytes []byte
@@ -75,7 +77,11 @@ func (r RouteMapper) OnEvent(event S.Event) error {
 
 	r.Log.Infof("found %s routes in the manifest", strconv.Itoa(len(m.Applications[0].Routes)))
 
-	domains, _ := r.Courier.Domains()
+	domains, err := r.DomainCache.Domains(foundationURL, deploymentInfo.Org, r.Courier)
+	if err != nil {
+		r.Log.Error(DomainsError{foundationURL, err})
+		return DomainsError{foundationURL, err}
+	}
 
 	r.Log.Debugf("mapping routes to %s", tempAppWithUUID)
 	for _, route := range m.Applications[0].Routes {
@@ -104,9 +110,9 @@ func (r RouteMapper) OnEvent(event S.Event) error {
 	return nil
 }
 
-func isRouteADomainInTheFoundation(route string, domains []string) bool {
+func isRouteADomainInTheFoundation(route string, domains []S.Domain) bool {
 	for _, domain := range domains {
-		if route == domain {
+		if route == domain.Name {
 			return true
 		}
 	}
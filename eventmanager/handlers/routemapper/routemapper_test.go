@@ -35,9 +35,10 @@ var _ = Describe("Routemapper", func() {
 		deploymentInfo *S.DeploymentInfo
 		event          S.Event
 
-		courier   *mocks.Courier
-		af        *afero.Afero
-		logBuffer *Buffer
+		courier     *mocks.Courier
+		domainCache *mocks.DomainCache
+		af          *afero.Afero
+		logBuffer   *Buffer
 
 		routemapper RouteMapper
 	)
@@ -66,6 +67,7 @@ var _ = Describe("Routemapper", func() {
 		}
 
 		courier = &mocks.Courier{}
+		domainCache = &mocks.DomainCache{}
 		af = &afero.Afero{Fs: afero.NewMemMapFs()}
 
 		event = S.Event{
@@ -81,8 +83,9 @@ var _ = Describe("Routemapper", func() {
 		logBuffer = NewBuffer()
 
 		routemapper = RouteMapper{
-			FileSystem: af,
-			Log:        logger.DefaultLogger(logBuffer, logging.DEBUG, "routemapper_test"),
+			DomainCache: domainCache,
+			FileSystem:  af,
+			Log:         logger.DefaultLogger(logBuffer, logging.DEBUG, "routemapper_test"),
 		}
 	})
 
@@ -110,7 +113,7 @@ applications:
 				routes[2],
 			)
 
-			courier.DomainsCall.Returns.Domains = []string{randomDomain + "0", randomDomain + "1", randomDomain + "2"}
+			domainCache.DomainsCall.Returns.Domains = domainsNamed(randomDomain+"0", randomDomain+"1", randomDomain+"2")
 		})
 
 		It("returns nil", func() {
@@ -144,7 +147,7 @@ applications:
 
 		Context("when map route fails", func() {
 			It("returns an error", func() {
-				courier.DomainsCall.Returns.Domains = []string{randomDomain + "0"}
+				domainCache.DomainsCall.Returns.Domains = domainsNamed(randomDomain + "0")
 
 				courier.MapRouteCall.Returns.Output = append(courier.MapRouteCall.Returns.Output, []byte("map route output"))
 				courier.MapRouteCall.Returns.Error = append(courier.MapRouteCall.Returns.Error, errors.New("map route error"))
@@ -156,7 +159,7 @@ applications:
 		})
 
 		It("prints output to the logs", func() {
-			courier.DomainsCall.Returns.Domains = []string{randomDomain + "0"}
+			domainCache.DomainsCall.Returns.Domains = domainsNamed(randomDomain + "0")
 
 			courier.MapRouteCall.Returns.Output = append(courier.MapRouteCall.Returns.Output, []byte("map route output"))
 			courier.MapRouteCall.Returns.Error = append(courier.MapRouteCall.Returns.Error, errors.New("map route error"))
@@ -195,7 +198,7 @@ applications:
 		})
 
 		It("calls map-route for the number of routes", func() {
-			courier.DomainsCall.Returns.Domains = []string{randomDomain + "0", randomDomain + "1", randomDomain + "2"}
+			domainCache.DomainsCall.Returns.Domains = domainsNamed(randomDomain+"0", randomDomain+"1", randomDomain+"2")
 
 			routemapper.OnEvent(event)
 
@@ -208,7 +211,7 @@ applications:
 
 		Context("when map route fails", func() {
 			It("returns an error", func() {
-				courier.DomainsCall.Returns.Domains = []string{randomDomain + "0"}
+				domainCache.DomainsCall.Returns.Domains = domainsNamed(randomDomain + "0")
 
 				courier.MapRouteCall.Returns.Output = append(courier.MapRouteCall.Returns.Output, []byte("map route output"))
 				courier.MapRouteCall.Returns.Error = append(courier.MapRouteCall.Returns.Error, errors.New("map route error"))
@@ -219,7 +222,7 @@ applications:
 			})
 
 			It("prints output to the logs", func() {
-				courier.DomainsCall.Returns.Domains = []string{randomDomain + "0"}
+				domainCache.DomainsCall.Returns.Domains = domainsNamed(randomDomain + "0")
 
 				courier.MapRouteCall.Returns.Output = append(courier.MapRouteCall.Returns.Output, []byte("map route output"))
 				courier.MapRouteCall.Returns.Error = append(courier.MapRouteCall.Returns.Error, errors.New("map route error"))
@@ -314,6 +317,39 @@ applications:
 		})
 	})
 
+	Context("when looking up the domains", func() {
+		It("uses the domains of the foundation and org being pushed to", func() {
+			deploymentInfo.Manifest = fmt.Sprintf(`
+---
+applications:
+- name: example
+  routes:
+  - route: %s.%s`, randomHostName, randomDomain)
+			domainCache.DomainsCall.Returns.Domains = domainsNamed(randomDomain)
+
+			Expect(routemapper.OnEvent(event)).To(Succeed())
+
+			Expect(domainCache.DomainsCall.Received.FoundationURL).To(Equal(randomFoundationURL))
+			Expect(domainCache.DomainsCall.Received.Org).To(Equal(randomOrg))
+			Expect(domainCache.DomainsCall.Received.Courier).To(Equal(courier))
+		})
+
+		Context("when the domains cannot be found", func() {
+			It("returns an error", func() {
+				deploymentInfo.Manifest = fmt.Sprintf(`
+---
+applications:
+- name: example
+  routes:
+  - route: %s.%s`, randomHostName, randomDomain)
+				domainCache.DomainsCall.Returns.Error = errors.New("domains error")
+
+				err := routemapper.OnEvent(event)
+				Expect(err).To(MatchError(DomainsError{randomFoundationURL, errors.New("domains error")}))
+			})
+		})
+	})
+
 	Context("when the domain is not found", func() {
 		It("returns an error", func() {
 			deploymentInfo.Manifest = fmt.Sprintf(`
@@ -324,7 +360,7 @@ applications:
   - route: test.example.com`,
 			)
 
-			courier.DomainsCall.Returns.Domains = []string{randomDomain}
+			domainCache.DomainsCall.Returns.Domains = domainsNamed(randomDomain)
 
 			err := routemapper.OnEvent(event)
 
@@ -334,7 +370,7 @@ applications:
 
 	Context("when manifest is bundled with the application", func() {
 		It("reads the manifest file", func() {
-			courier.DomainsCall.Returns.Domains = []string{randomDomain}
+			domainCache.DomainsCall.Returns.Domains = domainsNamed(randomDomain)
 
 			manifest := []byte(fmt.Sprintf(`---
 applications:
@@ -374,3 +410,11 @@ applications:
 		})
 	})
 })
+
+func domainsNamed(names ...string) []S.Domain {
+	domains := []S.Domain{}
+	for _, name := range names {
+		domains = append(domains, S.Domain{Name: name, Type: S.SharedDomain, Protocol: "http"})
+	}
+	return domains
+}
@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"io"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// ArtifactCache interface.
+type ArtifactCache interface {
+	LookupDigest(checksums S.Checksums, credentials string) (S.CachedArtifact, bool)
+	LookupURL(url string) (S.CachedArtifact, bool)
+	CopyTo(artifact S.CachedArtifact, destination io.Writer) error
+	Store(artifact S.CachedArtifact, source string) error
+}
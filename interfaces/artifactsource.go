@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/compozed/deployadactyl/config"
+)
+
+// ArtifactSource interface.
+type ArtifactSource interface {
+	Get(request *http.Request, credentials config.ArtifactCredentials) (*http.Response, error)
+}
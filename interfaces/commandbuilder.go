@@ -0,0 +1,13 @@
+package interfaces
+
+import S "github.com/compozed/deployadactyl/structs"
+
+// CommandBuilder interface.
+type CommandBuilder interface {
+	Push(appName, hostname string, instances uint16) []string
+	MapRoute(appName, domain, hostname string) []string
+	UnmapRoute(appName, domain, hostname string) []string
+	MapsRouteAfterPush() bool
+	SupportsRollingDeployments() bool
+	ParseDomains(output []byte) ([]S.Domain, error)
+}
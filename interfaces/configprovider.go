@@ -0,0 +1,8 @@
+package interfaces
+
+import "github.com/compozed/deployadactyl/config"
+
+// ConfigProvider interface.
+type ConfigProvider interface {
+	Config() config.Config
+}
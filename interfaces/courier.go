@@ -1,5 +1,7 @@
 package interfaces
 
+import S "github.com/compozed/deployadactyl/structs"
+
 // Courier interface.
 type Courier interface {
 	Login(foundationURL, username, password, org, space string, skipSSL bool) ([]byte, error)
@@ -12,6 +14,10 @@ type Courier interface {
 	Exists(appName string) bool
 	Cups(appName string, body string) ([]byte, error)
 	Uups(appName string, body string) ([]byte, error)
-	Domains() ([]string, error)
+	Domains() ([]S.Domain, error)
+	PushRolling(appName, appLocation string, instances uint16) ([]byte, error)
+	CancelDeployment(appName string) ([]byte, error)
+	DeployedRevision(appName string) (int, error)
+	Rollback(appName string, revision int) ([]byte, error)
 	CleanUp() error
 }
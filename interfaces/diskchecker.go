@@ -0,0 +1,6 @@
+package interfaces
+
+// DiskChecker interface.
+type DiskChecker interface {
+	Check(required int64) error
+}
@@ -0,0 +1,8 @@
+package interfaces
+
+import S "github.com/compozed/deployadactyl/structs"
+
+// DomainCache interface.
+type DomainCache interface {
+	Domains(foundationURL, org string, courier Courier) ([]S.Domain, error)
+}
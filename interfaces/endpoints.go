@@ -5,4 +5,6 @@ import "github.com/gin-gonic/gin"
 // Endpoints interface.
 type Endpoints interface {
 	Deploy(c *gin.Context)
+	Environments(c *gin.Context)
+	Environment(c *gin.Context)
 }
@@ -1,9 +1,17 @@
 package interfaces
 
-import S "github.com/compozed/deployadactyl/structs"
+import (
+	"github.com/compozed/deployadactyl/config"
+	S "github.com/compozed/deployadactyl/structs"
+)
 
 // EventManager interface.
 type EventManager interface {
 	AddHandler(handler Handler, eventType string) error
+	AddEnvironmentHandler(handler EnvironmentHandler, eventType string) error
 	Emit(event S.Event) error
 }
+
+// EnvironmentHandler returns the Handler for the events of deployments to an environment,
+// or nil when the environment does not use it.
+type EnvironmentHandler func(environment config.Environment) Handler
@@ -2,5 +2,5 @@ package interfaces
 
 // Extractor interface.
 type Extractor interface {
-	Unzip(source, destination, manifest string) error
+	Extract(source, destination, manifest string) error
 }
@@ -1,9 +1,16 @@
 package interfaces
 
-import "net/http"
+import (
+	"io"
+	"net/http"
+
+	"github.com/compozed/deployadactyl/config"
+	S "github.com/compozed/deployadactyl/structs"
+)
 
 // Fetcher interface.
 type Fetcher interface {
-	Fetch(url, manifest string) (string, error)
+	Fetch(url, manifest string, checksums S.Checksums, signature S.ArtifactSignature, environment config.Environment, response io.Writer) (string, S.Checksums, error)
+	FetchArchive(archive io.Reader, manifest string, checksums S.Checksums, signature S.ArtifactSignature, environment config.Environment, response io.Writer) (string, S.Checksums, error)
 	FetchZipFromRequest(*http.Request) (string, error)
 }
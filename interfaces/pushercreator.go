@@ -3,10 +3,11 @@ package interfaces
 import (
 	"io"
 
+	"github.com/compozed/deployadactyl/config"
 	S "github.com/compozed/deployadactyl/structs"
 )
 
 // PusherCreator interface.
 type PusherCreator interface {
-	CreatePusher(deploymentInfo S.DeploymentInfo, response io.ReadWriter) (Pusher, error)
+	CreatePusher(deploymentInfo S.DeploymentInfo, environment config.Environment, response io.ReadWriter) (Pusher, error)
 }
@@ -0,0 +1,8 @@
+package interfaces
+
+// SessionExecutor interface.
+type SessionExecutor interface {
+	Executor
+	Resume(foundationURL, username, password string, skipSSL bool) bool
+	LoggedIn(success bool)
+}
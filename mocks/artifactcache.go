@@ -0,0 +1,88 @@
+package mocks
+
+import (
+	"io"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// ArtifactCache handmade mock for tests.
+type ArtifactCache struct {
+	LookupDigestCall struct {
+		Received struct {
+			Checksums   S.Checksums
+			Credentials string
+		}
+		Returns struct {
+			Artifact S.CachedArtifact
+			Found    bool
+		}
+	}
+
+	LookupURLCall struct {
+		Received struct {
+			URL string
+		}
+		Returns struct {
+			Artifact S.CachedArtifact
+			Found    bool
+		}
+	}
+
+	CopyToCall struct {
+		TimesCalled int
+		Received    struct {
+			Artifact S.CachedArtifact
+		}
+		Returns struct {
+			Contents []byte
+			Error    error
+		}
+	}
+
+	StoreCall struct {
+		TimesCalled int
+		Received    struct {
+			Artifact S.CachedArtifact
+			Source   string
+		}
+		Returns struct {
+			Error error
+		}
+	}
+}
+
+// LookupDigest mock method.
+func (a *ArtifactCache) LookupDigest(checksums S.Checksums, credentials string) (S.CachedArtifact, bool) {
+	a.LookupDigestCall.Received.Checksums = checksums
+	a.LookupDigestCall.Received.Credentials = credentials
+
+	return a.LookupDigestCall.Returns.Artifact, a.LookupDigestCall.Returns.Found
+}
+
+// LookupURL mock method.
+func (a *ArtifactCache) LookupURL(url string) (S.CachedArtifact, bool) {
+	a.LookupURLCall.Received.URL = url
+
+	return a.LookupURLCall.Returns.Artifact, a.LookupURLCall.Returns.Found
+}
+
+// CopyTo mock method.
+func (a *ArtifactCache) CopyTo(artifact S.CachedArtifact, destination io.Writer) error {
+	defer func() { a.CopyToCall.TimesCalled++ }()
+
+	a.CopyToCall.Received.Artifact = artifact
+	destination.Write(a.CopyToCall.Returns.Contents)
+
+	return a.CopyToCall.Returns.Error
+}
+
+// Store mock method.
+func (a *ArtifactCache) Store(artifact S.CachedArtifact, source string) error {
+	defer func() { a.StoreCall.TimesCalled++ }()
+
+	a.StoreCall.Received.Artifact = artifact
+	a.StoreCall.Received.Source = source
+
+	return a.StoreCall.Returns.Error
+}
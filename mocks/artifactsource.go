@@ -0,0 +1,29 @@
+package mocks
+
+import (
+	"net/http"
+
+	"github.com/compozed/deployadactyl/config"
+)
+
+// ArtifactSource handmade mock for tests.
+type ArtifactSource struct {
+	GetCall struct {
+		Received struct {
+			Request     *http.Request
+			Credentials config.ArtifactCredentials
+		}
+		Returns struct {
+			Response *http.Response
+			Error    error
+		}
+	}
+}
+
+// Get mock method.
+func (a *ArtifactSource) Get(request *http.Request, credentials config.ArtifactCredentials) (*http.Response, error) {
+	a.GetCall.Received.Request = request
+	a.GetCall.Received.Credentials = credentials
+
+	return a.GetCall.Returns.Response, a.GetCall.Returns.Error
+}
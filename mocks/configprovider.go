@@ -0,0 +1,20 @@
+package mocks
+
+import "github.com/compozed/deployadactyl/config"
+
+// ConfigProvider handmade mock for tests.
+type ConfigProvider struct {
+	ConfigCall struct {
+		TimesCalled int
+		Returns     struct {
+			Config config.Config
+		}
+	}
+}
+
+// Config mock method.
+func (c *ConfigProvider) Config() config.Config {
+	defer func() { c.ConfigCall.TimesCalled++ }()
+
+	return c.ConfigCall.Returns.Config
+}
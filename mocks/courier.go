@@ -1,5 +1,7 @@
 package mocks
 
+import S "github.com/compozed/deployadactyl/structs"
+
 // Courier handmade mock for tests.
 type Courier struct {
 	LoginCall struct {
@@ -120,11 +122,57 @@ type Courier struct {
 	DomainsCall struct {
 		TimesCalled int
 		Returns     struct {
-			Domains []string
+			Domains []S.Domain
 			Error   error
 		}
 	}
 
+	PushRollingCall struct {
+		Received struct {
+			AppName   string
+			AppPath   string
+			Instances uint16
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	CancelDeploymentCall struct {
+		TimesCalled int
+		Received    struct {
+			AppName string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	DeployedRevisionCall struct {
+		TimesCalled int
+		Received    struct {
+			AppName string
+		}
+		Returns struct {
+			Revision int
+			Error    error
+		}
+	}
+
+	RollbackCall struct {
+		TimesCalled int
+		Received    struct {
+			AppName  string
+			Revision int
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
 	CleanUpCall struct {
 		Returns struct {
 			Error error
@@ -228,12 +276,49 @@ func (c *Courier) Uups(appName string, body string) ([]byte, error) {
 }
 
 // Domains mock method.
-func (c *Courier) Domains() ([]string, error) {
+func (c *Courier) Domains() ([]S.Domain, error) {
 	defer func() { c.DomainsCall.TimesCalled++ }()
 
 	return c.DomainsCall.Returns.Domains, c.DomainsCall.Returns.Error
 }
 
+// PushRolling mock method.
+func (c *Courier) PushRolling(appName, appLocation string, instances uint16) ([]byte, error) {
+	c.PushRollingCall.Received.AppName = appName
+	c.PushRollingCall.Received.AppPath = appLocation
+	c.PushRollingCall.Received.Instances = instances
+
+	return c.PushRollingCall.Returns.Output, c.PushRollingCall.Returns.Error
+}
+
+// CancelDeployment mock method.
+func (c *Courier) CancelDeployment(appName string) ([]byte, error) {
+	defer func() { c.CancelDeploymentCall.TimesCalled++ }()
+
+	c.CancelDeploymentCall.Received.AppName = appName
+
+	return c.CancelDeploymentCall.Returns.Output, c.CancelDeploymentCall.Returns.Error
+}
+
+// DeployedRevision mock method.
+func (c *Courier) DeployedRevision(appName string) (int, error) {
+	defer func() { c.DeployedRevisionCall.TimesCalled++ }()
+
+	c.DeployedRevisionCall.Received.AppName = appName
+
+	return c.DeployedRevisionCall.Returns.Revision, c.DeployedRevisionCall.Returns.Error
+}
+
+// Rollback mock method.
+func (c *Courier) Rollback(appName string, revision int) ([]byte, error) {
+	defer func() { c.RollbackCall.TimesCalled++ }()
+
+	c.RollbackCall.Received.AppName = appName
+	c.RollbackCall.Received.Revision = revision
+
+	return c.RollbackCall.Returns.Output, c.RollbackCall.Returns.Error
+}
+
 // CleanUp mock method.
 func (c *Courier) CleanUp() error {
 	return c.CleanUpCall.Returns.Error
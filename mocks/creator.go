@@ -26,6 +26,12 @@ import (
 // ENDPOINT is used by the handler to define the deployment endpoint.
 const ENDPOINT = "/v1/apps/:environment/:org/:space/:appName"
 
+// ENVIRONMENTS_ENDPOINT and ENVIRONMENT_ENDPOINT are used by the handler to define the endpoints that return the environments in the config.
+const (
+	ENVIRONMENTS_ENDPOINT = "/v1/environments"
+	ENVIRONMENT_ENDPOINT  = "/v1/environments/:name"
+)
+
 // Handmade Creator mock.
 // Uses a mock prechecker to skip verifying the foundations are up and running.
 // Uses a mock Courier and Executor to mock pushing an application.
@@ -71,6 +77,8 @@ func (c Creator) CreateControllerHandler() *gin.Engine {
 	r.Use(gin.ErrorLogger())
 
 	r.POST(ENDPOINT, d.Deploy)
+	r.GET(ENVIRONMENTS_ENDPOINT, d.Environments)
+	r.GET(ENVIRONMENT_ENDPOINT, d.Environment)
 
 	return r
 }
@@ -78,6 +86,7 @@ func (c Creator) CreateControllerHandler() *gin.Engine {
 func (c Creator) CreateController() controller.Controller {
 	return controller.Controller{
 		Deployer: c.CreateDeployer(),
+		Config:   c.CreateConfigProvider(),
 		Log:      c.CreateLogger(),
 	}
 }
@@ -88,7 +97,7 @@ func (c Creator) CreateRandomizer() I.Randomizer {
 
 func (c Creator) CreateDeployer() I.Deployer {
 	return deployer.Deployer{
-		Config:      c.CreateConfig(),
+		Config:      c.CreateConfigProvider(),
 		BlueGreener: c.CreateBlueGreener(),
 		Fetcher: &artifetcher.Artifetcher{
 			FileSystem: c.CreateFileSystem(),
@@ -117,7 +126,7 @@ func (c Creator) createFetcher() I.Fetcher {
 	}
 }
 
-func (c Creator) CreatePusher(deploymentInfo S.DeploymentInfo, response io.ReadWriter) (I.Pusher, error) {
+func (c Creator) CreatePusher(deploymentInfo S.DeploymentInfo, environment config.Environment, response io.ReadWriter) (I.Pusher, error) {
 	courier := &Courier{}
 
 	courier.LoginCall.Returns.Output = []byte("logged in\t")
@@ -130,6 +139,7 @@ func (c Creator) CreatePusher(deploymentInfo S.DeploymentInfo, response io.ReadW
 	p := &pusher.Pusher{
 		Courier:        courier,
 		DeploymentInfo: deploymentInfo,
+		Environment:    environment,
 		EventManager:   c.CreateEventManager(),
 		Response:       response,
 		Log:            c.CreateLogger(),
@@ -150,6 +160,12 @@ func (c Creator) CreateConfig() config.Config {
 	return c.config
 }
 
+func (c Creator) CreateConfigProvider() I.ConfigProvider {
+	provider := &ConfigProvider{}
+	provider.ConfigCall.Returns.Config = c.config
+	return provider
+}
+
 func (c Creator) CreatePrechecker() I.Prechecker {
 	return &Prechecker{}
 }
@@ -0,0 +1,23 @@
+package mocks
+
+// DiskChecker handmade mock for tests.
+type DiskChecker struct {
+	CheckCall struct {
+		TimesCalled int
+		Received    struct {
+			Required []int64
+		}
+		Returns struct {
+			Error error
+		}
+	}
+}
+
+// Check mock method.
+func (d *DiskChecker) Check(required int64) error {
+	defer func() { d.CheckCall.TimesCalled++ }()
+
+	d.CheckCall.Received.Required = append(d.CheckCall.Received.Required, required)
+
+	return d.CheckCall.Returns.Error
+}
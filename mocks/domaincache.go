@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// DomainCache handmade mock for tests.
+type DomainCache struct {
+	DomainsCall struct {
+		TimesCalled int
+		Received    struct {
+			FoundationURL string
+			Org           string
+			Courier       I.Courier
+		}
+		Returns struct {
+			Domains []S.Domain
+			Error   error
+		}
+	}
+}
+
+// Domains mock method.
+func (d *DomainCache) Domains(foundationURL, org string, courier I.Courier) ([]S.Domain, error) {
+	defer func() { d.DomainsCall.TimesCalled++ }()
+
+	d.DomainsCall.Received.FoundationURL = foundationURL
+	d.DomainsCall.Received.Org = org
+	d.DomainsCall.Received.Courier = courier
+
+	return d.DomainsCall.Returns.Domains, d.DomainsCall.Returns.Error
+}
@@ -16,6 +16,15 @@ type EventManager struct {
 			Error error
 		}
 	}
+	AddEnvironmentHandlerCall struct {
+		Received struct {
+			Handlers   []I.EnvironmentHandler
+			EventTypes []string
+		}
+		Returns struct {
+			Error error
+		}
+	}
 	EmitCall struct {
 		TimesCalled int
 		Received    struct {
@@ -35,6 +44,14 @@ func (e *EventManager) AddHandler(handler I.Handler, eventType string) error {
 	return e.AddHandlerCall.Returns.Error
 }
 
+// AddEnvironmentHandler mock method.
+func (e *EventManager) AddEnvironmentHandler(handler I.EnvironmentHandler, eventType string) error {
+	e.AddEnvironmentHandlerCall.Received.Handlers = append(e.AddEnvironmentHandlerCall.Received.Handlers, handler)
+	e.AddEnvironmentHandlerCall.Received.EventTypes = append(e.AddEnvironmentHandlerCall.Received.EventTypes, eventType)
+
+	return e.AddEnvironmentHandlerCall.Returns.Error
+}
+
 // Emit mock method.
 func (e *EventManager) Emit(event S.Event) error {
 	defer func() { e.EmitCall.TimesCalled++ }()
@@ -3,7 +3,8 @@ package mocks
 // Executor handmade mock for tests.
 type Executor struct {
 	ExecuteCall struct {
-		Received struct {
+		TimesCalled int
+		Received    struct {
 			Args []string
 		}
 		Returns struct {
@@ -24,7 +25,8 @@ type Executor struct {
 	}
 
 	CleanUpCall struct {
-		Returns struct {
+		TimesCalled int
+		Returns     struct {
 			Error error
 		}
 	}
@@ -32,6 +34,8 @@ type Executor struct {
 
 // Execute mock method.
 func (e *Executor) Execute(args ...string) ([]byte, error) {
+	defer func() { e.ExecuteCall.TimesCalled++ }()
+
 	e.ExecuteCall.Received.Args = args
 
 	return e.ExecuteCall.Returns.Output, e.ExecuteCall.Returns.Error
@@ -47,5 +51,7 @@ func (e *Executor) ExecuteInDirectory(appLocation string, args ...string) ([]byt
 
 // CleanUp mock method.
 func (e *Executor) CleanUp() error {
+	defer func() { e.CleanUpCall.TimesCalled++ }()
+
 	return e.CleanUpCall.Returns.Error
 }
@@ -2,7 +2,7 @@ package mocks
 
 // Extractor handmade mock for tests.
 type Extractor struct {
-	UnzipCall struct {
+	ExtractCall struct {
 		Received struct {
 			Source      string
 			Destination string
@@ -14,11 +14,11 @@ type Extractor struct {
 	}
 }
 
-// Unzip mock method.
-func (e *Extractor) Unzip(source, destination, manifest string) error {
-	e.UnzipCall.Received.Source = source
-	e.UnzipCall.Received.Destination = destination
-	e.UnzipCall.Received.Manifest = manifest
+// Extract mock method.
+func (e *Extractor) Extract(source, destination, manifest string) error {
+	e.ExtractCall.Received.Source = source
+	e.ExtractCall.Received.Destination = destination
+	e.ExtractCall.Received.Manifest = manifest
 
-	return e.UnzipCall.Returns.Error
+	return e.ExtractCall.Returns.Error
 }
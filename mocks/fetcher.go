@@ -1,6 +1,13 @@
 package mocks
 
-import "net/http"
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/compozed/deployadactyl/config"
+	S "github.com/compozed/deployadactyl/structs"
+)
 
 // Fetcher handmade mock for tests.
 type Fetcher struct {
@@ -8,10 +15,31 @@ type Fetcher struct {
 		Received struct {
 			ArtifactURL string
 			Manifest    string
+			Checksums   S.Checksums
+			Signature   S.ArtifactSignature
+			Environment config.Environment
+			Response    io.Writer
 		}
 		Returns struct {
-			AppPath string
-			Error   error
+			AppPath   string
+			Checksums S.Checksums
+			Error     error
+		}
+	}
+
+	FetchArchiveCall struct {
+		Received struct {
+			Archive     []byte
+			Manifest    string
+			Checksums   S.Checksums
+			Signature   S.ArtifactSignature
+			Environment config.Environment
+			Response    io.Writer
+		}
+		Returns struct {
+			AppPath   string
+			Checksums S.Checksums
+			Error     error
 		}
 	}
 
@@ -27,11 +55,27 @@ type Fetcher struct {
 }
 
 // Fetch mock method.
-func (f *Fetcher) Fetch(url, manifest string) (string, error) {
+func (f *Fetcher) Fetch(url, manifest string, checksums S.Checksums, signature S.ArtifactSignature, environment config.Environment, response io.Writer) (string, S.Checksums, error) {
 	f.FetchCall.Received.ArtifactURL = url
 	f.FetchCall.Received.Manifest = manifest
+	f.FetchCall.Received.Checksums = checksums
+	f.FetchCall.Received.Signature = signature
+	f.FetchCall.Received.Environment = environment
+	f.FetchCall.Received.Response = response
+
+	return f.FetchCall.Returns.AppPath, f.FetchCall.Returns.Checksums, f.FetchCall.Returns.Error
+}
+
+// FetchArchive mock method.
+func (f *Fetcher) FetchArchive(archive io.Reader, manifest string, checksums S.Checksums, signature S.ArtifactSignature, environment config.Environment, response io.Writer) (string, S.Checksums, error) {
+	f.FetchArchiveCall.Received.Archive, _ = ioutil.ReadAll(archive)
+	f.FetchArchiveCall.Received.Manifest = manifest
+	f.FetchArchiveCall.Received.Checksums = checksums
+	f.FetchArchiveCall.Received.Signature = signature
+	f.FetchArchiveCall.Received.Environment = environment
+	f.FetchArchiveCall.Received.Response = response
 
-	return f.FetchCall.Returns.AppPath, f.FetchCall.Returns.Error
+	return f.FetchArchiveCall.Returns.AppPath, f.FetchArchiveCall.Returns.Checksums, f.FetchArchiveCall.Returns.Error
 }
 
 // FetchZipFromRequest mock method.
@@ -3,6 +3,7 @@ package mocks
 import (
 	"io"
 
+	"github.com/compozed/deployadactyl/config"
 	"github.com/compozed/deployadactyl/interfaces"
 	S "github.com/compozed/deployadactyl/structs"
 )
@@ -11,7 +12,11 @@ import (
 type PusherCreator struct {
 	CreatePusherCall struct {
 		TimesCalled int
-		Returns     struct {
+		Received    struct {
+			DeploymentInfos []S.DeploymentInfo
+			Environments    []config.Environment
+		}
+		Returns struct {
 			Pushers []interfaces.Pusher
 			Error   []error
 		}
@@ -19,8 +24,11 @@ type PusherCreator struct {
 }
 
 // CreatePusher mock method.
-func (p *PusherCreator) CreatePusher(deploymentInfo S.DeploymentInfo, response io.ReadWriter) (interfaces.Pusher, error) {
+func (p *PusherCreator) CreatePusher(deploymentInfo S.DeploymentInfo, environment config.Environment, response io.ReadWriter) (interfaces.Pusher, error) {
 	defer func() { p.CreatePusherCall.TimesCalled++ }()
 
+	p.CreatePusherCall.Received.DeploymentInfos = append(p.CreatePusherCall.Received.DeploymentInfos, deploymentInfo)
+	p.CreatePusherCall.Received.Environments = append(p.CreatePusherCall.Received.Environments, environment)
+
 	return p.CreatePusherCall.Returns.Pushers[p.CreatePusherCall.TimesCalled], p.CreatePusherCall.Returns.Error[p.CreatePusherCall.TimesCalled]
 }
@@ -0,0 +1,42 @@
+package mocks
+
+// SessionExecutor handmade mock for tests.
+type SessionExecutor struct {
+	Executor
+
+	ResumeCall struct {
+		Received struct {
+			FoundationURL string
+			Username      string
+			Password      string
+			SkipSSL       bool
+		}
+		Returns struct {
+			Bool bool
+		}
+	}
+
+	LoggedInCall struct {
+		TimesCalled int
+		Received    struct {
+			Success bool
+		}
+	}
+}
+
+// Resume mock method.
+func (s *SessionExecutor) Resume(foundationURL, username, password string, skipSSL bool) bool {
+	s.ResumeCall.Received.FoundationURL = foundationURL
+	s.ResumeCall.Received.Username = username
+	s.ResumeCall.Received.Password = password
+	s.ResumeCall.Received.SkipSSL = skipSSL
+
+	return s.ResumeCall.Returns.Bool
+}
+
+// LoggedIn mock method.
+func (s *SessionExecutor) LoggedIn(success bool) {
+	defer func() { s.LoggedInCall.TimesCalled++ }()
+
+	s.LoggedInCall.Received.Success = success
+}
@@ -1,16 +1,23 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/compozed/deployadactyl/config"
 	C "github.com/compozed/deployadactyl/constants"
 	"github.com/compozed/deployadactyl/creator"
 	"github.com/compozed/deployadactyl/eventmanager/handlers/envvar"
 	"github.com/compozed/deployadactyl/eventmanager/handlers/healthchecker"
 	"github.com/compozed/deployadactyl/eventmanager/handlers/routemapper"
+	I "github.com/compozed/deployadactyl/interfaces"
 	"github.com/compozed/deployadactyl/logger"
 	"github.com/op/go-logging"
 )
@@ -19,14 +26,22 @@ const (
 	defaultConfigFilePath = "./config.yml"
 	defaultLogLevel       = "DEBUG"
 	logLevelEnvVarName    = "DEPLOYADACTYL_LOGLEVEL"
+
+	defaultShutdownTimeout = 10 * time.Minute
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(validate(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	var (
-		config               = flag.String("config", defaultConfigFilePath, "location of the config file")
-		envVarHandlerEnabled = flag.Bool("env", false, "enable environment variable handling")
-		healthCheckEnabled   = flag.Bool("health-check", false, "health checker to check endpoints during a deployment")
-		routeMapperEnabled   = flag.Bool("route-mapper", false, "enables route mapper to map additional routes from a manifest")
+		configPath           = flag.String("config", defaultConfigFilePath, "location of the config file")
+		envVarHandlerEnabled = flag.Bool("env", false, "deprecated: enable environment variable handling for every environment")
+		healthCheckEnabled   = flag.Bool("health-check", false, "deprecated: health checker to check endpoints during a deployment to every environment")
+		routeMapperEnabled   = flag.Bool("route-mapper", false, "deprecated: enables route mapper to map additional routes from a manifest for every environment")
+		configReloadInterval = flag.Duration("config-reload-interval", 0, "how often to check the config file for changes and reload it, such as 30s; 0 only reloads on SIGHUP")
+		shutdownTimeout      = flag.Duration("shutdown-timeout", defaultShutdownTimeout, "how long to wait for deployments in progress to finish on SIGINT or SIGTERM")
 	)
 	flag.Parse()
 
@@ -43,47 +58,161 @@ func main() {
 	log := logger.DefaultLogger(os.Stdout, logLevel, "deployadactyl")
 	log.Infof("log level : %s", level)
 
-	c, err := creator.Custom(level, *config)
+	c, err := creator.Custom(level, *configPath)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	configProvider := c.CreateConfigProvider()
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go configProvider.ReloadOn(hangups, nil)
+
+	if *configReloadInterval > 0 {
+		log.Infof("checking %s for changes every %s", *configPath, *configReloadInterval)
+		go configProvider.Watch(*configReloadInterval, nil)
+	}
+
 	em := c.CreateEventManager()
 
+	envVarHandler := newEnvVarHandler(c)
+	routeMapper := newRouteMapper(c)
+	healthCheckers := newHealthCheckers(c)
+
 	if *envVarHandlerEnabled {
-		envVarHandler := envvar.Envvarhandler{Logger: c.CreateLogger(), FileSystem: c.CreateFileSystem()}
-		log.Infof("registering environment variable event handler")
+		log.Infof("registering environment variable event handler for every environment: the -env flag is deprecated, enable environment_variables in the handlers of each environment instead")
 		em.AddHandler(envVarHandler, C.DeployStartEvent)
 	}
 
 	if *healthCheckEnabled {
-		healthHandler := healthchecker.HealthChecker{
-			OldURL: "",
-			NewURL: "",
-			Client: c.CreateHTTPClient(),
-			Log:    c.CreateLogger(),
-		}
-		log.Infof("registering health check handler")
-		em.AddHandler(healthHandler, C.PushFinishedEvent)
+		log.Infof("registering health check handler for every environment: the -health-check flag is deprecated, enable health_check in the handlers of each environment instead")
+		em.AddHandler(healthCheckers.forSettings(config.HealthCheckHandler{}), C.PushFinishedEvent)
 	}
 
 	if *routeMapperEnabled {
-		routeMapper := routemapper.RouteMapper{
-			FileSystem: c.CreateFileSystem(),
-			Log:        c.CreateLogger(),
-		}
-
-		log.Infof("registering health check handler")
+		log.Infof("registering route mapper handler for every environment: the -route-mapper flag is deprecated, enable route_mapper in the handlers of each environment instead")
 		em.AddHandler(routeMapper, C.PushFinishedEvent)
 	}
 
-	l := c.CreateListener()
-	deploy := c.CreateControllerHandler()
+	if !*envVarHandlerEnabled {
+		em.AddEnvironmentHandler(func(environment config.Environment) I.Handler {
+			if !environment.Handlers.EnvironmentVariables.Enabled {
+				return nil
+			}
+			return envVarHandler
+		}, C.DeployStartEvent)
+	}
 
-	log.Infof("Listening on Port %d", c.CreateConfig().Port)
+	if !*healthCheckEnabled {
+		em.AddEnvironmentHandler(func(environment config.Environment) I.Handler {
+			if !environment.Handlers.HealthCheck.Enabled {
+				return nil
+			}
+			return healthCheckers.forSettings(environment.Handlers.HealthCheck)
+		}, C.PushFinishedEvent)
+	}
+
+	if !*routeMapperEnabled {
+		em.AddEnvironmentHandler(func(environment config.Environment) I.Handler {
+			if !environment.Handlers.RouteMapper.Enabled {
+				return nil
+			}
+			return routeMapper
+		}, C.PushFinishedEvent)
+	}
 
-	err = http.Serve(l, deploy)
+	if certProvider := c.CreateCertProvider(); certProvider != nil {
+		certificateHangups := make(chan os.Signal, 1)
+		signal.Notify(certificateHangups, syscall.SIGHUP)
+		go certProvider.ReloadOn(certificateHangups, nil)
+	}
+
+	l, err := c.CreateListener()
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	server := c.CreateServer()
+
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+	exitCode := make(chan int)
+	go func() {
+		sig := <-shutdown
+		log.Infof("received %s: waiting up to %s for deployments in progress to finish", sig, *shutdownTimeout)
+		exitCode <- stop(server, c, *shutdownTimeout, log)
+	}()
+
+	log.Infof("Listening on %s", l.Addr())
+
+	err = server.Serve(l)
+	if err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+
+	os.Exit(<-exitCode)
+}
+
+// stop stops accepting requests and waits for the requests in progress to finish for up to timeout,
+// then cleans up the cf sessions. It returns the exit status, which is 1 when requests were still in progress.
+func stop(server *http.Server, c creator.Creator, timeout time.Duration, log I.Logger) int {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+
+	log.Infof("cleaning up cf sessions and shutting down")
+	c.Close()
+
+	if err != nil {
+		log.Errorf("deployments were still in progress after %s: %s", timeout, err)
+		return 1
+	}
+	return 0
+}
+
+func newEnvVarHandler(c creator.Creator) envvar.Envvarhandler {
+	return envvar.Envvarhandler{Logger: c.CreateLogger(), FileSystem: c.CreateFileSystem()}
+}
+
+// healthCheckers builds a health checker once for each health_check config of an environment,
+// instead of every time an event is emitted.
+type healthCheckers struct {
+	creator  creator.Creator
+	mutex    sync.Mutex
+	checkers map[config.HealthCheckHandler]healthchecker.HealthChecker
+}
+
+func newHealthCheckers(c creator.Creator) *healthCheckers {
+	return &healthCheckers{
+		creator:  c,
+		checkers: make(map[config.HealthCheckHandler]healthchecker.HealthChecker),
+	}
+}
+
+func (h *healthCheckers) forSettings(settings config.HealthCheckHandler) healthchecker.HealthChecker {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	checker, ok := h.checkers[settings]
+	if !ok {
+		checker = healthchecker.HealthChecker{
+			OldURL:      settings.OldURL,
+			NewURL:      settings.NewURL,
+			Client:      h.creator.CreateHTTPClient(),
+			DomainCache: h.creator.CreateDomainCache(),
+			Log:         h.creator.CreateLogger(),
+		}
+		h.checkers[settings] = checker
+	}
+	return checker
+}
+
+func newRouteMapper(c creator.Creator) routemapper.RouteMapper {
+	return routemapper.RouteMapper{
+		DomainCache: c.CreateDomainCache(),
+		FileSystem:  c.CreateFileSystem(),
+		Log:         c.CreateLogger(),
+	}
 }
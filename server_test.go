@@ -109,4 +109,42 @@ var _ = Describe("Server", func() {
 			})
 		})
 	})
+
+	Describe("validate command", func() {
+		var configLocation string
+
+		BeforeEach(func() {
+			configLocation = fmt.Sprintf("%s/validate.yml", path.Dir(pathToCLI))
+		})
+
+		AfterEach(func() {
+			os.Remove(configLocation)
+		})
+
+		Context("when the config is valid", func() {
+			It("exits with zero", func() {
+				Expect(ioutil.WriteFile(configLocation, goodConfig, 0644)).To(Succeed())
+
+				session, err = gexec.Start(exec.Command(pathToCLI, "validate", "-config", configLocation), GinkgoWriter, GinkgoWriter)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(0))
+				Expect(session.Out).To(Say("is valid"))
+			})
+		})
+
+		Context("when the config has problems", func() {
+			It("reports every problem and exits with non-zero", func() {
+				Expect(ioutil.WriteFile(configLocation, append(badConfig, []byte("    foundatoins: []\n")...), 0644)).To(Succeed())
+
+				session, err = gexec.Start(exec.Command(pathToCLI, "validate", "-config", configLocation), GinkgoWriter, GinkgoWriter)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1))
+				Expect(session.Err).To(Say("unknown key environments\\[0\\].foundatoins"))
+				Expect(session.Err).To(Say("missing required parameter foundations in environment sandbox"))
+				Expect(session.Err).To(Say("is not valid"))
+			})
+		})
+	})
 })
@@ -0,0 +1,267 @@
+// Package sessionpool keeps Cloud Foundry CLI sessions that are logged in so later deployments can reuse them.
+package sessionpool
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// SessionPool keeps the CF_HOME of every executor that logged in successfully, keyed by foundation
+// and credentials. A session is leased to one executor at a time, so concurrent deployments to the
+// same foundation each get their own CF_HOME. Sessions older than the TTL are thrown away and the
+// next deployment logs in again. Idle sessions are also swept every TTL, so the CF_HOME of a
+// foundation or credentials that are not used again is not kept until the process exits.
+// Sessions are also keyed by the CA bundle their executor verifies the foundation with.
+type SessionPool struct {
+	TTL         time.Duration
+	NewExecutor func(caCertFile string) (I.Executor, error)
+	Log         I.Logger
+	mutex       sync.Mutex
+	idle        map[string][]session
+	closed      bool
+	stop        chan struct{}
+}
+
+type session struct {
+	executor   I.Executor
+	loggedInAt time.Time
+}
+
+// New returns a SessionPool that keeps sessions for the length of the ttl.
+// newExecutor creates the executor used for a new session, with the CA bundle of its foundation.
+// Expired sessions are swept every ttl until the pool is closed.
+func New(ttl time.Duration, newExecutor func(caCertFile string) (I.Executor, error), log I.Logger) *SessionPool {
+	p := &SessionPool{
+		TTL:         ttl,
+		NewExecutor: newExecutor,
+		Log:         log,
+		idle:        make(map[string][]session),
+		stop:        make(chan struct{}),
+	}
+
+	go p.sweepEvery(ttl)
+
+	return p
+}
+
+// Sweep cleans up the idle sessions that are older than the TTL.
+func (p *SessionPool) Sweep() {
+	var expired []session
+
+	p.mutex.Lock()
+	for key, sessions := range p.idle {
+		var kept []session
+		for _, idle := range sessions {
+			if time.Since(idle.loggedInAt) < p.TTL {
+				kept = append(kept, idle)
+			} else {
+				expired = append(expired, idle)
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(p.idle, key)
+		} else {
+			p.idle[key] = kept
+		}
+	}
+	p.mutex.Unlock()
+
+	if len(expired) > 0 {
+		p.Log.Debugf("cleaning up %d expired cf sessions", len(expired))
+	}
+	for _, idle := range expired {
+		p.cleanUp(idle)
+	}
+}
+
+// Close stops sweeping and cleans up every idle session. Sessions that are released after
+// the pool is closed are cleaned up instead of being kept.
+func (p *SessionPool) Close() {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.stop)
+
+	idle := p.idle
+	p.idle = make(map[string][]session)
+	p.mutex.Unlock()
+
+	for _, sessions := range idle {
+		for _, session := range sessions {
+			p.cleanUp(session)
+		}
+	}
+}
+
+func (p *SessionPool) sweepEvery(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.Sweep()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Executor returns an Executor that leases its session from the pool when it logs in.
+// Its sessions verify the certificate of the foundation with the CA bundle in caCertFile,
+// or the CAs of the host when it is empty.
+func (p *SessionPool) Executor(caCertFile string) *Executor {
+	return &Executor{pool: p, caCertFile: caCertFile}
+}
+
+func (p *SessionPool) lease(key, foundationURL string) (session, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for len(p.idle[key]) > 0 {
+		sessions := p.idle[key]
+		leased := sessions[len(sessions)-1]
+		p.idle[key] = sessions[:len(sessions)-1]
+
+		if time.Since(leased.loggedInAt) < p.TTL {
+			p.Log.Debugf("reusing cf session for %s", foundationURL)
+			return leased, true
+		}
+
+		p.Log.Debugf("cf session for %s has expired", foundationURL)
+		p.cleanUp(leased)
+	}
+
+	return session{}, false
+}
+
+func (p *SessionPool) release(key string, released session) {
+	if time.Since(released.loggedInAt) >= p.TTL {
+		p.cleanUp(released)
+		return
+	}
+
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		p.cleanUp(released)
+		return
+	}
+	defer p.mutex.Unlock()
+
+	p.idle[key] = append(p.idle[key], released)
+}
+
+func (p *SessionPool) cleanUp(expired session) {
+	if err := expired.executor.CleanUp(); err != nil {
+		p.Log.Errorf("could not clean up cf session: %s", err)
+	}
+}
+
+func sessionKey(foundationURL, username, password string, skipSSL bool, caCertFile string) string {
+	passwordHash := sha256.Sum256([]byte(password))
+	return fmt.Sprintf("%s %s %x %t %s", foundationURL, username, passwordHash, skipSSL, caCertFile)
+}
+
+// Executor runs commands in the CF_HOME of a session leased from a SessionPool.
+// Before it resumes or logs in it uses a new session.
+type Executor struct {
+	pool          *SessionPool
+	caCertFile    string
+	key           string
+	session       session
+	authenticated bool
+}
+
+// Resume leases a logged in session for the foundation and credentials from the pool.
+//
+// Returns false if there is no session to resume and the executor has to log in.
+func (e *Executor) Resume(foundationURL, username, password string, skipSSL bool) bool {
+	e.key = sessionKey(foundationURL, username, password, skipSSL, e.caCertFile)
+
+	if e.session.executor != nil {
+		return false
+	}
+
+	leased, found := e.pool.lease(e.key, foundationURL)
+	if !found {
+		return false
+	}
+
+	e.session = leased
+	e.authenticated = true
+	return true
+}
+
+// LoggedIn records whether logging in with the session succeeded.
+// Only sessions that logged in are returned to the pool.
+func (e *Executor) LoggedIn(success bool) {
+	e.authenticated = success
+	if success {
+		e.session.loggedInAt = time.Now()
+	}
+}
+
+// Execute runs the command in the CF_HOME of the session.
+//
+// Returns the combined standard output and standard error.
+func (e *Executor) Execute(args ...string) ([]byte, error) {
+	if err := e.ensureSession(); err != nil {
+		return nil, err
+	}
+
+	return e.session.executor.Execute(args...)
+}
+
+// ExecuteInDirectory runs the command in a directory with the CF_HOME of the session.
+//
+// Returns the combined standard output and standard error.
+func (e *Executor) ExecuteInDirectory(directory string, args ...string) ([]byte, error) {
+	if err := e.ensureSession(); err != nil {
+		return nil, err
+	}
+
+	return e.session.executor.ExecuteInDirectory(directory, args...)
+}
+
+// CleanUp returns a logged in session to the pool and removes any other session.
+func (e *Executor) CleanUp() error {
+	if e.session.executor == nil {
+		return nil
+	}
+
+	defer func() { e.session = session{} }()
+
+	if e.authenticated && e.key != "" {
+		e.pool.release(e.key, e.session)
+		return nil
+	}
+
+	return e.session.executor.CleanUp()
+}
+
+func (e *Executor) ensureSession() error {
+	if e.session.executor != nil {
+		return nil
+	}
+
+	executor, err := e.pool.NewExecutor(e.caCertFile)
+	if err != nil {
+		return err
+	}
+
+	e.session = session{executor: executor}
+	return nil
+}
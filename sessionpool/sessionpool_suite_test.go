@@ -0,0 +1,13 @@
+package sessionpool_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSessionpool(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Sessionpool Suite")
+}
@@ -0,0 +1,197 @@
+package sessionpool_test
+
+import (
+	"errors"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	. "github.com/compozed/deployadactyl/sessionpool"
+	logging "github.com/op/go-logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("SessionPool", func() {
+	var (
+		foundationURL string
+		executors     []*mocks.Executor
+		logBuffer     *Buffer
+		pool          *SessionPool
+	)
+
+	BeforeEach(func() {
+		foundationURL = "https://api.cf." + randomizer.StringRunes(10) + ".com"
+		executors = nil
+
+		logBuffer = NewBuffer()
+		pool = New(time.Minute, func(caCertFile string) (I.Executor, error) {
+			executor := &mocks.Executor{}
+			executors = append(executors, executor)
+			return executor, nil
+		}, logger.DefaultLogger(logBuffer, logging.DEBUG, "sessionpool_test"))
+	})
+
+	AfterEach(func() {
+		pool.Close()
+	})
+
+	login := func(executor *Executor, password string) {
+		executor.Resume(foundationURL, "user", password, false)
+		executor.Execute("login")
+		executor.LoggedIn(true)
+	}
+
+	It("has no session to resume the first time", func() {
+		executor := pool.Executor("")
+
+		Expect(executor.Resume(foundationURL, "user", "password", false)).To(BeFalse())
+	})
+
+	It("resumes a session after it has logged in and been cleaned up", func() {
+		first := pool.Executor("")
+		login(first, "password")
+		Expect(first.CleanUp()).To(Succeed())
+
+		second := pool.Executor("")
+		Expect(second.Resume(foundationURL, "user", "password", false)).To(BeTrue())
+		second.Execute("target")
+
+		Expect(executors).To(HaveLen(1))
+		Expect(executors[0].ExecuteCall.TimesCalled).To(Equal(2))
+		Expect(executors[0].CleanUpCall).To(BeZero())
+		Eventually(logBuffer).Should(Say("reusing cf session for %s", foundationURL))
+	})
+
+	It("leases a session to one executor at a time", func() {
+		first := pool.Executor("")
+		login(first, "password")
+		Expect(first.CleanUp()).To(Succeed())
+
+		second := pool.Executor("")
+		third := pool.Executor("")
+
+		Expect(second.Resume(foundationURL, "user", "password", false)).To(BeTrue())
+		Expect(third.Resume(foundationURL, "user", "password", false)).To(BeFalse())
+	})
+
+	It("does not resume a session with different credentials", func() {
+		first := pool.Executor("")
+		login(first, "password")
+		Expect(first.CleanUp()).To(Succeed())
+
+		second := pool.Executor("")
+
+		Expect(second.Resume(foundationURL, "user", "other-password", false)).To(BeFalse())
+		Expect(second.Resume("https://api.other.example.com", "user", "password", false)).To(BeFalse())
+		Expect(second.Resume(foundationURL, "user", "password", true)).To(BeFalse())
+	})
+
+	It("removes sessions that did not log in", func() {
+		first := pool.Executor("")
+		first.Resume(foundationURL, "user", "password", false)
+		first.Execute("login")
+		first.LoggedIn(false)
+		Expect(first.CleanUp()).To(Succeed())
+
+		second := pool.Executor("")
+
+		Expect(second.Resume(foundationURL, "user", "password", false)).To(BeFalse())
+	})
+
+	It("removes sessions that are older than the TTL", func() {
+		pool.TTL = time.Millisecond
+
+		first := pool.Executor("")
+		login(first, "password")
+		Expect(first.CleanUp()).To(Succeed())
+
+		time.Sleep(2 * time.Millisecond)
+
+		second := pool.Executor("")
+
+		Expect(second.Resume(foundationURL, "user", "password", false)).To(BeFalse())
+	})
+
+	It("cleans up idle sessions that are older than the TTL without a new lease", func() {
+		pool.Close()
+		pool = New(10*time.Millisecond, func(caCertFile string) (I.Executor, error) {
+			executor := &mocks.Executor{}
+			executors = append(executors, executor)
+			return executor, nil
+		}, logger.DefaultLogger(logBuffer, logging.DEBUG, "sessionpool_test"))
+
+		first := pool.Executor("")
+		login(first, "password")
+		Expect(first.CleanUp()).To(Succeed())
+
+		Eventually(func() int { return executors[0].CleanUpCall.TimesCalled }).Should(Equal(1))
+		Eventually(logBuffer).Should(Say("cleaning up 1 expired cf sessions"))
+	})
+
+	It("keeps idle sessions that have not expired when it sweeps", func() {
+		first := pool.Executor("")
+		login(first, "password")
+		Expect(first.CleanUp()).To(Succeed())
+
+		pool.Sweep()
+
+		Expect(executors[0].CleanUpCall.TimesCalled).To(BeZero())
+		Expect(pool.Executor("").Resume(foundationURL, "user", "password", false)).To(BeTrue())
+	})
+
+	Describe("closing", func() {
+		It("cleans up every idle session", func() {
+			first := pool.Executor("")
+			login(first, "password")
+			Expect(first.CleanUp()).To(Succeed())
+
+			pool.Close()
+
+			Expect(executors[0].CleanUpCall.TimesCalled).To(Equal(1))
+			Expect(pool.Executor("").Resume(foundationURL, "user", "password", false)).To(BeFalse())
+		})
+
+		It("cleans up sessions that are released after it is closed", func() {
+			first := pool.Executor("")
+			login(first, "password")
+
+			pool.Close()
+			Expect(first.CleanUp()).To(Succeed())
+
+			Expect(executors[0].CleanUpCall.TimesCalled).To(Equal(1))
+			Expect(pool.Executor("").Resume(foundationURL, "user", "password", false)).To(BeFalse())
+		})
+	})
+
+	It("creates sessions with the CA bundle of the executor and does not share them with other bundles", func() {
+		var caCertFiles []string
+		pool.NewExecutor = func(caCertFile string) (I.Executor, error) {
+			caCertFiles = append(caCertFiles, caCertFile)
+			return &mocks.Executor{}, nil
+		}
+
+		first := pool.Executor("/etc/ssl/foundation.pem")
+		login(first, "password")
+		Expect(first.CleanUp()).To(Succeed())
+
+		Expect(pool.Executor("/etc/ssl/other.pem").Resume(foundationURL, "user", "password", false)).To(BeFalse())
+		Expect(pool.Executor("").Resume(foundationURL, "user", "password", false)).To(BeFalse())
+		Expect(pool.Executor("/etc/ssl/foundation.pem").Resume(foundationURL, "user", "password", false)).To(BeTrue())
+		Expect(caCertFiles).To(Equal([]string{"/etc/ssl/foundation.pem"}))
+	})
+
+	It("returns an error when a session cannot be created", func() {
+		pool.NewExecutor = func(caCertFile string) (I.Executor, error) {
+			return nil, errors.New("temp dir error")
+		}
+
+		_, err := pool.Executor("").Execute("login")
+
+		Expect(err).To(MatchError("temp dir error"))
+	})
+})
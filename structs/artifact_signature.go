@@ -0,0 +1,8 @@
+package structs
+
+// ArtifactSignature is a detached ed25519 signature of the SHA-256 digest of an artifact.
+// Signature is base64 encoded. When it is empty the signature is downloaded from URL.
+type ArtifactSignature struct {
+	Signature string
+	URL       string
+}
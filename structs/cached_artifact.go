@@ -0,0 +1,16 @@
+package structs
+
+import "time"
+
+// CachedArtifact describes an artifact kept in the artifact cache.
+// ETag and LastModified are the validators the artifact was served with. Credentials identifies
+// the credentials the artifact was downloaded with, and is empty when it was downloaded without any.
+type CachedArtifact struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag"`
+	LastModified string    `json:"last_modified"`
+	Checksums    Checksums `json:"checksums"`
+	Size         int64     `json:"size"`
+	Credentials  string    `json:"credentials,omitempty"`
+	LastUsed     time.Time `json:"last_used"`
+}
@@ -0,0 +1,9 @@
+package structs
+
+// Checksums are the hex encoded digests of an artifact.
+// An empty digest is not checked.
+type Checksums struct {
+	SHA256 string
+	SHA1   string
+	MD5    string
+}
@@ -0,0 +1,7 @@
+package structs
+
+// Credentials are a username and password used to log in to a Cloud Foundry foundation.
+type Credentials struct {
+	Username string
+	Password string
+}
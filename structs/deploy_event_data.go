@@ -1,13 +1,19 @@
 package structs
 
-import "io"
+import (
+	"io"
+
+	"github.com/compozed/deployadactyl/config"
+)
 
 // DeployEventData has a RequestBody and DeploymentInfo.
+// Environment is the config of the environment the deployment started with.
 type DeployEventData struct {
 	// Writer is being deprecated in favor of using Response as a ReadWriter. 01/03/2017
 	Writer io.Writer
 
 	Response       io.ReadWriter
 	DeploymentInfo *DeploymentInfo
+	Environment    config.Environment
 	RequestBody    io.Reader
 }
@@ -5,6 +5,11 @@ package structs
 type DeploymentInfo struct {
 	ArtifactURL          string `json:"artifact_url"`
 	Manifest             string `json:"manifest"`
+	ArtifactSHA256       string `json:"artifact_sha256"`
+	ArtifactSHA1         string `json:"artifact_sha1"`
+	ArtifactMD5          string `json:"artifact_md5"`
+	ArtifactSignature    string `json:"artifact_signature"`
+	ArtifactSignatureURL string `json:"artifact_signature_url"`
 	Username             string
 	Password             string
 	Environment          string
@@ -16,9 +21,22 @@ type DeploymentInfo struct {
 	Instances            uint16
 	Domain               string
 	AppPath              string
+	Strategy             string
 	EnvironmentVariables map[string]string `json:"environment_variables"`
 	HealthCheckEndpoint  string            `json:"health_check_endpoint"`
 
+	// FoundationCredentials are used instead of the Username and Password to log in to the
+	// foundations they are keyed by. They come from the config, never from the request.
+	FoundationCredentials map[string]Credentials `json:"-"`
+
+	// AppsDomain is the domain of the foundation that is used for the temporary health check route.
+	// It comes from the config and is set for each foundation.
+	AppsDomain string `json:"-"`
+
+	// CACert is the PEM bundle that the cf cli verifies the certificate of the foundation with.
+	// It comes from the config and is set for each foundation.
+	CACert string `json:"-"`
+
 	// Generic map used for users to provide their own deployment properties in JSON format.
 	Data map[string]interface{} `json:"data"`
 }
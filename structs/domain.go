@@ -0,0 +1,16 @@
+package structs
+
+const (
+	// SharedDomain is the type of a domain that is available to every org in a foundation.
+	SharedDomain = "shared"
+	// PrivateDomain is the type of a domain that is owned by a single org.
+	PrivateDomain = "private"
+)
+
+// Domain is a Cloud Foundry domain available in a foundation.
+type Domain struct {
+	Name     string
+	Type     string
+	Protocol string
+	Internal bool
+}
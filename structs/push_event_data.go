@@ -2,15 +2,19 @@ package structs
 
 import (
 	"io"
+
+	"github.com/compozed/deployadactyl/config"
 )
 
 // PushEventData has a RequestBody and DeploymentInfo.
+// Environment is the config of the environment the deployment started with.
 type PushEventData struct {
 	AppPath         string
 	FoundationURL   string
 	TempAppWithUUID string
 
 	DeploymentInfo *DeploymentInfo
+	Environment    config.Environment
 	Courier        interface{}
 	Response       io.ReadWriter
 }
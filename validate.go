@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/compozed/deployadactyl/config"
+)
+
+// validate checks a config file and writes every problem with it to stderr, so config changes
+// can be checked before they are deployed. It returns the exit code of the validate command.
+func validate(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var (
+		configPath       = flags.String("config", defaultConfigFilePath, "location of the config file")
+		checkCredentials = flags.Bool("credentials", false, "also check that CF_USERNAME, CF_PASSWORD and the credentials of the environments can be read")
+	)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	errs := config.Validate(os.Getenv, *configPath, *checkCredentials)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(stderr, "%s: %s\n", *configPath, err)
+		}
+		fmt.Fprintf(stderr, "%s is not valid\n", *configPath)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "%s is valid\n", *configPath)
+	return 0
+}